@@ -0,0 +1,16 @@
+package main
+
+import "fmt"
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "migrate":
+		return runMigrate(args)
+	case "rates":
+		return runRates(args)
+	case "journal":
+		return runJournal(args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gastoslog/internal/database"
+	"gastoslog/internal/journal"
+)
+
+const journalUsage = "usage: journal -user EMAIL [-format ledger|hledger|beancount] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-account Assets:Cash] [-o FILE]"
+
+// runJournal writes a user's expenses as a plain-text accounting journal, to
+// standard output unless -o names a file.
+func runJournal(args []string) error {
+	flags := flag.NewFlagSet("journal", flag.ContinueOnError)
+	email := flags.String("user", "", "email of the user whose expenses are exported")
+	formatName := flags.String("format", string(journal.FormatHledger), "journal format, ledger, hledger or beancount")
+	fromValue := flags.String("from", "", "first day to export, YYYY-MM-DD")
+	toValue := flags.String("to", "", "last day to export, YYYY-MM-DD")
+	account := flags.String("account", journal.DefaultAccount, "account expenses paid from no wallet come from")
+	output := flags.String("o", "", "file to write instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return errors.New(journalUsage)
+	}
+	if *email == "" || flags.NArg() > 0 {
+		return errors.New(journalUsage)
+	}
+
+	format, err := journal.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	from, err := parseDay("from", *fromValue)
+	if err != nil {
+		return err
+	}
+	to, err := parseDay("to", *toValue)
+	if err != nil {
+		return err
+	}
+
+	db := database.New()
+	defer db.Close()
+
+	ctx := context.Background()
+
+	user, err := db.UserRepository().GetByEmail(ctx, *email)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *email, err)
+	}
+
+	options := journal.Options{
+		Format:         format,
+		Filters:        database.ListExpenseInput{UserID: user.ID, From: from, To: to},
+		DefaultAccount: *account,
+	}
+	if *output == "" {
+		return journal.Export(ctx, db, os.Stdout, options)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := journal.Export(ctx, db, file, options); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// parseDay parses an optional YYYY-MM-DD flag.
+func parseDay(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -%s %q, use YYYY-MM-DD", name, value)
+	}
+	return &day, nil
+}
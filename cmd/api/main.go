@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -42,6 +43,14 @@ func main() {
 	// Load env's
 	config.LoadENV()
 
+	// Run a subcommand instead of the server, e.g. `main migrate status`
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	server := server.NewServer()
 
 	// Create a done channel to signal when the shutdown is complete
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"gastoslog/internal/config"
+	"gastoslog/internal/database"
+)
+
+const migrateUsage = "usage: migrate up|down|status"
+
+func runMigrate(args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := database.Open(config.DB_URL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Println("no migrations to revert")
+			return nil
+		}
+		fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
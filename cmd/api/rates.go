@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"gastoslog/internal/database"
+	"gastoslog/internal/exchangerate"
+)
+
+const ratesUsage = "usage: rates import [-format ecb|csv] FILE..."
+
+// runRates imports exchange rates from local files, e.g. the ECB
+// eurofxref-hist.xml, without any network access.
+func runRates(args []string) error {
+	if len(args) == 0 || args[0] != "import" {
+		return errors.New(ratesUsage)
+	}
+
+	flags := flag.NewFlagSet("rates import", flag.ContinueOnError)
+	formatName := flags.String("format", "", "file format, ecb or csv (detected when omitted)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return errors.New(ratesUsage)
+	}
+	if flags.NArg() == 0 {
+		return errors.New(ratesUsage)
+	}
+
+	var format exchangerate.Format
+	if *formatName != "" {
+		parsedFormat, err := exchangerate.ParseFormat(*formatName)
+		if err != nil {
+			return err
+		}
+		format = parsedFormat
+	}
+
+	db := database.New()
+	defer db.Close()
+
+	ctx := context.Background()
+
+	for _, path := range flags.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		result, err := exchangerate.Import(ctx, db, file, format)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		fmt.Printf("%s: imported %d rates, skipped %d\n", path, result.Imported, result.Skipped)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package account
 import (
 	"context"
 	"errors"
+	"gastoslog/internal/currency"
 	"gastoslog/internal/database"
 	"gastoslog/internal/encryption"
 	"time"
@@ -16,6 +17,7 @@ type UserResponse struct {
 	ID              int64      `json:"id"`
 	Email           string     `json:"email"`
 	Role            string     `json:"role"`
+	BaseCurrency    string     `json:"baseCurrency"`
 	EmailVerifiedAt *time.Time `json:"-"`
 	CreatedAt       time.Time  `json:"createdAt"`
 	UpdatedAt       time.Time  `json:"updatedAt"`
@@ -88,11 +90,27 @@ func (s *Service) SignIn(ctx context.Context, email, password string) (*UserResp
 	return toUserResponse(user), nil
 }
 
+// UpdateBaseCurrency sets the currency overviews are reported in. It returns
+// currency.ErrUnknown for codes outside ISO 4217.
+func (s *Service) UpdateBaseCurrency(ctx context.Context, userID int64, code string) (*UserResponse, error) {
+	baseCurrency, err := currency.Lookup(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateBaseCurrency(ctx, userID, baseCurrency.Code); err != nil {
+		return nil, err
+	}
+
+	return s.GetUserById(ctx, userID)
+}
+
 func toUserResponse(user *database.User) *UserResponse {
 	return &UserResponse{
 		ID:              user.ID,
 		Email:           user.Email,
 		Role:            user.Role,
+		BaseCurrency:    user.BaseCurrency,
 		EmailVerifiedAt: user.EmailVerifiedAt,
 		CreatedAt:       user.CreatedAt,
 		UpdatedAt:       user.UpdatedAt,
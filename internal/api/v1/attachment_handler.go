@@ -0,0 +1,289 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"gastoslog/internal/storage"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// maxAttachmentBytes caps the size of an uploaded file.
+const maxAttachmentBytes = 10 << 20
+
+// MaxAttachmentRequestBytes caps a whole upload request, leaving room for
+// the multipart headers around the file.
+const MaxAttachmentRequestBytes = maxAttachmentBytes + 64<<10
+
+type AttachmentHandler struct {
+	db                   database.Service
+	attachmentRepository database.AttachmentRepository
+	expenseRepository    database.ExpenseRepository
+	store                *storage.Local
+}
+
+func NewAttachmentHandler(db database.Service, store *storage.Local) *AttachmentHandler {
+	return &AttachmentHandler{
+		db:                   db,
+		attachmentRepository: db.AttachmentRepository(),
+		expenseRepository:    db.ExpenseRepository(),
+		store:                store,
+	}
+}
+
+// checkExpense makes sure the expense belongs to the user and is not in the
+// trash.
+func checkExpense(ctx context.Context, expenses database.ExpenseRepository, userID, expenseID int64) error {
+	exist, err := expenses.ExistWithUserID(ctx, database.ExistExpenseWithUserIDInput{UserID: userID, ExpenseID: expenseID})
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return huma.Error404NotFound("Expense not found")
+	}
+	return nil
+}
+
+// getOwnedAttachment loads an attachment of the user's expense, or fails
+// with 404.
+func getOwnedAttachment(ctx context.Context, attachments database.AttachmentRepository, userID, expenseID, attachmentID int64) (*database.Attachment, error) {
+	exist, err := attachments.ExistWithUserID(ctx, database.ExistAttachmentWithUserIDInput{UserID: userID, AttachmentID: attachmentID})
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, huma.Error404NotFound("Attachment not found")
+	}
+
+	attachment, err := attachments.GetByID(ctx, attachmentID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get attachment", err)
+	}
+	if attachment.ExpenseID != expenseID {
+		return nil, huma.Error404NotFound("Attachment not found")
+	}
+	return attachment, nil
+}
+
+type UploadAttachmentInput struct {
+	ExpenseID int64 `path:"expenseId" doc:"Expense ID"`
+	RawBody   huma.MultipartFormFiles[struct {
+		File huma.FormFile `form:"file" contentType:"image/jpeg,image/png,image/webp,image/heic,application/pdf" required:"true" doc:"Photo or PDF of the receipt, up to 10 MB"`
+	}]
+}
+
+type UploadedAttachmentOutput struct {
+	Body struct {
+		Data AttachmentResponse `json:"data" doc:"Attachment uploaded successfully"`
+	}
+}
+
+func (h *AttachmentHandler) UploadAttachment(ctx context.Context, input *UploadAttachmentInput) (*UploadedAttachmentOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkExpense(ctx, h.expenseRepository, int64(userID), input.ExpenseID); err != nil {
+		return nil, err
+	}
+
+	file := input.RawBody.Data().File
+	defer file.Close()
+	if file.Size > maxAttachmentBytes {
+		return nil, huma.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Attachments are limited to %d MB", maxAttachmentBytes>>20))
+	}
+
+	key, size, err := h.store.Put(file)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to store attachment", err)
+	}
+
+	filename := filepath.Base(strings.ReplaceAll(file.Filename, `\`, "/"))
+	if filename == "." || filename == "/" {
+		filename = "receipt"
+	}
+
+	attachment, err := h.attachmentRepository.Create(ctx, database.NewAttachmentInput{
+		ExpenseID:   input.ExpenseID,
+		UserID:      int64(userID),
+		SHA256:      key,
+		Filename:    filename,
+		ContentType: file.ContentType,
+		Size:        size,
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to save attachment", err)
+	}
+
+	// A delete of the last attachment sharing this content may have removed
+	// the file between Put and Create; store it again now the row keeps it
+	if err := h.restoreFile(file, key); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to store attachment", err)
+	}
+
+	resp := &UploadedAttachmentOutput{}
+	resp.Body.Data = toAttachmentResponse(*attachment)
+	return resp, nil
+}
+
+// restoreFile stores the uploaded file again if key is no longer in the
+// store.
+func (h *AttachmentHandler) restoreFile(file huma.FormFile, key string) error {
+	exists, err := h.store.Exists(key)
+	if err != nil || exists {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, _, err = h.store.Put(file)
+	return err
+}
+
+type ExpenseAttachmentsInput struct {
+	ExpenseID int64 `path:"expenseId" doc:"Expense ID"`
+}
+
+type ListAttachmentOutput struct {
+	Body struct {
+		Data []AttachmentResponse `json:"data" doc:"Attachments of the expense in upload order"`
+	}
+}
+
+func (h *AttachmentHandler) ListAttachment(ctx context.Context, input *ExpenseAttachmentsInput) (*ListAttachmentOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkExpense(ctx, h.expenseRepository, int64(userID), input.ExpenseID); err != nil {
+		return nil, err
+	}
+
+	attachments, err := h.attachmentRepository.ListByExpense(ctx, input.ExpenseID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list attachments", err)
+	}
+
+	resp := &ListAttachmentOutput{}
+	resp.Body.Data = make([]AttachmentResponse, len(attachments))
+	for index, attachment := range attachments {
+		resp.Body.Data[index] = toAttachmentResponse(attachment)
+	}
+	return resp, nil
+}
+
+type AttachmentIDInput struct {
+	ExpenseID    int64 `path:"expenseId" doc:"Expense ID"`
+	AttachmentID int64 `path:"attachmentId" doc:"Attachment ID"`
+}
+
+type DownloadAttachmentOutput struct {
+	ContentType        string `header:"Content-Type"`
+	ContentDisposition string `header:"Content-Disposition"`
+	Body               []byte
+}
+
+func (h *AttachmentHandler) DownloadAttachment(ctx context.Context, input *AttachmentIDInput) (*DownloadAttachmentOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := getOwnedAttachment(ctx, h.attachmentRepository, int64(userID), input.ExpenseID, input.AttachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := h.store.Open(attachment.SHA256)
+	if errors.Is(err, storage.ErrNotFound) {
+		// Only a delete racing an upload of the same content can get here;
+		// see DeleteAttachment
+		log.Printf("Attachment %d has no stored file %s", attachment.ID, attachment.SHA256)
+		return nil, huma.Error404NotFound("Attachment file is missing. Upload it again")
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to open attachment", err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to read attachment", err)
+	}
+
+	resp := &DownloadAttachmentOutput{}
+	resp.ContentType = attachment.ContentType
+	resp.ContentDisposition = mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename})
+	resp.Body = content
+	return resp, nil
+}
+
+// DeleteAttachment removes an attachment, and its file when no other
+// attachment shares it.
+//
+// Counting and deleting the file are not atomic with uploads: an upload of
+// the same content that finds the file in place and creates its row just
+// after the count loses the file. UploadAttachment stores the file again
+// when it is gone after creating the row, which leaves only a delete landing
+// between that check and its own count; DownloadAttachment reports those.
+func (h *AttachmentHandler) DeleteAttachment(ctx context.Context, input *AttachmentIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := getOwnedAttachment(ctx, h.attachmentRepository, int64(userID), input.ExpenseID, input.AttachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.attachmentRepository.Delete(ctx, attachment.ID); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete attachment", err)
+	}
+
+	count, err := h.attachmentRepository.CountBySHA256(ctx, attachment.SHA256)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete attachment file", err)
+	}
+	if count == 0 {
+		if err := h.store.Delete(attachment.SHA256); err != nil {
+			return nil, huma.Error500InternalServerError("Failed to delete attachment file", err)
+		}
+	}
+
+	return nil, nil
+}
+
+type AttachmentResponse struct {
+	ID          int64     `json:"id"`
+	ExpenseID   int64     `json:"expenseId"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size" doc:"Size in bytes"`
+	SHA256      string    `json:"sha256" doc:"Hex SHA-256 of the content"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func toAttachmentResponse(attachment database.Attachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:          attachment.ID,
+		ExpenseID:   attachment.ExpenseID,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		SHA256:      attachment.SHA256,
+		CreatedAt:   attachment.CreatedAt,
+	}
+}
@@ -0,0 +1,424 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type BudgetHandler struct {
+	db               database.Service
+	budgetRepository database.BudgetRepository
+	userRepository   database.UserRepository
+}
+
+func NewBudgetHandler(db database.Service) *BudgetHandler {
+	return &BudgetHandler{
+		db:               db,
+		budgetRepository: db.BudgetRepository(),
+		userRepository:   db.UserRepository(),
+	}
+}
+
+type BudgetInputBody struct {
+	CategoryID int64   `json:"categoryId,omitempty" doc:"Category the budget applies to, including its subcategories. Omit for an overall budget"`
+	Period     string  `json:"period" enum:"week,month,year" doc:"How often the budget resets. Weeks start on Monday"`
+	Amount     float64 `json:"amount" minimum:"0.01" doc:"Budget per period in major units, e.g. 500.00"`
+	Currency   string  `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code. Defaults to the user's base currency"`
+}
+
+type BudgetResponse struct {
+	ID         int64     `json:"id"`
+	CategoryID *int64    `json:"categoryId" doc:"Budgeted category, null for the overall budget"`
+	Period     string    `json:"period"`
+	Amount     int64     `json:"amount" doc:"Budget per period in minor units of currency"`
+	Currency   string    `json:"currency"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+func toBudgetResponse(budget database.Budget) BudgetResponse {
+	return BudgetResponse{
+		ID:         budget.ID,
+		CategoryID: nullInt64Ptr(budget.CategoryID),
+		Period:     budget.Period,
+		Amount:     budget.Amount,
+		Currency:   budget.Currency,
+		CreatedAt:  budget.CreatedAt,
+		UpdatedAt:  budget.UpdatedAt,
+	}
+}
+
+// checkBudgetTarget makes sure the budgeted category belongs to the user and
+// that no other budget already covers it. budgetID is 0 for a new budget.
+func checkBudgetTarget(ctx context.Context, tx database.Repositories, userID, budgetID, categoryID int64) (sql.NullInt64, error) {
+	target := sql.NullInt64{Int64: categoryID, Valid: categoryID != 0}
+
+	if target.Valid {
+		exist, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{
+			UserID:     userID,
+			CategoryID: categoryID,
+			Kind:       database.CategoryKindExpense,
+		})
+		if err != nil {
+			return target, err
+		}
+		if !exist {
+			return target, huma.Error404NotFound("Category not found")
+		}
+	}
+
+	budgets, err := tx.BudgetRepository().List(ctx, userID)
+	if err != nil {
+		return target, err
+	}
+	for _, budget := range budgets {
+		if budget.ID != budgetID && budget.CategoryID == target {
+			return target, huma.Error409Conflict(fmt.Sprintf("Budget %d already covers this category", budget.ID))
+		}
+	}
+
+	return target, nil
+}
+
+type NewBudgetInput struct {
+	Body BudgetInputBody
+}
+
+type CreatedBudgetOutput struct {
+	Body struct {
+		Data BudgetResponse `json:"data" doc:"Budget created successfully"`
+	}
+}
+
+func (h *BudgetHandler) CreateBudget(ctx context.Context, input *NewBudgetInput) (*CreatedBudgetOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	budgetCurrency, err := resolveCurrency(ctx, h.userRepository, int64(userID), input.Body.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdBudget *database.Budget
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		categoryID, err := checkBudgetTarget(ctx, tx, int64(userID), 0, input.Body.CategoryID)
+		if err != nil {
+			return err
+		}
+
+		createdBudget, err = tx.BudgetRepository().Create(ctx, database.NewBudgetInput{
+			UserID:     int64(userID),
+			CategoryID: categoryID,
+			Period:     input.Body.Period,
+			Amount:     budgetCurrency.ToMinor(input.Body.Amount),
+			Currency:   budgetCurrency.Code,
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create budget", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedBudgetOutput{}
+	resp.Body.Data = toBudgetResponse(*createdBudget)
+	return resp, nil
+}
+
+type ListBudgetInput struct {
+}
+
+type ListBudgetOutput struct {
+	Body struct {
+		Data []BudgetResponse `json:"data" doc:"User's budgets, the overall budget first"`
+	}
+}
+
+func (h *BudgetHandler) ListBudget(ctx context.Context, input *ListBudgetInput) (*ListBudgetOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	budgets, err := h.budgetRepository.List(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list budgets", err)
+	}
+
+	resp := &ListBudgetOutput{}
+	resp.Body.Data = make([]BudgetResponse, len(budgets))
+	for index, budget := range budgets {
+		resp.Body.Data[index] = toBudgetResponse(budget)
+	}
+
+	return resp, nil
+}
+
+type DetailBudgetInput struct {
+	BudgetID int64 `path:"budgetId" doc:"Budget ID"`
+}
+
+type DetailBudgetOutput struct {
+	Body struct {
+		Data BudgetResponse `json:"data" doc:"Budget Detail"`
+	}
+}
+
+func (h *BudgetHandler) DetailBudget(ctx context.Context, input *DetailBudgetInput) (*DetailBudgetOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exist, err := h.budgetRepository.ExistWithUserID(ctx, database.ExistBudgetWithUserIDInput{
+		UserID:   int64(userID),
+		BudgetID: input.BudgetID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, huma.Error404NotFound("Budget not found")
+	}
+
+	budget, err := h.budgetRepository.GetByID(ctx, input.BudgetID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get budget", err)
+	}
+
+	resp := &DetailBudgetOutput{}
+	resp.Body.Data = toBudgetResponse(*budget)
+	return resp, nil
+}
+
+type UpdateBudgetInput struct {
+	BudgetID int64 `path:"budgetId" doc:"Budget ID"`
+	Body     BudgetInputBody
+}
+
+type UpdatedBudgetOutput struct {
+	Body struct {
+		Data BudgetResponse `json:"data" doc:"Budget updated successfully"`
+	}
+}
+
+func (h *BudgetHandler) UpdateBudget(ctx context.Context, input *UpdateBudgetInput) (*UpdatedBudgetOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	budgetCurrency, err := resolveCurrency(ctx, h.userRepository, int64(userID), input.Body.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var updatedBudget *database.Budget
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		exist, err := tx.BudgetRepository().ExistWithUserID(ctx, database.ExistBudgetWithUserIDInput{
+			UserID:   int64(userID),
+			BudgetID: input.BudgetID,
+		})
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return huma.Error404NotFound("Budget not found")
+		}
+
+		categoryID, err := checkBudgetTarget(ctx, tx, int64(userID), input.BudgetID, input.Body.CategoryID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.BudgetRepository().Update(ctx, database.UpdateBudgetInput{
+			BudgetID:   input.BudgetID,
+			UserID:     int64(userID),
+			CategoryID: categoryID,
+			Period:     input.Body.Period,
+			Amount:     budgetCurrency.ToMinor(input.Body.Amount),
+			Currency:   budgetCurrency.Code,
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to update budget", err)
+		}
+
+		updatedBudget, err = tx.BudgetRepository().GetByID(ctx, input.BudgetID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UpdatedBudgetOutput{}
+	resp.Body.Data = toBudgetResponse(*updatedBudget)
+	return resp, nil
+}
+
+type DeleteBudgetInput struct {
+	BudgetID int64 `path:"budgetId" doc:"Budget ID"`
+}
+
+func (h *BudgetHandler) DeleteBudget(ctx context.Context, input *DeleteBudgetInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exist, err := h.budgetRepository.ExistWithUserID(ctx, database.ExistBudgetWithUserIDInput{
+		UserID:   int64(userID),
+		BudgetID: input.BudgetID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, huma.Error404NotFound("Budget not found")
+	}
+
+	if err := h.budgetRepository.Delete(ctx, input.BudgetID); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete budget", err)
+	}
+
+	return nil, nil
+}
+
+// BudgetUsage is how much of a budget has been spent in its current period,
+// in major units of the user's base currency.
+type BudgetUsage struct {
+	BudgetID     int64   `json:"budgetId" required:"false"`
+	BudgetPeriod string  `json:"budgetPeriod" required:"false" doc:"week, month or year"`
+	PeriodStart  string  `json:"periodStart" required:"false" doc:"First day of the budget period (YYYY-MM-DD)"`
+	PeriodEnd    string  `json:"periodEnd" required:"false" doc:"Last day of the budget period (YYYY-MM-DD)"`
+	Budget       float64 `json:"budget" required:"false"`
+	Spent        float64 `json:"spent" required:"false" doc:"Spent in the budget period, including subcategories"`
+	Remaining    float64 `json:"remaining" required:"false" doc:"Negative once the budget is overspent"`
+	PercentUsed  float64 `json:"percentUsed" required:"false"`
+}
+
+// trackedBudget pairs a budget's usage with its category, nil for the
+// overall budget.
+type trackedBudget struct {
+	category *database.Category
+	usage    BudgetUsage
+}
+
+// trackBudgets works out the usage of each of the user's budgets for the
+// period containing on. A budget on a category also counts the spending of
+// its subcategories. Budgets on deleted categories, or in a currency without
+// a rate, are left out, and so is spending without a rate.
+func trackBudgets(ctx context.Context, repos database.Repositories, converter *baseConverter, userID int64, on time.Time) ([]trackedBudget, error) {
+	budgets, err := repos.BudgetRepository().List(ctx, userID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list budgets", err)
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	list, err := repos.CategoryRepository().List(ctx, database.ListCategoryInput{UserID: userID, Kind: database.CategoryKindExpense, All: true})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list categories", err)
+	}
+	categories := make(map[int64]*database.Category, len(list.Categories))
+	for index := range list.Categories {
+		categories[list.Categories[index].ID] = &list.Categories[index]
+	}
+
+	// Spending per category including subcategories, keyed by period. The
+	// overall total is kept under category 0.
+	spentByPeriod := map[string]map[int64]int64{}
+
+	tracked := []trackedBudget{}
+	for _, budget := range budgets {
+		var category *database.Category
+		if budget.CategoryID.Valid {
+			category = categories[budget.CategoryID.Int64]
+			if category == nil {
+				continue
+			}
+		}
+
+		from, to := budget.Window(on)
+
+		spent, ok := spentByPeriod[budget.Period]
+		if !ok {
+			overviews, err := repos.ExpenseRepository().GetOverviewByCategoryBetween(ctx, userID, from, to)
+			if err != nil {
+				return nil, huma.Error500InternalServerError("Failed to get budget spending", err)
+			}
+
+			codes, days := []string{}, []string{}
+			for _, overview := range overviews {
+				codes = append(codes, overview.Currency)
+				days = append(days, overview.Day)
+			}
+			if err := converter.preload(ctx, codes, days); err != nil {
+				return nil, err
+			}
+
+			spent = map[int64]int64{}
+			for _, overview := range overviews {
+				amount, _, err := converter.convert(ctx, overview.TotalAmount, overview.Currency, overview.Day)
+				if err != nil {
+					return nil, err
+				}
+
+				spent[0] += amount
+				// Walk up to the top level, guarding against a corrupt cycle
+				seen := map[int64]bool{}
+				for id := overview.CategoryID; id != 0 && !seen[id]; {
+					seen[id] = true
+					spent[id] += amount
+
+					parent := categories[id]
+					if parent == nil || !parent.ParentID.Valid {
+						break
+					}
+					id = parent.ParentID.Int64
+				}
+			}
+			spentByPeriod[budget.Period] = spent
+		}
+
+		budgetAmount, ok, err := converter.convert(ctx, budget.Amount, budget.Currency, from.Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		spentAmount := spent[budget.CategoryID.Int64]
+		percentUsed := 0.0
+		if budgetAmount > 0 {
+			percentUsed = float64(spentAmount) / float64(budgetAmount) * 100
+		}
+
+		tracked = append(tracked, trackedBudget{
+			category: category,
+			usage: BudgetUsage{
+				BudgetID:     budget.ID,
+				BudgetPeriod: budget.Period,
+				PeriodStart:  from.Format("2006-01-02"),
+				PeriodEnd:    to.AddDate(0, 0, -1).Format("2006-01-02"),
+				Budget:       converter.base.FromMinor(budgetAmount),
+				Spent:        converter.base.FromMinor(spentAmount),
+				Remaining:    converter.base.FromMinor(budgetAmount - spentAmount),
+				PercentUsed:  percentUsed,
+			},
+		})
+	}
+
+	return tracked, nil
+}
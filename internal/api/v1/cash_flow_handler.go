@@ -0,0 +1,162 @@
+package v1
+
+import (
+	"context"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"sort"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type CashFlowHandler struct {
+	expenseRepository      database.ExpenseRepository
+	incomeRepository       database.IncomeRepository
+	userRepository         database.UserRepository
+	exchangeRateRepository database.ExchangeRateRepository
+}
+
+func NewCashFlowHandler(db database.Service) *CashFlowHandler {
+	return &CashFlowHandler{
+		expenseRepository:      db.ExpenseRepository(),
+		incomeRepository:       db.IncomeRepository(),
+		userRepository:         db.UserRepository(),
+		exchangeRateRepository: db.ExchangeRateRepository(),
+	}
+}
+
+// bucketLength maps a cash flow interval to the length of the YYYY-MM-DD
+// prefix that names its buckets.
+var bucketLength = map[string]int{
+	"day":   len("2006-01-02"),
+	"month": len("2006-01"),
+	"year":  len("2006"),
+}
+
+type CashFlowInput struct {
+	ExpenseOverviewInput
+	Interval string `query:"interval" enum:"day,month,year" default:"day" doc:"Group the period into days, months or years"`
+}
+
+type CashFlowOutput struct {
+	Body struct {
+		Data []CashFlowBucket `json:"data" doc:"Cash flow per interval with any income or expense, oldest first"`
+		Meta CashFlowMeta     `json:"meta"`
+	}
+}
+
+type CashFlowBucket struct {
+	Period  string  `json:"period" doc:"The day, month or year, as YYYY-MM-DD, YYYY-MM or YYYY"`
+	Income  float64 `json:"income" doc:"Income in major units of the base currency"`
+	Expense float64 `json:"expense" doc:"Expenses in major units of the base currency"`
+	Net     float64 `json:"net" doc:"Income minus expenses in major units of the base currency"`
+}
+
+type CashFlowMeta struct {
+	Period       string          `json:"period" doc:"Period of the report"`
+	Interval     string          `json:"interval" doc:"Length of each bucket"`
+	Currency     string          `json:"currency" doc:"User's base currency all figures are converted to"`
+	TotalIncome  int64           `json:"totalIncome" doc:"Income for the period, in minor units of the base currency"`
+	TotalExpense int64           `json:"totalExpense" doc:"Expenses for the period, in minor units of the base currency"`
+	Net          int64           `json:"net" doc:"Income minus expenses for the period, in minor units of the base currency"`
+	Unconverted  []CurrencyTotal `json:"unconverted,omitempty" doc:"Income and expenses left out of the figures for lack of an exchange rate, per currency in its minor units"`
+}
+
+// cashFlowTotals is a bucket in minor units of the base currency.
+type cashFlowTotals struct {
+	income  int64
+	expense int64
+}
+
+// GetCashFlow reports income, expenses and their difference over the same
+// periods as the expense overview.
+func (h *CashFlowHandler) GetCashFlow(ctx context.Context, input *CashFlowInput) (*CashFlowOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	customDate, err := parseOverviewDate(input.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	incomes, err := h.incomeRepository.GetDailyTotals(ctx, int64(userID), input.Period, customDate, input.Wallet)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get incomes", err)
+	}
+
+	expenses, err := h.expenseRepository.GetDailyTotals(ctx, int64(userID), input.Period, customDate, input.Wallet)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get expenses", err)
+	}
+
+	converter, err := newBaseConverter(ctx, h.userRepository, h.exchangeRateRepository, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	codes, days := []string{}, []string{}
+	for _, totals := range [][]database.DailyTotal{incomes, expenses} {
+		for _, total := range totals {
+			codes = append(codes, total.Currency)
+			days = append(days, total.Day)
+		}
+	}
+	if err := converter.preload(ctx, codes, days); err != nil {
+		return nil, err
+	}
+
+	length := bucketLength[input.Interval]
+	buckets := map[string]*cashFlowTotals{}
+	add := func(totals []database.DailyTotal, amountOf func(bucket *cashFlowTotals) *int64) error {
+		for _, total := range totals {
+			amount, _, err := converter.convert(ctx, total.TotalAmount, total.Currency, total.Day)
+			if err != nil {
+				return err
+			}
+
+			key := total.Day[:length]
+			if buckets[key] == nil {
+				buckets[key] = &cashFlowTotals{}
+			}
+			*amountOf(buckets[key]) += amount
+		}
+		return nil
+	}
+
+	if err := add(incomes, func(bucket *cashFlowTotals) *int64 { return &bucket.income }); err != nil {
+		return nil, err
+	}
+	if err := add(expenses, func(bucket *cashFlowTotals) *int64 { return &bucket.expense }); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	resp := &CashFlowOutput{}
+	resp.Body.Data = make([]CashFlowBucket, len(keys))
+	for i, key := range keys {
+		bucket := buckets[key]
+		resp.Body.Data[i] = CashFlowBucket{
+			Period:  key,
+			Income:  converter.base.FromMinor(bucket.income),
+			Expense: converter.base.FromMinor(bucket.expense),
+			Net:     converter.base.FromMinor(bucket.income - bucket.expense),
+		}
+		resp.Body.Meta.TotalIncome += bucket.income
+		resp.Body.Meta.TotalExpense += bucket.expense
+	}
+
+	resp.Body.Meta.Period = input.Period
+	resp.Body.Meta.Interval = input.Interval
+	resp.Body.Meta.Currency = converter.base.Code
+	resp.Body.Meta.Net = resp.Body.Meta.TotalIncome - resp.Body.Meta.TotalExpense
+	resp.Body.Meta.Unconverted = converter.unconvertedTotals()
+
+	return resp, nil
+}
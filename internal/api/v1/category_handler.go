@@ -2,6 +2,9 @@ package v1
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"gastoslog/internal/database"
 	"gastoslog/internal/middleware"
 	"strconv"
@@ -11,20 +14,56 @@ import (
 )
 
 type CategoryHandler struct {
+	db                 database.Service
 	categoryRepository database.CategoryRepository
 }
 
-func NewCategoryHandler(categoryRepository database.CategoryRepository) *CategoryHandler {
-	return &CategoryHandler{categoryRepository: categoryRepository}
+func NewCategoryHandler(db database.Service) *CategoryHandler {
+	return &CategoryHandler{db: db, categoryRepository: db.CategoryRepository()}
 }
 
 type NewCategoryInput struct {
 	Body struct {
 		Name        string `json:"name" minLength:"2" maxLength:"255"`
 		Description string `json:"description,omitempty"`
+		Kind        string `json:"kind,omitempty" enum:"expense,income" default:"expense" doc:"Whether the category files expenses or incomes. Cannot be changed later"`
+		ParentID    int64  `json:"parentId,omitempty" doc:"Nest the category under this one, e.g. Fuel under Transport"`
 	}
 }
 
+// resolveParent checks that parentID can become the parent of categoryID,
+// which is 0 for a new category, of the given kind. A parentID of 0 means
+// the top level.
+func resolveParent(ctx context.Context, tx database.Repositories, userID, categoryID int64, kind string, parentID int64) (sql.NullInt64, error) {
+	if parentID == 0 {
+		return sql.NullInt64{}, nil
+	}
+
+	exist, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{
+		UserID:     userID,
+		CategoryID: parentID,
+		Kind:       kind,
+	})
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	if !exist {
+		return sql.NullInt64{}, huma.Error404NotFound("Parent category not found")
+	}
+
+	if categoryID != 0 {
+		cycle, err := tx.CategoryRepository().IsDescendant(ctx, parentID, categoryID)
+		if err != nil {
+			return sql.NullInt64{}, err
+		}
+		if cycle {
+			return sql.NullInt64{}, huma.Error422UnprocessableEntity("A category cannot be nested under itself or one of its subcategories")
+		}
+	}
+
+	return sql.NullInt64{Int64: parentID, Valid: true}, nil
+}
+
 type CreatedCategoryOutput struct {
 	Body struct {
 		Category CategoryResponse `json:"category" doc:"Category created successfully"`
@@ -37,11 +76,23 @@ func (c *CategoryHandler) CreateCategory(ctx context.Context, input *NewCategory
 		return nil, err
 	}
 
-	newCategoryInput := &database.NewCategoryInput{UserID: int64(userID), Name: input.Body.Name, Description: input.Body.Description}
+	newCategoryInput := &database.NewCategoryInput{UserID: int64(userID), Name: input.Body.Name, Description: input.Body.Description, Kind: input.Body.Kind}
+
+	var createdCategory *database.Category
+	err = c.db.WithTx(ctx, func(tx database.Repositories) error {
+		newCategoryInput.ParentID, err = resolveParent(ctx, tx, int64(userID), 0, input.Body.Kind, input.Body.ParentID)
+		if err != nil {
+			return err
+		}
 
-	createdCategory, err := c.categoryRepository.Create(ctx, *newCategoryInput)
+		createdCategory, err = tx.CategoryRepository().Create(ctx, *newCategoryInput)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create category", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Failed to create category", err)
+		return nil, err
 	}
 
 	resp := &CreatedCategoryOutput{}
@@ -53,15 +104,22 @@ type ListCategoryInput struct {
 	Page   int    `query:"page" default:"1" doc:"Page number of pagination"`
 	Limit  int    `query:"limit" default:"10" doc:"Limit per page of pagination"`
 	Search string `query:"s" doc:"Search category name"`
+	Cursor string `query:"cursor" doc:"Continue after the page that returned this nextCursor. Takes precedence over page"`
+	Tree   bool   `query:"tree" doc:"Return every category as a tree of top-level categories and their children, ignoring pagination"`
+	Kind   string `query:"kind" enum:"expense,income" doc:"Only list expense or income categories"`
+}
+
+// CategoryPageMeta adds the incomes of the matching categories, which are
+// kept apart from their expenses.
+type CategoryPageMeta struct {
+	PageMeta
+	IncomeTotals []CurrencyTotal `json:"incomeTotals,omitempty" doc:"Sum of the incomes of the matching categories, one per currency. totals sums their expenses"`
 }
 
 type ListCategoryOutput struct {
 	Body struct {
 		Data []CategoryResponse `json:"data" doc:"List of Categories"`
-		Meta struct {
-			Page  int `json:"page" doc:"Page number of pagination"`
-			Limit int `json:"limit" doc:"Limit per page of pagination"`
-		} `json:"meta"`
+		Meta CategoryPageMeta   `json:"meta"`
 	}
 }
 
@@ -76,16 +134,32 @@ func (c *CategoryHandler) ListCategory(ctx context.Context, input *ListCategoryI
 		Page:   input.Page,
 		Limit:  input.Limit,
 		Search: input.Search,
+		Cursor: input.Cursor,
+		Kind:   input.Kind,
+		All:    input.Tree,
 	})
 
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, huma.Error400BadRequest("Invalid cursor")
+	}
 	if err != nil {
 		return nil, huma.Error500InternalServerError("Failed to list categories", err)
 	}
 
 	resp := &ListCategoryOutput{}
-	resp.Body.Data = toCategoryResponseList(list)
+	resp.Body.Data = toCategoryResponseList(list.Categories)
 	resp.Body.Meta.Page = input.Page
 	resp.Body.Meta.Limit = input.Limit
+	if input.Tree {
+		resp.Body.Data = toCategoryTree(list.Categories)
+		resp.Body.Meta.Page = 1
+		resp.Body.Meta.Limit = len(list.Categories)
+	}
+	resp.Body.Meta.NextCursor = list.NextCursor
+	resp.Body.Meta.HasMore = list.HasMore
+	resp.Body.Meta.TotalCount = list.TotalCount
+	resp.Body.Meta.Totals = toCurrencyTotals(list.Totals)
+	resp.Body.Meta.IncomeTotals = toCurrencyTotals(list.IncomeTotals)
 
 	return resp, nil
 }
@@ -95,6 +169,7 @@ type UpdateCategoryInput struct {
 	Body       struct {
 		Name        string `json:"name" minLength:"2" maxLength:"255"`
 		Description string `json:"description,omitempty"`
+		ParentID    *int64 `json:"parentId,omitempty" doc:"Move the category under this one, or 0 for the top level. Omit to keep the current parent"`
 	}
 }
 
@@ -115,25 +190,42 @@ func (c *CategoryHandler) UpdateCategory(ctx context.Context, input *UpdateCateg
 		return nil, huma.Error400BadRequest("Failed to parse categoryID")
 	}
 
-	exist, err := c.categoryRepository.ExistWithUserID(ctx, database.ExistWithUserIDInput{
-		UserID:     int64(userID),
-		CategoryID: categoryID,
-	})
-	if err != nil {
-		return nil, err
-	}
-	if !exist {
-		return nil, huma.Error404NotFound("Category not found")
-	}
-
 	payload := &database.UpdateCategoryInput{CategoryID: categoryID, UserID: int64(userID), Name: input.Body.Name, Description: input.Body.Description}
 
-	err = c.categoryRepository.Update(ctx, *payload)
-	if err != nil {
-		return nil, huma.Error500InternalServerError("Failed to update category", err)
-	}
-
-	updatedCategory, err := c.categoryRepository.GetByID(ctx, categoryID)
+	var updatedCategory *database.Category
+	err = c.db.WithTx(ctx, func(tx database.Repositories) error {
+		exist, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{
+			UserID:     int64(userID),
+			CategoryID: categoryID,
+		})
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return huma.Error404NotFound("Category not found")
+		}
+
+		current, err := tx.CategoryRepository().GetByID(ctx, categoryID)
+		if err != nil {
+			return err
+		}
+
+		payload.ParentID = current.ParentID
+		if input.Body.ParentID != nil {
+			payload.ParentID, err = resolveParent(ctx, tx, int64(userID), categoryID, current.Kind, *input.Body.ParentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.CategoryRepository().Update(ctx, *payload)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to update category", err)
+		}
+
+		updatedCategory, err = tx.CategoryRepository().GetByID(ctx, categoryID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -143,11 +235,27 @@ func (c *CategoryHandler) UpdateCategory(ctx context.Context, input *UpdateCateg
 	return resp, nil
 }
 
+const (
+	DeleteCategoryKeep     = "keep"
+	DeleteCategoryBlock    = "block"
+	DeleteCategoryReassign = "reassign"
+	DeleteCategoryCascade  = "cascade"
+)
+
 type DeleteCategoryInput struct {
-	CategoryID string `path:"categoryId" doc:"Category ID"`
+	CategoryID       string `path:"categoryId" doc:"Category ID"`
+	Strategy         string `query:"strategy" enum:"keep,block,reassign,cascade" default:"keep" doc:"What happens to the category's expenses, or incomes for an income category: keep leaves them filed under the deleted category and in its overviews, block refuses while it has any, reassign moves them to targetCategoryId, cascade deletes them too until the category is restored"`
+	TargetCategoryID int64  `query:"targetCategoryId" doc:"Category of the same kind receiving the expenses or incomes when strategy is reassign"`
+}
+
+type DeletedCategoryOutput struct {
+	Body struct {
+		Strategy         string `json:"strategy" doc:"Strategy applied to the category's expenses"`
+		AffectedExpenses int64  `json:"affectedExpenses" doc:"Number of expenses, or incomes for an income category, kept, moved or deleted"`
+	}
 }
 
-func (c *CategoryHandler) DeleteCategory(ctx context.Context, input *DeleteCategoryInput) (*struct{}, error) {
+func (c *CategoryHandler) DeleteCategory(ctx context.Context, input *DeleteCategoryInput) (*DeletedCategoryOutput, error) {
 	userID, err := middleware.GetContextUserID(ctx)
 	if err != nil {
 		return nil, err
@@ -158,23 +266,166 @@ func (c *CategoryHandler) DeleteCategory(ctx context.Context, input *DeleteCateg
 		return nil, huma.Error400BadRequest("Failed to parse categoryID")
 	}
 
-	exist, err := c.categoryRepository.ExistWithUserID(ctx, database.ExistWithUserIDInput{
-		UserID:     int64(userID),
-		CategoryID: categoryID,
+	if input.Strategy == DeleteCategoryReassign && (input.TargetCategoryID == 0 || input.TargetCategoryID == categoryID) {
+		return nil, huma.Error400BadRequest("targetCategoryId must be another category when strategy is reassign")
+	}
+
+	var affected int64
+	err = c.db.WithTx(ctx, func(tx database.Repositories) error {
+		exist, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{
+			UserID:     int64(userID),
+			CategoryID: categoryID,
+		})
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return huma.Error404NotFound("Category not found")
+		}
+
+		category, err := tx.CategoryRepository().GetByID(ctx, categoryID)
+		if err != nil {
+			return err
+		}
+
+		// Subcategories move up a level rather than hang off a deleted category
+		if _, err := tx.CategoryRepository().ReparentChildren(ctx, categoryID, category.ParentID); err != nil {
+			return huma.Error500InternalServerError("Failed to move subcategories", err)
+		}
+
+		// The category goes first so entries deleted with it share its
+		// deletion time, which is how restoring it finds them
+		err = tx.CategoryRepository().Delete(ctx, categoryID)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to delete category", err)
+		}
+
+		entries, noun := categoryEntries(tx, category.Kind)
+
+		switch input.Strategy {
+		case DeleteCategoryKeep:
+			// Kept entries stay in overviews under the deleted category
+			affected, err = entries.CountByCategory(ctx, categoryID)
+			if err != nil {
+				return err
+			}
+		case DeleteCategoryReassign:
+			existTarget, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{
+				UserID:     int64(userID),
+				CategoryID: input.TargetCategoryID,
+				Kind:       category.Kind,
+			})
+			if err != nil {
+				return err
+			}
+			if !existTarget {
+				return huma.Error404NotFound("Target category not found")
+			}
+
+			affected, err = entries.ReassignCategory(ctx, categoryID, input.TargetCategoryID)
+			if err != nil {
+				return huma.Error500InternalServerError("Failed to reassign "+noun, err)
+			}
+		case DeleteCategoryCascade:
+			affected, err = entries.DeleteByCategory(ctx, categoryID)
+			if err != nil {
+				return huma.Error500InternalServerError("Failed to delete "+noun, err)
+			}
+		case DeleteCategoryBlock:
+			count, err := entries.CountByCategory(ctx, categoryID)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return huma.Error409Conflict(fmt.Sprintf("Category still has %d %s; use strategy reassign or cascade", count, noun))
+			}
+		}
+
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	if !exist {
-		return nil, huma.Error404NotFound("Category not found")
+
+	resp := &DeletedCategoryOutput{}
+	resp.Body.Strategy = input.Strategy
+	resp.Body.AffectedExpenses = affected
+	return resp, nil
+}
+
+// categoryEntryRepository is implemented by the repositories of the entries
+// a category files, expenses or incomes.
+type categoryEntryRepository interface {
+	CountByCategory(ctx context.Context, categoryID int64) (int64, error)
+	ReassignCategory(ctx context.Context, fromCategoryID, toCategoryID int64) (int64, error)
+	DeleteByCategory(ctx context.Context, categoryID int64) (int64, error)
+	RestoreByCategory(ctx context.Context, categoryID int64) error
+}
+
+// categoryEntries returns the repository of the entries filed under a
+// category of the given kind, and their plural name for messages.
+func categoryEntries(tx database.Repositories, kind string) (categoryEntryRepository, string) {
+	if kind == database.CategoryKindIncome {
+		return tx.IncomeRepository(), "incomes"
+	}
+	return tx.ExpenseRepository(), "expenses"
+}
+
+type RestoreCategoryInput struct {
+	CategoryID int64 `path:"categoryId" doc:"Category ID"`
+}
+
+type RestoredCategoryOutput struct {
+	Body struct {
+		Data CategoryResponse `json:"data" doc:"Category restored successfully"`
+	}
+}
+
+func (c *CategoryHandler) RestoreCategory(ctx context.Context, input *RestoreCategoryInput) (*RestoredCategoryOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	err = c.categoryRepository.Delete(ctx, categoryID)
+	var restoredCategory *database.Category
+	err = c.db.WithTx(ctx, func(tx database.Repositories) error {
+		exist, err := tx.CategoryRepository().ExistDeletedWithUserID(ctx, database.ExistWithUserIDInput{
+			UserID:     int64(userID),
+			CategoryID: input.CategoryID,
+		})
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return huma.Error404NotFound("Category not found in trash")
+		}
+
+		category, err := tx.CategoryRepository().GetByID(ctx, input.CategoryID)
+		if err != nil {
+			return err
+		}
+
+		// Whatever a cascade deleted comes back with the category
+		entries, noun := categoryEntries(tx, category.Kind)
+		if err := entries.RestoreByCategory(ctx, input.CategoryID); err != nil {
+			return huma.Error500InternalServerError("Failed to restore "+noun, err)
+		}
+
+		err = tx.CategoryRepository().Restore(ctx, input.CategoryID)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to restore category", err)
+		}
+
+		restoredCategory, err = tx.CategoryRepository().GetByID(ctx, input.CategoryID)
+		return err
+	})
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Failed to delete category", err)
+		return nil, err
 	}
 
-	return nil, nil
+	resp := &RestoredCategoryOutput{}
+	resp.Body.Data = toCategoryResponse(*restoredCategory)
+	return resp, nil
 }
 
 type DetailCategoryInput struct {
@@ -218,11 +469,21 @@ func (c *CategoryHandler) DetailCategory(ctx context.Context, input *DetailCateg
 }
 
 type CategoryResponse struct {
-	ID          int64     `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Kind        string             `json:"kind,omitempty" enum:"expense,income" doc:"Whether the category files expenses or incomes"`
+	ParentID    *int64             `json:"parentId" doc:"Parent category, null at the top level"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+	Children    []CategoryResponse `json:"children,omitempty" doc:"Subcategories, only filled in tree listings"`
+}
+
+func nullInt64Ptr(value sql.NullInt64) *int64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int64
 }
 
 func toCategoryResponse(category database.Category) CategoryResponse {
@@ -230,11 +491,47 @@ func toCategoryResponse(category database.Category) CategoryResponse {
 		ID:          category.ID,
 		Name:        category.Name,
 		Description: category.Description.String,
+		Kind:        category.Kind,
+		ParentID:    nullInt64Ptr(category.ParentID),
 		CreatedAt:   category.CreatedAt,
 		UpdatedAt:   category.UpdatedAt,
 	}
 }
 
+// toCategoryTree nests categories under their parents, keeping the given
+// order among siblings. A category whose parent is not in the list, e.g.
+// filtered out by a search, is shown at the top level.
+func toCategoryTree(categories []database.Category) []CategoryResponse {
+	listed := make(map[int64]bool, len(categories))
+	for _, category := range categories {
+		listed[category.ID] = true
+	}
+
+	roots := []database.Category{}
+	children := map[int64][]database.Category{}
+	for _, category := range categories {
+		if category.ParentID.Valid && listed[category.ParentID.Int64] {
+			children[category.ParentID.Int64] = append(children[category.ParentID.Int64], category)
+			continue
+		}
+		roots = append(roots, category)
+	}
+
+	var build func(nodes []database.Category) []CategoryResponse
+	build = func(nodes []database.Category) []CategoryResponse {
+		response := make([]CategoryResponse, len(nodes))
+		for index, node := range nodes {
+			response[index] = toCategoryResponse(node)
+			if nested, ok := children[node.ID]; ok {
+				response[index].Children = build(nested)
+			}
+		}
+		return response
+	}
+
+	return build(roots)
+}
+
 func toCategoryResponseList(categories []database.Category) []CategoryResponse {
 	response := make([]CategoryResponse, len(categories))
 	for index, category := range categories {
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"gastoslog/internal/currency"
+	"gastoslog/internal/database"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// resolveCurrency looks up the currency an amount is given in, defaulting to
+// the user's base currency when code is empty.
+func resolveCurrency(ctx context.Context, users database.UserRepository, userID int64, code string) (currency.Currency, error) {
+	if code == "" {
+		user, err := users.GetByID(ctx, userID)
+		if err != nil {
+			return currency.Currency{}, huma.Error500InternalServerError("Failed to get user", err)
+		}
+		code = user.BaseCurrency
+	}
+
+	resolved, err := currency.Lookup(code)
+	if err != nil {
+		return currency.Currency{}, huma.Error422UnprocessableEntity("Unknown currency " + code)
+	}
+	return resolved, nil
+}
+
+// baseConverter converts amounts into a user's base currency at the rate of
+// the day they occurred. Rates are looked up once per currency and day, and
+// amounts without a rate are set aside instead of failing the conversion.
+type baseConverter struct {
+	base  currency.Currency
+	rates database.ExchangeRateRepository
+	// table holds the rates read by preload, nil until then
+	table  *database.RateTable
+	cached map[string]cachedRate
+	// unconverted sums per currency the amounts that had no rate
+	unconverted map[string]int64
+}
+
+type cachedRate struct {
+	rate  float64
+	found bool
+}
+
+func newBaseConverter(ctx context.Context, users database.UserRepository, rates database.ExchangeRateRepository, userID int64) (*baseConverter, error) {
+	user, err := users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get user", err)
+	}
+
+	base, err := currency.Lookup(user.BaseCurrency)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Invalid base currency", err)
+	}
+
+	return &baseConverter{base: base, rates: rates, cached: map[string]cachedRate{}, unconverted: map[string]int64{}}, nil
+}
+
+// preload reads in one query the rates for converting amounts in codes that
+// occurred on days, each YYYY-MM-DD. Amounts outside of the first to the
+// last of them are still converted, looking their rate up on its own.
+func (b *baseConverter) preload(ctx context.Context, codes []string, days []string) error {
+	if len(codes) == 0 {
+		return nil
+	}
+
+	since, err := time.Parse("2006-01-02", slices.Min(days))
+	if err != nil {
+		return huma.Error500InternalServerError("Invalid expense day", err)
+	}
+	until, err := time.Parse("2006-01-02", slices.Max(days))
+	if err != nil {
+		return huma.Error500InternalServerError("Invalid expense day", err)
+	}
+
+	codes = append(codes, b.base.Code)
+	table, err := b.rates.Table(ctx, codes, since, until)
+	if err != nil {
+		return huma.Error500InternalServerError("Failed to get exchange rates", err)
+	}
+	b.table = table
+	return nil
+}
+
+// convert turns amount, in minor units of code, into minor units of the base
+// currency. day is formatted as YYYY-MM-DD. When no rate is dated on or
+// before day it returns 0 and false, and adds amount to the unconverted ones.
+func (b *baseConverter) convert(ctx context.Context, amount int64, code string, day string) (int64, bool, error) {
+	from, err := currency.Lookup(code)
+	if err != nil {
+		return 0, false, huma.Error500InternalServerError("Invalid expense currency", err)
+	}
+
+	key := from.Code + " " + day
+	cached, ok := b.cached[key]
+	if !ok {
+		on, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return 0, false, huma.Error500InternalServerError("Invalid expense day", err)
+		}
+
+		var rate float64
+		if b.table != nil && b.table.Covers(from.Code, on) {
+			rate, err = b.table.Rate(from.Code, b.base.Code, on)
+		} else {
+			rate, err = b.rates.Rate(ctx, from.Code, b.base.Code, on)
+		}
+		if err != nil && !errors.Is(err, database.ErrRateNotFound) {
+			return 0, false, huma.Error500InternalServerError("Failed to get exchange rate", err)
+		}
+
+		cached = cachedRate{rate: rate, found: err == nil}
+		b.cached[key] = cached
+	}
+
+	if !cached.found {
+		b.unconverted[from.Code] += amount
+		return 0, false, nil
+	}
+	return currency.Convert(amount, from, b.base, cached.rate), true, nil
+}
+
+// unconvertedTotals lists the amounts convert found no rate for, by currency.
+func (b *baseConverter) unconvertedTotals() []CurrencyTotal {
+	totals := []CurrencyTotal{}
+	for code, amount := range b.unconverted {
+		totals = append(totals, CurrencyTotal{Currency: code, Amount: amount})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Currency < totals[j].Currency
+	})
+	return totals
+}
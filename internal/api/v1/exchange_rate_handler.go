@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"gastoslog/internal/database"
+	"gastoslog/internal/exchangerate"
+	"gastoslog/internal/middleware"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type ExchangeRateHandler struct {
+	db                     database.Service
+	userRepository         database.UserRepository
+	exchangeRateRepository database.ExchangeRateRepository
+}
+
+func NewExchangeRateHandler(db database.Service) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		db:                     db,
+		userRepository:         db.UserRepository(),
+		exchangeRateRepository: db.ExchangeRateRepository(),
+	}
+}
+
+// requireAdmin returns 403 unless the session user has the admin role.
+func requireAdmin(ctx context.Context, users database.UserRepository) error {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := users.GetByID(ctx, int64(userID))
+	if err != nil {
+		return huma.Error404NotFound("User not found")
+	}
+	if user.Role != "admin" {
+		return huma.Error403Forbidden("Admin role required")
+	}
+
+	return nil
+}
+
+type ListExchangeRateInput struct {
+	Date string `query:"date" doc:"Day the rates are in effect (YYYY-MM-DD format). Defaults to today"`
+}
+
+type ListExchangeRateOutput struct {
+	Body struct {
+		Data []ExchangeRateResponse `json:"data" doc:"Latest rate of each currency pair on or before the date"`
+	}
+}
+
+type ExchangeRateResponse struct {
+	Date          string    `json:"date" doc:"Day the rate was published"`
+	BaseCurrency  string    `json:"baseCurrency"`
+	QuoteCurrency string    `json:"quoteCurrency"`
+	Rate          float64   `json:"rate" doc:"Price of one unit of baseCurrency in quoteCurrency"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func (h *ExchangeRateHandler) ListExchangeRates(ctx context.Context, input *ListExchangeRateInput) (*ListExchangeRateOutput, error) {
+	on := time.Now().UTC()
+	if input.Date != "" {
+		parsedDate, err := time.Parse("2006-01-02", input.Date)
+		if err != nil {
+			return nil, huma.Error400BadRequest("Invalid date format. Use YYYY-MM-DD")
+		}
+		on = parsedDate
+	}
+
+	rates, err := h.exchangeRateRepository.List(ctx, on)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list exchange rates", err)
+	}
+
+	resp := &ListExchangeRateOutput{}
+	resp.Body.Data = make([]ExchangeRateResponse, len(rates))
+	for index, rate := range rates {
+		resp.Body.Data[index] = ExchangeRateResponse{
+			Date:          rate.RateDate.Format("2006-01-02"),
+			BaseCurrency:  rate.BaseCurrency,
+			QuoteCurrency: rate.QuoteCurrency,
+			Rate:          rate.Rate,
+			UpdatedAt:     rate.UpdatedAt,
+		}
+	}
+
+	return resp, nil
+}
+
+type ImportExchangeRateInput struct {
+	Format  string `query:"format" enum:"ecb,csv" doc:"File format. Detected from the content when omitted"`
+	RawBody []byte `doc:"ECB euro reference rates XML, or CSV with a date,base,quote,rate header"`
+}
+
+type ImportExchangeRateOutput struct {
+	Body exchangerate.ImportResult
+}
+
+func (h *ExchangeRateHandler) ImportExchangeRates(ctx context.Context, input *ImportExchangeRateInput) (*ImportExchangeRateOutput, error) {
+	if err := requireAdmin(ctx, h.userRepository); err != nil {
+		return nil, err
+	}
+
+	var format exchangerate.Format
+	if input.Format != "" {
+		parsedFormat, err := exchangerate.ParseFormat(input.Format)
+		if err != nil {
+			return nil, huma.Error400BadRequest("Invalid format. Use ecb or csv")
+		}
+		format = parsedFormat
+	}
+
+	result, err := exchangerate.Import(ctx, h.db, bytes.NewReader(input.RawBody), format)
+	if errors.Is(err, exchangerate.ErrUnknownFormat) {
+		return nil, huma.Error400BadRequest("Invalid format. Use ecb or csv")
+	}
+	var parseErr *exchangerate.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, huma.Error422UnprocessableEntity("Failed to import exchange rates", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to import exchange rates", err)
+	}
+
+	resp := &ImportExchangeRateOutput{}
+	resp.Body = result
+	return resp, nil
+}
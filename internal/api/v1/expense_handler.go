@@ -2,8 +2,13 @@ package v1
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gastoslog/internal/currency"
 	"gastoslog/internal/database"
 	"gastoslog/internal/middleware"
+	"sort"
 	"strconv"
 	"time"
 
@@ -12,22 +17,157 @@ import (
 )
 
 type ExpenseHandler struct {
-	expenseRepository   database.ExpenseRepository
-	categoryRespository database.CategoryRepository
+	db                     database.Service
+	expenseRepository      database.ExpenseRepository
+	categoryRespository    database.CategoryRepository
+	userRepository         database.UserRepository
+	exchangeRateRepository database.ExchangeRateRepository
 }
 
-func NewExpenseHandler(expenseRepo database.ExpenseRepository, categoryRepo database.CategoryRepository) *ExpenseHandler {
-	return &ExpenseHandler{expenseRepository: expenseRepo, categoryRespository: categoryRepo}
+func NewExpenseHandler(db database.Service) *ExpenseHandler {
+	return &ExpenseHandler{
+		db:                     db,
+		expenseRepository:      db.ExpenseRepository(),
+		categoryRespository:    db.CategoryRepository(),
+		userRepository:         db.UserRepository(),
+		exchangeRateRepository: db.ExchangeRateRepository(),
+	}
 }
 
 type NewExpenseInput struct {
 	Body struct {
-		Amount      float64 `json:"amount" doc:"Expense amount" minimum:"1"`
-		Description string  `json:"description,omitempty" doc:"Expense description"`
-		CategoryID  int64   `json:"categoryId" doc:"Category ID"`
+		Amount      float64            `json:"amount" doc:"Expense amount in major units, e.g. 12.50" minimum:"1"`
+		Currency    string             `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code. Defaults to the user's base currency"`
+		Description string             `json:"description,omitempty" doc:"Expense description"`
+		CategoryID  int64              `json:"categoryId,omitempty" doc:"Category ID. Required unless the expense is split, which files it under its first line's category"`
+		OccurredAt  string             `json:"occurredAt,omitempty" doc:"When the expense happened, as YYYY-MM-DD or an RFC 3339 date-time. Defaults to now"`
+		Tags        []string           `json:"tags,omitempty" maxItems:"20" doc:"Tag names, e.g. work-trip. A leading # is ignored and new tags are created"`
+		WalletID    int64              `json:"walletId,omitempty" doc:"Wallet the expense was paid from. The currency then defaults to, and must match, the wallet's"`
+		GroupID     int64              `json:"groupId,omitempty" doc:"Share the expense with a group you are a member or owner of"`
+		Shares      *ExpenseShareInput `json:"shares,omitempty" doc:"Make the expense a bill you paid that members of its group owe shares of"`
+
+		Splits []ExpenseSplitInput `json:"splits,omitempty" maxItems:"50" doc:"Split the expense across categories. Line amounts must add up to the amount"`
 	}
 }
 
+type ExpenseSplitInput struct {
+	CategoryID  int64   `json:"categoryId" doc:"Category of the line"`
+	Amount      float64 `json:"amount" exclusiveMinimum:"0" doc:"Line amount in major units of the expense's currency"`
+	Description string  `json:"description,omitempty" doc:"What the line covers, e.g. detergent"`
+}
+
+// resolveSplits checks the split lines of an expense of amount, in minor
+// units of expenseCurrency, and returns the category the expense is filed
+// under along with the lines in minor units. Without lines the expense is
+// filed under categoryID.
+func resolveSplits(ctx context.Context, tx database.Repositories, userID, categoryID int64, amount int64, expenseCurrency currency.Currency, lines []ExpenseSplitInput) (int64, []database.NewExpenseSplitInput, error) {
+	if len(lines) == 0 {
+		if categoryID == 0 {
+			return 0, nil, huma.Error422UnprocessableEntity("categoryId is required unless the expense is split")
+		}
+		return categoryID, nil, checkCategory(ctx, tx, userID, categoryID, database.CategoryKindExpense)
+	}
+	if len(lines) == 1 {
+		return 0, nil, huma.Error422UnprocessableEntity("Split an expense into at least two lines, or give its categoryId instead")
+	}
+
+	splits := make([]database.NewExpenseSplitInput, len(lines))
+	var total int64
+	for i, line := range lines {
+		if err := checkCategory(ctx, tx, userID, line.CategoryID, database.CategoryKindExpense); err != nil {
+			return 0, nil, err
+		}
+
+		splits[i] = database.NewExpenseSplitInput{
+			CategoryID:  line.CategoryID,
+			Amount:      expenseCurrency.ToMinor(line.Amount),
+			Description: line.Description,
+		}
+		total += splits[i].Amount
+	}
+
+	if total != amount {
+		return 0, nil, huma.Error422UnprocessableEntity(fmt.Sprintf("Split lines add up to %.*f but the amount is %.*f",
+			expenseCurrency.MinorUnits, expenseCurrency.FromMinor(total), expenseCurrency.MinorUnits, expenseCurrency.FromMinor(amount)))
+	}
+
+	return splits[0].CategoryID, splits, nil
+}
+
+// parseTags validates and normalizes tag names from a request.
+func parseTags(names []string) ([]string, error) {
+	tags, err := database.NormalizeTagNames(names)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	return tags, nil
+}
+
+// setExpenseTags attaches the named tags to an expense, replacing its
+// current ones.
+func setExpenseTags(ctx context.Context, tx database.Repositories, userID, expenseID int64, names []string) error {
+	tags, err := tx.TagRepository().Ensure(ctx, userID, names)
+	if err != nil {
+		return huma.Error500InternalServerError("Failed to save tags", err)
+	}
+
+	tagIDs := make([]int64, len(tags))
+	for i, tag := range tags {
+		tagIDs[i] = tag.ID
+	}
+
+	if err := tx.TagRepository().SetExpenseTags(ctx, expenseID, tagIDs); err != nil {
+		return huma.Error500InternalServerError("Failed to save tags", err)
+	}
+	return nil
+}
+
+// parseOccurredAt accepts a plain date, stored as midnight UTC, or a full
+// RFC 3339 date-time. An empty value returns nil.
+func parseOccurredAt(value string) (*time.Time, error) {
+	return parseDateTime("occurredAt", value)
+}
+
+// parseDateTime parses the named field like parseOccurredAt.
+func parseDateTime(field string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return &date, nil
+	}
+
+	dateTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, huma.Error400BadRequest("Invalid " + field + ". Use YYYY-MM-DD or an RFC 3339 date-time")
+	}
+	return &dateTime, nil
+}
+
+// parseDateRange parses the optional from and to days of a list filter.
+func parseDateRange(fromValue, toValue string) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+	if fromValue != "" {
+		parsed, err := time.Parse("2006-01-02", fromValue)
+		if err != nil {
+			return nil, nil, huma.Error400BadRequest("Invalid from date. Use YYYY-MM-DD")
+		}
+		from = &parsed
+	}
+	if toValue != "" {
+		parsed, err := time.Parse("2006-01-02", toValue)
+		if err != nil {
+			return nil, nil, huma.Error400BadRequest("Invalid to date. Use YYYY-MM-DD")
+		}
+		to = &parsed
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		return nil, nil, huma.Error400BadRequest("The to date must not be before the from date")
+	}
+	return from, to, nil
+}
+
 type CreatedExpenseOutput struct {
 	Body struct {
 		Expense ExpenseResponse `json:"expense" doc:"Expense created successfully"`
@@ -40,20 +180,60 @@ func (c *ExpenseHandler) CreateExpense(ctx context.Context, input *NewExpenseInp
 		return nil, err
 	}
 
-	existCategory, err := c.categoryRespository.ExistWithUserID(ctx, database.ExistWithUserIDInput{CategoryID: input.Body.CategoryID, UserID: int64(userID)})
-	if err != nil || !existCategory {
-		return nil, huma.Error404NotFound("Category not found")
+	occurredAt, err := parseOccurredAt(input.Body.OccurredAt)
+	if err != nil {
+		return nil, err
 	}
 
-	amountCents := int64(input.Body.Amount * 100)
-	newExpenseInput := &database.NewExpenseInput{UserID: int64(userID), CategoryID: input.Body.CategoryID, Amount: amountCents, Description: input.Body.Description}
-
-	created, err := c.expenseRepository.Create(ctx, *newExpenseInput)
+	tags, err := parseTags(input.Body.Tags)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Failed to create expense", err)
+		return nil, err
 	}
 
-	createdExpense, err := c.expenseRepository.GetByID(ctx, created.ID)
+	var createdExpense *database.RawExpense
+	err = c.db.WithTx(ctx, func(tx database.Repositories) error {
+		walletID, expenseCurrency, err := resolveEntryCurrency(ctx, tx, int64(userID), input.Body.WalletID, input.Body.Currency)
+		if err != nil {
+			return err
+		}
+
+		amount := expenseCurrency.ToMinor(input.Body.Amount)
+		categoryID, splits, err := resolveSplits(ctx, tx, int64(userID), input.Body.CategoryID, amount, expenseCurrency, input.Body.Splits)
+		if err != nil {
+			return err
+		}
+
+		groupID, err := resolveGroup(ctx, tx, int64(userID), input.Body.GroupID)
+		if err != nil {
+			return err
+		}
+		shares, err := resolveShares(ctx, tx, groupID, amount, expenseCurrency, input.Body.Shares)
+		if err != nil {
+			return err
+		}
+
+		newExpenseInput := &database.NewExpenseInput{UserID: int64(userID), CategoryID: categoryID, Amount: amount, Currency: expenseCurrency.Code, Description: input.Body.Description, OccurredAt: occurredAt, WalletID: walletID, GroupID: groupID}
+
+		created, err := tx.ExpenseRepository().Create(ctx, *newExpenseInput)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create expense", err)
+		}
+
+		if err := tx.ExpenseRepository().SetSplits(ctx, created.ID, splits); err != nil {
+			return huma.Error500InternalServerError("Failed to save split lines", err)
+		}
+
+		if err := tx.ExpenseRepository().SetShares(ctx, created.ID, shares); err != nil {
+			return huma.Error500InternalServerError("Failed to save shares", err)
+		}
+
+		if err := setExpenseTags(ctx, tx, int64(userID), created.ID, tags); err != nil {
+			return err
+		}
+
+		createdExpense, err = tx.ExpenseRepository().GetByID(ctx, created.ID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -64,19 +244,23 @@ func (c *ExpenseHandler) CreateExpense(ctx context.Context, input *NewExpenseInp
 }
 
 type ListExpenseInput struct {
-	Page     int     `query:"page" default:"1" doc:"Page number of pagination"`
-	Limit    int     `query:"limit" default:"10" doc:"Limit per page of pagination"`
-	Date     string  `query:"date" doc:"Filter date for expense (YYYY-MM-DD format)"`
-	Category []int64 `query:"category" doc:"Filter category"`
+	Page     int      `query:"page" default:"1" doc:"Page number of pagination"`
+	Limit    int      `query:"limit" default:"10" doc:"Limit per page of pagination"`
+	Date     string   `query:"date" doc:"Filter by the day the expense occurred (YYYY-MM-DD format)"`
+	From     string   `query:"from" doc:"Only expenses that occurred on or after this day (YYYY-MM-DD format)"`
+	To       string   `query:"to" doc:"Only expenses that occurred on or before this day (YYYY-MM-DD format)"`
+	Category []int64  `query:"category" doc:"Filter category"`
+	Query    string   `query:"q" maxLength:"200" doc:"Search expense descriptions, matching word prefixes. Results are ranked by relevance"`
+	Cursor   string   `query:"cursor" doc:"Continue after the page that returned this nextCursor. Takes precedence over page"`
+	Tag      []string `query:"tag" doc:"Filter by tag name"`
+	TagMatch string   `query:"tagMatch" enum:"any,all" default:"any" doc:"Match expenses with any or all of the tag filters"`
+	Wallet   int64    `query:"wallet" doc:"Filter by the wallet the expense was paid from"`
 }
 
 type ListExpenseOutput struct {
 	Body struct {
 		Data []ExpenseResponse `json:"data" doc:"List of Expenses"`
-		Meta struct {
-			Page  int `json:"page" doc:"Page number of pagination"`
-			Limit int `json:"limit" doc:"Limit per page of pagination"`
-		} `json:"meta"`
+		Meta PageMeta          `json:"meta"`
 	}
 }
 
@@ -95,22 +279,47 @@ func (c *ExpenseHandler) ListExpense(ctx context.Context, input *ListExpenseInpu
 		date = &parsedDate
 	}
 
+	from, to, err := parseDateRange(input.From, input.To)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := parseTags(input.Tag)
+	if err != nil {
+		return nil, err
+	}
+
 	list, err := c.expenseRepository.List(ctx, database.ListExpenseInput{
 		UserID:   int64(userID),
 		Page:     input.Page,
 		Limit:    input.Limit,
 		Date:     date,
+		From:     from,
+		To:       to,
 		Category: input.Category,
+		Search:   input.Query,
+		Cursor:   input.Cursor,
+		WalletID: input.Wallet,
+
+		Tags:         tags,
+		MatchAllTags: input.TagMatch == "all",
 	})
 
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, huma.Error400BadRequest("Invalid cursor")
+	}
 	if err != nil {
 		return nil, huma.Error500InternalServerError("Failed to list expenses", err)
 	}
 
 	resp := &ListExpenseOutput{}
-	resp.Body.Data = toExpenseResponseList(list)
+	resp.Body.Data = toExpenseResponseList(list.Expenses)
 	resp.Body.Meta.Page = input.Page
 	resp.Body.Meta.Limit = input.Limit
+	resp.Body.Meta.NextCursor = list.NextCursor
+	resp.Body.Meta.HasMore = list.HasMore
+	resp.Body.Meta.TotalCount = list.TotalCount
+	resp.Body.Meta.Totals = toCurrencyTotals(list.Totals)
 
 	return resp, nil
 }
@@ -118,9 +327,17 @@ func (c *ExpenseHandler) ListExpense(ctx context.Context, input *ListExpenseInpu
 type UpdateExpenseInput struct {
 	ExpenseID string `path:"expenseId" doc:"Expense ID"`
 	Body      struct {
-		CategoryID  int64   `json:"categoryId" doc:"Expense category"`
-		Amount      float64 `json:"amount" minimum:"1"`
-		Description string  `json:"description,omitempty"`
+		CategoryID  int64              `json:"categoryId,omitempty" doc:"Expense category. Required unless the expense is split"`
+		Amount      float64            `json:"amount" minimum:"1" doc:"Expense amount in major units, e.g. 12.50"`
+		Currency    string             `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code. Unchanged when omitted, unless the expense moves to another wallet, whose currency it takes"`
+		Description string             `json:"description,omitempty"`
+		OccurredAt  string             `json:"occurredAt,omitempty" doc:"When the expense happened, as YYYY-MM-DD or an RFC 3339 date-time. Unchanged when omitted"`
+		Tags        []string           `json:"tags,omitempty" maxItems:"20" doc:"Replaces the expense's tags. Unchanged when omitted, an empty list removes them"`
+		WalletID    *int64             `json:"walletId,omitempty" doc:"Wallet the expense was paid from. Unchanged when omitted, 0 removes it"`
+		GroupID     *int64             `json:"groupId,omitempty" doc:"Group the expense is shared with. Unchanged when omitted, 0 makes it private"`
+		Shares      *ExpenseShareInput `json:"shares,omitempty" doc:"Replaces the shares of the bill. Omitting them makes it an ordinary expense"`
+
+		Splits []ExpenseSplitInput `json:"splits,omitempty" maxItems:"50" doc:"Replaces the split lines. Omitting them files the whole expense under categoryId"`
 	}
 }
 
@@ -136,36 +353,94 @@ func (c *ExpenseHandler) UpdateExpense(ctx context.Context, input *UpdateExpense
 		return nil, err
 	}
 
-	existCategory, err := c.categoryRespository.ExistWithUserID(ctx, database.ExistWithUserIDInput{CategoryID: input.Body.CategoryID, UserID: int64(userID)})
-	if err != nil || !existCategory {
-		return nil, huma.Error404NotFound("Category not found")
-	}
-
 	expenseID, err := strconv.ParseInt(input.ExpenseID, 10, 64)
 	if err != nil {
 		return nil, huma.Error400BadRequest("Failed to parse expenseID")
 	}
 
-	exist, err := c.expenseRepository.ExistWithUserID(ctx, database.ExistExpenseWithUserIDInput{
-		UserID:    int64(userID),
-		ExpenseID: expenseID,
-	})
+	occurredAt, err := parseOccurredAt(input.Body.OccurredAt)
 	if err != nil {
 		return nil, err
 	}
-	if !exist {
-		return nil, huma.Error404NotFound("Expense not found")
-	}
-
-	amountCents := int64(input.Body.Amount * 100)
-	payload := &database.UpdateExpenseInput{ExpenseID: expenseID, CategoryID: input.Body.CategoryID, UserID: int64(userID), Amount: amountCents, Description: input.Body.Description}
 
-	err = c.expenseRepository.Update(ctx, *payload)
+	tags, err := parseTags(input.Body.Tags)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Failed to update expense", err)
+		return nil, err
 	}
 
-	updatedExpense, err := c.expenseRepository.GetByID(ctx, expenseID)
+	var updatedExpense *database.RawExpense
+	err = c.db.WithTx(ctx, func(tx database.Repositories) error {
+		exist, err := tx.ExpenseRepository().ExistWithUserID(ctx, database.ExistExpenseWithUserIDInput{
+			UserID:    int64(userID),
+			ExpenseID: expenseID,
+		})
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return huma.Error404NotFound("Expense not found")
+		}
+
+		current, err := tx.ExpenseRepository().GetByID(ctx, expenseID)
+		if err != nil {
+			return err
+		}
+		walletID := current.WalletID.Int64
+		if input.Body.WalletID != nil {
+			walletID = *input.Body.WalletID
+		}
+
+		code := input.Body.Currency
+		if code == "" && walletID == current.WalletID.Int64 {
+			code = current.Currency
+		}
+		wallet, expenseCurrency, err := resolveEntryCurrency(ctx, tx, int64(userID), walletID, code)
+		if err != nil {
+			return err
+		}
+
+		amount := expenseCurrency.ToMinor(input.Body.Amount)
+		categoryID, splits, err := resolveSplits(ctx, tx, int64(userID), input.Body.CategoryID, amount, expenseCurrency, input.Body.Splits)
+		if err != nil {
+			return err
+		}
+
+		group := current.GroupID
+		if input.Body.GroupID != nil {
+			group, err = resolveGroup(ctx, tx, int64(userID), *input.Body.GroupID)
+			if err != nil {
+				return err
+			}
+		}
+		shares, err := resolveShares(ctx, tx, group, amount, expenseCurrency, input.Body.Shares)
+		if err != nil {
+			return err
+		}
+
+		payload := &database.UpdateExpenseInput{ExpenseID: expenseID, CategoryID: categoryID, UserID: int64(userID), Amount: amount, Currency: expenseCurrency.Code, Description: input.Body.Description, OccurredAt: occurredAt, WalletID: wallet, GroupID: group}
+
+		err = tx.ExpenseRepository().Update(ctx, *payload)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to update expense", err)
+		}
+
+		if err := tx.ExpenseRepository().SetSplits(ctx, expenseID, splits); err != nil {
+			return huma.Error500InternalServerError("Failed to save split lines", err)
+		}
+
+		if err := tx.ExpenseRepository().SetShares(ctx, expenseID, shares); err != nil {
+			return huma.Error500InternalServerError("Failed to save shares", err)
+		}
+
+		if input.Body.Tags != nil {
+			if err := setExpenseTags(ctx, tx, int64(userID), expenseID, tags); err != nil {
+				return err
+			}
+		}
+
+		updatedExpense, err = tx.ExpenseRepository().GetByID(ctx, expenseID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -209,6 +484,74 @@ func (c *ExpenseHandler) DeleteExpense(ctx context.Context, input *DeleteExpense
 	return nil, nil
 }
 
+type RestoreExpenseInput struct {
+	ExpenseID int64 `path:"expenseId" doc:"Expense ID"`
+}
+
+type RestoredExpenseOutput struct {
+	Body struct {
+		Data ExpenseResponse `json:"data" doc:"Expense restored successfully"`
+	}
+}
+
+func (c *ExpenseHandler) RestoreExpense(ctx context.Context, input *RestoreExpenseInput) (*RestoredExpenseOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var restoredExpense *database.RawExpense
+	err = c.db.WithTx(ctx, func(tx database.Repositories) error {
+		exist, err := tx.ExpenseRepository().ExistDeletedWithUserID(ctx, database.ExistExpenseWithUserIDInput{
+			UserID:    int64(userID),
+			ExpenseID: input.ExpenseID,
+		})
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return huma.Error404NotFound("Expense not found in trash")
+		}
+
+		err = tx.ExpenseRepository().Restore(ctx, input.ExpenseID)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to restore expense", err)
+		}
+
+		restoredExpense, err = tx.ExpenseRepository().GetByID(ctx, input.ExpenseID)
+		if err != nil {
+			return err
+		}
+
+		existCategory, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{CategoryID: restoredExpense.CategoryID, UserID: int64(userID)})
+		if err != nil {
+			return err
+		}
+		if !existCategory {
+			return huma.Error409Conflict("Restore the expense category first")
+		}
+
+		for _, split := range restoredExpense.Splits {
+			existCategory, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{CategoryID: split.CategoryID, UserID: int64(userID)})
+			if err != nil {
+				return err
+			}
+			if !existCategory {
+				return huma.Error409Conflict("Restore the category " + split.CategoryName + " of a split line first")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &RestoredExpenseOutput{}
+	resp.Body.Data = toExpenseResponse(*restoredExpense)
+	return resp, nil
+}
+
 type DetailExpenseInput struct {
 	ExpenseID int `path:"expenseId" doc:"Expense ID"`
 }
@@ -234,11 +577,17 @@ func (c *ExpenseHandler) DetailExpense(ctx context.Context, input *DetailExpense
 	if err != nil {
 		return nil, err
 	}
-	if !exist {
-		return nil, huma.Error404NotFound("Expense not found")
-	}
 
 	expense, err := c.expenseRepository.GetByID(ctx, expenseID)
+	if !exist {
+		// Members of the group an expense is shared with may see it too
+		if err != nil || !expense.GroupID.Valid {
+			return nil, huma.Error404NotFound("Expense not found")
+		}
+		if _, err := requireGroupRole(ctx, c.db.GroupRepository(), expense.GroupID.Int64, int64(userID), database.GroupRoleViewer); err != nil {
+			return nil, huma.Error404NotFound("Expense not found")
+		}
+	}
 	if err != nil {
 		return nil, huma.Error500InternalServerError("Failed to get expense", err)
 	}
@@ -251,58 +600,133 @@ func (c *ExpenseHandler) DetailExpense(ctx context.Context, input *DetailExpense
 type ExpenseOverviewInput struct {
 	Period string `query:"period" enum:"today,month,year" default:"today" doc:"Period for overview (today, month, year)"`
 	Date   string `query:"date" doc:"Custom date for overview (YYYY-MM-DD format)"`
+	Wallet int64  `query:"wallet" doc:"Only count entries paid from or into this wallet"`
+}
+
+const (
+	OverviewLevelLeaf = "leaf"
+	OverviewLevelTop  = "top"
+)
+
+type CategoryOverviewInput struct {
+	ExpenseOverviewInput
+	Level string `query:"level" enum:"leaf,top" default:"leaf" doc:"leaf reports the category each expense is logged to, top rolls subcategories up into their top-level category"`
 }
 
 type ExpenseOverviewOutput struct {
 	Body struct {
-		Data         []CategoryExpenseOverviewResponse `json:"data" doc:"Expense overview by category"`
-		OverviewMeta struct {
-			Period      string `json:"period" doc:"Period of the overview"`
-			TotalAmount int64  `json:"totalAmount" doc:"Total amount for the period"`
-			TotalCount  int64  `json:"totalCount" doc:"Total number of expenses for the period"`
-		} `json:"meta"`
+		Data          []CategoryExpenseOverviewResponse `json:"data" doc:"Expense overview by category. Budgeted categories are listed even without spending in the period"`
+		OverviewMeta  OverviewMeta                      `json:"meta"`
+		OverallBudget *BudgetUsage                      `json:"overallBudget,omitempty" doc:"Usage of the budget on all spending, when one is set"`
 	}
 }
 
+type OverviewMeta struct {
+	Period      string          `json:"period" doc:"Period of the overview"`
+	Currency    string          `json:"currency" doc:"User's base currency all totals are converted to"`
+	TotalAmount int64           `json:"totalAmount" doc:"Total of all expenses for the period, in minor units of the base currency"`
+	TotalCount  int64           `json:"totalCount" doc:"Total number of expenses for the period"`
+	Unconverted []CurrencyTotal `json:"unconverted,omitempty" doc:"Amounts left out of the totals for lack of an exchange rate, per currency in its minor units"`
+}
+
 type CategoryExpenseOverviewResponse struct {
 	CategoryID   int64   `json:"categoryId"`
 	CategoryName string  `json:"categoryName"`
-	TotalAmount  float64 `json:"totalAmount"`
+	ParentID     *int64  `json:"parentId,omitempty" doc:"Parent of a subcategory. Omitted at the top level"`
+	TotalAmount  float64 `json:"totalAmount" doc:"Total in major units of the base currency"`
 	Count        int64   `json:"count"`
 	Percentage   float64 `json:"percentage"`
+	// Budget fields are only present when the category has a budget
+	*BudgetUsage
+}
+
+// parseOverviewDate parses the optional YYYY-MM-DD date of an overview.
+func parseOverviewDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsedDate, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, huma.Error400BadRequest("Invalid date format. Use YYYY-MM-DD")
+	}
+	return &parsedDate, nil
 }
 
-func (c *ExpenseHandler) GetExpenseOverview(ctx context.Context, input *ExpenseOverviewInput) (*ExpenseOverviewOutput, error) {
+func (c *ExpenseHandler) GetExpenseOverview(ctx context.Context, input *CategoryOverviewInput) (*ExpenseOverviewOutput, error) {
 	userID, err := middleware.GetContextUserID(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var customDate *time.Time
-	if input.Date != "" {
-		parsedDate, err := time.Parse("2006-01-02", input.Date)
-		if err != nil {
-			return nil, huma.Error400BadRequest("Invalid date format. Use YYYY-MM-DD")
-		}
-		customDate = &parsedDate
+	customDate, err := parseOverviewDate(input.Date)
+	if err != nil {
+		return nil, err
 	}
 
-	overviews, err := c.expenseRepository.GetOverviewByCategory(ctx, int64(userID), input.Period, customDate)
+	overviews, err := c.expenseRepository.GetOverviewByCategory(ctx, int64(userID), input.Period, customDate, input.Wallet)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("Failed to get expense overview", err)
 	}
 
-	// Calculate totals
+	converter, err := newBaseConverter(ctx, c.userRepository, c.exchangeRateRepository, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	codes, days := []string{}, []string{}
+	for _, overview := range overviews {
+		codes = append(codes, overview.Currency)
+		days = append(days, overview.Day)
+	}
+	if err := converter.preload(ctx, codes, days); err != nil {
+		return nil, err
+	}
+
+	// Merge each category's per-currency rows into base currency totals.
+	// Rolling up only changes which category a row counts towards, so every
+	// expense is still counted once and percentages add up either way.
 	var totalAmount int64
 	var totalCount int64
+	categories := []database.CategoryExpenseOverview{}
+	indexByCategory := map[int64]int{}
 	for _, overview := range overviews {
-		totalAmount += overview.TotalAmount
-		totalCount += overview.Count
+		amount, _, err := converter.convert(ctx, overview.TotalAmount, overview.Currency, overview.Day)
+		if err != nil {
+			return nil, err
+		}
+
+		totalAmount += amount
+		totalCount += overview.ExpenseCount
+
+		if input.Level == OverviewLevelTop {
+			overview.CategoryID = overview.RootID
+			overview.CategoryName = overview.RootName
+			overview.ParentID = sql.NullInt64{}
+		}
+
+		index, ok := indexByCategory[overview.CategoryID]
+		if !ok {
+			index = len(categories)
+			indexByCategory[overview.CategoryID] = index
+			categories = append(categories, database.CategoryExpenseOverview{
+				CategoryID:   overview.CategoryID,
+				CategoryName: overview.CategoryName,
+				ParentID:     overview.ParentID,
+				Currency:     converter.base.Code,
+			})
+		}
+		categories[index].TotalAmount += amount
+		categories[index].Count += overview.Count
 	}
 
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].TotalAmount > categories[j].TotalAmount
+	})
+
 	// Convert to response format
-	responses := make([]CategoryExpenseOverviewResponse, len(overviews))
-	for i, overview := range overviews {
+	responses := make([]CategoryExpenseOverviewResponse, len(categories))
+	for i, overview := range categories {
 		percentage := 0.0
 		if totalAmount > 0 {
 			percentage = float64(overview.TotalAmount) / float64(totalAmount) * 100
@@ -311,29 +735,217 @@ func (c *ExpenseHandler) GetExpenseOverview(ctx context.Context, input *ExpenseO
 		responses[i] = CategoryExpenseOverviewResponse{
 			CategoryID:   overview.CategoryID,
 			CategoryName: overview.CategoryName,
-			TotalAmount:  float64(overview.TotalAmount) / 100, // Convert cents to dollars
+			ParentID:     nullInt64Ptr(overview.ParentID),
+			TotalAmount:  converter.base.FromMinor(overview.TotalAmount),
 			Count:        overview.Count,
 			Percentage:   percentage,
 		}
 	}
 
+	// Taken before the budgets, whose spending overlaps the overview's
+	unconverted := converter.unconvertedTotals()
+
+	on := time.Now()
+	if customDate != nil {
+		on = *customDate
+	}
+	budgets, err := trackBudgets(ctx, c.db, converter, int64(userID), on)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := &ExpenseOverviewOutput{}
+	for _, budget := range budgets {
+		usage := budget.usage
+		if budget.category == nil {
+			resp.Body.OverallBudget = &usage
+			continue
+		}
+
+		// Subcategory budgets have no row of their own once rolled up
+		if input.Level == OverviewLevelTop && budget.category.ParentID.Valid {
+			continue
+		}
+
+		found := false
+		for i := range responses {
+			if responses[i].CategoryID == budget.category.ID {
+				responses[i].BudgetUsage = &usage
+				found = true
+				break
+			}
+		}
+		if !found {
+			responses = append(responses, CategoryExpenseOverviewResponse{
+				CategoryID:   budget.category.ID,
+				CategoryName: budget.category.Name,
+				ParentID:     nullInt64Ptr(budget.category.ParentID),
+				BudgetUsage:  &usage,
+			})
+		}
+	}
+
 	resp.Body.Data = responses
 	resp.Body.OverviewMeta.Period = input.Period
+	resp.Body.OverviewMeta.Currency = converter.base.Code
 	resp.Body.OverviewMeta.TotalAmount = totalAmount
 	resp.Body.OverviewMeta.TotalCount = totalCount
+	resp.Body.OverviewMeta.Unconverted = unconverted
+
+	return resp, nil
+}
+
+type TagOverviewOutput struct {
+	Body struct {
+		Data         []TagExpenseOverviewResponse `json:"data" doc:"Expense overview by tag. Expenses with several tags count towards each"`
+		OverviewMeta OverviewMeta                 `json:"meta"`
+	}
+}
+
+type TagExpenseOverviewResponse struct {
+	TagID       int64   `json:"tagId"`
+	TagName     string  `json:"tagName"`
+	TotalAmount float64 `json:"totalAmount" doc:"Total in major units of the base currency"`
+	Count       int64   `json:"count"`
+	Percentage  float64 `json:"percentage" doc:"Share of all spending in the period"`
+}
+
+func (c *ExpenseHandler) GetTagOverview(ctx context.Context, input *ExpenseOverviewInput) (*TagOverviewOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	customDate, err := parseOverviewDate(input.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	tagOverviews, err := c.expenseRepository.GetOverviewByTag(ctx, int64(userID), input.Period, customDate, input.Wallet)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get tag overview", err)
+	}
+
+	// Tags overlap, so percentages are taken against all spending
+	categoryOverviews, err := c.expenseRepository.GetOverviewByCategory(ctx, int64(userID), input.Period, customDate, input.Wallet)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get expense overview", err)
+	}
+
+	converter, err := newBaseConverter(ctx, c.userRepository, c.exchangeRateRepository, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	codes, days := []string{}, []string{}
+	for _, overview := range categoryOverviews {
+		codes = append(codes, overview.Currency)
+		days = append(days, overview.Day)
+	}
+	if err := converter.preload(ctx, codes, days); err != nil {
+		return nil, err
+	}
+
+	var totalAmount int64
+	var totalCount int64
+	for _, overview := range categoryOverviews {
+		amount, _, err := converter.convert(ctx, overview.TotalAmount, overview.Currency, overview.Day)
+		if err != nil {
+			return nil, err
+		}
+		totalAmount += amount
+		totalCount += overview.ExpenseCount
+	}
+	// Tagged spending is part of the spending above, so it is only reported
+	// once
+	unconverted := converter.unconvertedTotals()
+
+	tags := []database.TagExpenseOverview{}
+	indexByTag := map[int64]int{}
+	for _, overview := range tagOverviews {
+		amount, _, err := converter.convert(ctx, overview.TotalAmount, overview.Currency, overview.Day)
+		if err != nil {
+			return nil, err
+		}
+
+		index, ok := indexByTag[overview.TagID]
+		if !ok {
+			index = len(tags)
+			indexByTag[overview.TagID] = index
+			tags = append(tags, database.TagExpenseOverview{
+				TagID:    overview.TagID,
+				TagName:  overview.TagName,
+				Currency: converter.base.Code,
+			})
+		}
+		tags[index].TotalAmount += amount
+		tags[index].Count += overview.Count
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].TotalAmount > tags[j].TotalAmount
+	})
+
+	responses := make([]TagExpenseOverviewResponse, len(tags))
+	for i, overview := range tags {
+		percentage := 0.0
+		if totalAmount > 0 {
+			percentage = float64(overview.TotalAmount) / float64(totalAmount) * 100
+		}
+
+		responses[i] = TagExpenseOverviewResponse{
+			TagID:       overview.TagID,
+			TagName:     overview.TagName,
+			TotalAmount: converter.base.FromMinor(overview.TotalAmount),
+			Count:       overview.Count,
+			Percentage:  percentage,
+		}
+	}
+
+	resp := &TagOverviewOutput{}
+	resp.Body.Data = responses
+	resp.Body.OverviewMeta.Period = input.Period
+	resp.Body.OverviewMeta.Currency = converter.base.Code
+	resp.Body.OverviewMeta.TotalAmount = totalAmount
+	resp.Body.OverviewMeta.TotalCount = totalCount
+	resp.Body.OverviewMeta.Unconverted = unconverted
 
 	return resp, nil
 }
 
 type ExpenseResponse struct {
-	ID          int64            `json:"id"`
-	Amount      int64            `json:"amount"`
-	Description null.String      `json:"description"`
-	CategoryID  int64            `json:"categoryId"`
-	Category    CategoryResponse `json:"category"`
-	CreatedAt   time.Time        `json:"createdAt"`
-	UpdatedAt   time.Time        `json:"updatedAt"`
+	ID          int64                  `json:"id"`
+	Amount      int64                  `json:"amount" doc:"Amount in minor units of currency"`
+	Currency    string                 `json:"currency" doc:"ISO 4217 code"`
+	Description null.String            `json:"description"`
+	CategoryID  int64                  `json:"categoryId"`
+	Category    CategoryResponse       `json:"category"`
+	OccurredAt  time.Time              `json:"occurredAt" doc:"When the expense happened"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	UpdatedAt   time.Time              `json:"updatedAt"`
+	Tags        []string               `json:"tags" doc:"Tag names in alphabetical order"`
+	Snippet     *string                `json:"snippet,omitempty" doc:"Description with search matches wrapped in <mark> tags"`
+	WalletID    *int64                 `json:"walletId" doc:"Wallet the expense was paid from, if any"`
+	UserID      int64                  `json:"userId" doc:"User who recorded the expense"`
+	GroupID     *int64                 `json:"groupId" doc:"Group the expense is shared with, if any"`
+	Splits      []ExpenseSplitResponse `json:"splits" doc:"Lines of an expense split across categories, empty when it is not split"`
+	Shares      []ExpenseShareResponse `json:"shares" doc:"What members of the group owe of a bill, empty when the expense is not a bill"`
+}
+
+type ExpenseShareResponse struct {
+	UserID  int64    `json:"userId"`
+	Email   string   `json:"email"`
+	Amount  int64    `json:"amount" doc:"Share in minor units of the expense's currency"`
+	Mode    string   `json:"mode" doc:"How the bill was divided: equal, exact or percent"`
+	Percent *float64 `json:"percent,omitempty" doc:"Percentage the share was given as"`
+}
+
+type ExpenseSplitResponse struct {
+	ID           int64  `json:"id"`
+	CategoryID   int64  `json:"categoryId"`
+	CategoryName string `json:"categoryName"`
+	Amount       int64  `json:"amount" doc:"Amount in minor units of the expense's currency"`
+	Description  string `json:"description"`
 }
 
 func toExpenseResponse(expense database.RawExpense) ExpenseResponse {
@@ -350,14 +962,52 @@ func toExpenseResponse(expense database.RawExpense) ExpenseResponse {
 		description = null.StringFrom(expense.Description.String)
 	}
 
+	var snippet *string
+	if expense.Snippet.Valid {
+		snippet = &expense.Snippet.String
+	}
+
+	splits := make([]ExpenseSplitResponse, len(expense.Splits))
+	for i, split := range expense.Splits {
+		splits[i] = ExpenseSplitResponse{
+			ID:           split.ID,
+			CategoryID:   split.CategoryID,
+			CategoryName: split.CategoryName,
+			Amount:       split.Amount,
+			Description:  split.Description,
+		}
+	}
+
+	shares := make([]ExpenseShareResponse, len(expense.Shares))
+	for i, share := range expense.Shares {
+		shares[i] = ExpenseShareResponse{
+			UserID: share.UserID,
+			Email:  share.Email,
+			Amount: share.Amount,
+			Mode:   share.Mode,
+		}
+		if share.Percent.Valid {
+			shares[i].Percent = &share.Percent.Float64
+		}
+	}
+
 	return ExpenseResponse{
 		ID:          expense.ID,
 		Amount:      expense.Amount,
+		Currency:    expense.Currency,
 		Description: description,
 		Category:    *category,
 		CategoryID:  expense.CategoryID,
+		OccurredAt:  expense.OccurredAt,
 		CreatedAt:   expense.CreatedAt,
 		UpdatedAt:   expense.UpdatedAt,
+		Tags:        expense.Tags,
+		Snippet:     snippet,
+		WalletID:    nullInt64Ptr(expense.WalletID),
+		UserID:      expense.UserID,
+		GroupID:     nullInt64Ptr(expense.GroupID),
+		Splits:      splits,
+		Shares:      shares,
 	}
 }
 
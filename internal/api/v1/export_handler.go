@@ -0,0 +1,365 @@
+package v1
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"gastoslog/internal/currency"
+	"gastoslog/internal/database"
+	"gastoslog/internal/journal"
+	"gastoslog/internal/middleware"
+	"gastoslog/internal/xlsx"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// exportPageSize is how many expenses an export reads from the database at
+// a time.
+const exportPageSize = 500
+
+type ExportHandler struct {
+	db                     database.Service
+	expenseRepository      database.ExpenseRepository
+	userRepository         database.UserRepository
+	exchangeRateRepository database.ExchangeRateRepository
+	walletRepository       database.WalletRepository
+}
+
+func NewExportHandler(db database.Service) *ExportHandler {
+	return &ExportHandler{
+		db:                     db,
+		expenseRepository:      db.ExpenseRepository(),
+		userRepository:         db.UserRepository(),
+		exchangeRateRepository: db.ExchangeRateRepository(),
+		walletRepository:       db.WalletRepository(),
+	}
+}
+
+// ExportFilterParams are the expense list filters an export accepts.
+type ExportFilterParams struct {
+	Date     string   `query:"date" doc:"Filter by the day the expense occurred (YYYY-MM-DD format)"`
+	From     string   `query:"from" doc:"Only expenses that occurred on or after this day (YYYY-MM-DD format)"`
+	To       string   `query:"to" doc:"Only expenses that occurred on or before this day (YYYY-MM-DD format)"`
+	Category []int64  `query:"category" doc:"Filter category. Only the matching lines of split expenses are exported"`
+	Query    string   `query:"q" maxLength:"200" doc:"Search expense descriptions, matching word prefixes"`
+	Tag      []string `query:"tag" doc:"Filter by tag name"`
+	TagMatch string   `query:"tagMatch" enum:"any,all" default:"any" doc:"Match expenses with any or all of the tag filters"`
+	Wallet   int64    `query:"wallet" doc:"Filter by the wallet the expense was paid from"`
+}
+
+type ExportExpenseInput struct {
+	Format string `query:"format" enum:"csv,xlsx" default:"csv" doc:"File format"`
+	ExportFilterParams
+}
+
+// exportLine is one exported row: an expense, or a line of a split expense.
+type exportLine struct {
+	expense     database.RawExpense
+	category    string
+	description string
+	amount      int64
+}
+
+// expenseLines returns the rows an expense is exported as. A split expense
+// gives one per line, keeping only the lines in categories when there are
+// any, the same lines that count towards the list's total.
+func expenseLines(expense database.RawExpense, categories []int64) []exportLine {
+	if len(expense.Splits) == 0 {
+		return []exportLine{{
+			expense:     expense,
+			category:    expense.CategoryName,
+			description: expense.Description.String,
+			amount:      expense.Amount,
+		}}
+	}
+
+	lines := []exportLine{}
+	for _, split := range expense.Splits {
+		if len(categories) > 0 && !slices.Contains(categories, split.CategoryID) {
+			continue
+		}
+		description := expense.Description.String
+		switch {
+		case description == "":
+			description = split.Description
+		case split.Description != "":
+			description += ": " + split.Description
+		}
+		lines = append(lines, exportLine{
+			expense:     expense,
+			category:    split.CategoryName,
+			description: description,
+			amount:      split.Amount,
+		})
+	}
+	return lines
+}
+
+// exportFilters turns the filters of an export into the list input that
+// reads its first page.
+func exportFilters(userID int64, input ExportFilterParams) (database.ListExpenseInput, error) {
+	var date *time.Time
+	if input.Date != "" {
+		parsedDate, err := time.Parse("2006-01-02", input.Date)
+		if err != nil {
+			return database.ListExpenseInput{}, huma.Error400BadRequest("Invalid date format. Use YYYY-MM-DD")
+		}
+		date = &parsedDate
+	}
+
+	from, to, err := parseDateRange(input.From, input.To)
+	if err != nil {
+		return database.ListExpenseInput{}, err
+	}
+
+	tags, err := parseTags(input.Tag)
+	if err != nil {
+		return database.ListExpenseInput{}, err
+	}
+
+	return database.ListExpenseInput{
+		UserID:   userID,
+		Page:     1,
+		Limit:    exportPageSize,
+		Date:     date,
+		From:     from,
+		To:       to,
+		Category: input.Category,
+		Search:   input.Query,
+		WalletID: input.Wallet,
+
+		Tags:         tags,
+		MatchAllTags: input.TagMatch == "all",
+	}, nil
+}
+
+// exportSheet writes the rows of an export in one file format.
+type exportSheet interface {
+	WriteRow(cells ...any) error
+	Close() error
+}
+
+// csvSheet writes an export as CSV.
+type csvSheet struct {
+	writer *csv.Writer
+}
+
+func (s *csvSheet) WriteRow(cells ...any) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch value := cell.(type) {
+		case nil:
+		case xlsx.Number:
+			record[i] = string(value)
+		case string:
+			// Spreadsheets run text starting with these as formulas
+			if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+				value = "'" + value
+			}
+			record[i] = value
+		default:
+			record[i] = fmt.Sprint(value)
+		}
+	}
+	return s.writer.Write(record)
+}
+
+func (s *csvSheet) Close() error {
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+func newExportSheet(format string, w io.Writer) (exportSheet, error) {
+	if format == "xlsx" {
+		return xlsx.NewWriter(w, "Expenses")
+	}
+	return &csvSheet{writer: csv.NewWriter(w)}, nil
+}
+
+var exportContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportExpense streams every expense matching the filters, page by page, so
+// exports of any size are never held in memory. Filters are checked before
+// anything is written; a failure after that ends the file early.
+func (h *ExportHandler) ExportExpense(ctx context.Context, input *ExportExpenseInput) (*huma.StreamResponse, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := exportFilters(int64(userID), input.ExportFilterParams)
+	if err != nil {
+		return nil, err
+	}
+
+	converter, err := newBaseConverter(ctx, h.userRepository, h.exchangeRateRepository, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	wallets, err := h.walletRepository.List(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list wallets", err)
+	}
+	walletNames := make(map[int64]string, len(wallets))
+	for _, wallet := range wallets {
+		walletNames[wallet.ID] = wallet.Name
+	}
+
+	page, err := h.expenseRepository.List(ctx, filters)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list expenses", err)
+	}
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			filename := fmt.Sprintf("expenses-%s.%s", time.Now().Format("2006-01-02"), input.Format)
+			hctx.SetHeader("Content-Type", exportContentTypes[input.Format])
+			hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+			sheet, err := newExportSheet(input.Format, hctx.BodyWriter())
+			if err != nil {
+				log.Printf("Failed to export expenses: %v", err)
+				return
+			}
+
+			err = sheet.WriteRow("ID", "Occurred At", "Category", "Description", "Amount", "Currency", "Amount ("+converter.base.Code+")", "Wallet", "Tags")
+			for err == nil {
+				err = writeExportPage(ctx, sheet, converter, walletNames, page.Expenses, filters.Category)
+				if err != nil || !page.HasMore {
+					break
+				}
+
+				filters.Cursor = page.NextCursor
+				page, err = h.expenseRepository.List(ctx, filters)
+			}
+			if err == nil {
+				err = sheet.Close()
+			}
+			if err != nil {
+				log.Printf("Failed to export expenses: %v", err)
+			}
+		},
+	}, nil
+}
+
+func writeExportPage(ctx context.Context, sheet exportSheet, converter *baseConverter, walletNames map[int64]string, expenses []database.RawExpense, categories []int64) error {
+	codes, days := []string{}, []string{}
+	for _, expense := range expenses {
+		codes = append(codes, expense.Currency)
+		days = append(days, expense.OccurredAt.UTC().Format("2006-01-02"))
+	}
+	if err := converter.preload(ctx, codes, days); err != nil {
+		return err
+	}
+
+	for _, expense := range expenses {
+		for _, line := range expenseLines(expense, categories) {
+			if err := writeExportLine(ctx, sheet, converter, walletNames, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeExportLine(ctx context.Context, sheet exportSheet, converter *baseConverter, walletNames map[int64]string, line exportLine) error {
+	expense := line.expense
+	cur, err := currency.Lookup(expense.Currency)
+	if err != nil {
+		return err
+	}
+
+	// Converted at the rate of the day, left empty when there is none
+	var baseAmount any
+	amount, ok, err := converter.convert(ctx, line.amount, cur.Code, expense.OccurredAt.UTC().Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	if ok {
+		baseAmount = majorUnits(amount, converter.base)
+	}
+
+	var wallet any
+	if expense.WalletID.Valid {
+		wallet = walletNames[expense.WalletID.Int64]
+	}
+
+	return sheet.WriteRow(
+		expense.ID,
+		expense.OccurredAt.UTC().Format(time.RFC3339),
+		line.category,
+		line.description,
+		majorUnits(line.amount, cur),
+		cur.Code,
+		baseAmount,
+		wallet,
+		strings.Join(expense.Tags, ", "),
+	)
+}
+
+// majorUnits formats amount, in minor units of cur, with exactly the
+// currency's decimals.
+func majorUnits(amount int64, cur currency.Currency) xlsx.Number {
+	return xlsx.Number(strconv.FormatFloat(cur.FromMinor(amount), 'f', cur.MinorUnits, 64))
+}
+
+type ExportJournalInput struct {
+	Format  string `query:"format" enum:"ledger,hledger,beancount" default:"hledger" doc:"Journal format"`
+	Account string `query:"account" default:"Assets:Cash" doc:"Account expenses paid from no wallet come from. Wallets use Assets:<name>, or Liabilities:<name> for credit cards"`
+	ExportFilterParams
+}
+
+var journalExtensions = map[journal.Format]string{
+	journal.FormatLedger:    "ledger",
+	journal.FormatHledger:   "journal",
+	journal.FormatBeancount: "beancount",
+}
+
+// ExportJournal streams the expenses matching the filters as a plain-text
+// accounting journal, oldest first.
+func (h *ExportHandler) ExportJournal(ctx context.Context, input *ExportJournalInput) (*huma.StreamResponse, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := exportFilters(int64(userID), input.ExportFilterParams)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := journal.ParseFormat(input.Format)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	if _, err := format.ParseAccount(input.Account); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			filename := fmt.Sprintf("expenses-%s.%s", time.Now().Format("2006-01-02"), journalExtensions[format])
+			hctx.SetHeader("Content-Type", "text/plain; charset=utf-8")
+			hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+			err := journal.Export(ctx, h.db, hctx.BodyWriter(), journal.Options{
+				Format:         format,
+				Filters:        filters,
+				DefaultAccount: input.Account,
+			})
+			if err != nil {
+				log.Printf("Failed to export journal: %v", err)
+			}
+		},
+	}, nil
+}
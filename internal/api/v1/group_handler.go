@@ -0,0 +1,838 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type GroupHandler struct {
+	db                     database.Service
+	groupRepository        database.GroupRepository
+	userRepository         database.UserRepository
+	exchangeRateRepository database.ExchangeRateRepository
+}
+
+func NewGroupHandler(db database.Service) *GroupHandler {
+	return &GroupHandler{
+		db:                     db,
+		groupRepository:        db.GroupRepository(),
+		userRepository:         db.UserRepository(),
+		exchangeRateRepository: db.ExchangeRateRepository(),
+	}
+}
+
+// requireGroupRole returns the user's membership of a group. It fails with
+// 404 when they are not a member, so groups of others stay hidden, and with
+// 403 when their role ranks below role.
+func requireGroupRole(ctx context.Context, groups database.GroupRepository, groupID, userID int64, role string) (*database.GroupMember, error) {
+	member, err := groups.GetMember(ctx, groupID, userID)
+	if errors.Is(err, database.ErrGroupMemberNotFound) {
+		return nil, huma.Error404NotFound("Group not found")
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get group member", err)
+	}
+
+	if database.GroupRoleRank[member.Role] < database.GroupRoleRank[role] {
+		return nil, huma.Error403Forbidden("Requires the " + role + " role in this group")
+	}
+	return member, nil
+}
+
+// resolveGroup checks that the user may share expenses with a group, which
+// takes the member role. A groupID of 0 keeps the expense private.
+func resolveGroup(ctx context.Context, tx database.Repositories, userID, groupID int64) (sql.NullInt64, error) {
+	if groupID == 0 {
+		return sql.NullInt64{}, nil
+	}
+
+	if _, err := requireGroupRole(ctx, tx.GroupRepository(), groupID, userID, database.GroupRoleMember); err != nil {
+		return sql.NullInt64{}, err
+	}
+	return sql.NullInt64{Int64: groupID, Valid: true}, nil
+}
+
+type GroupInputBody struct {
+	Name string `json:"name" minLength:"1" maxLength:"100" doc:"Group name, e.g. Home"`
+}
+
+type NewGroupInput struct {
+	Body GroupInputBody
+}
+
+type CreatedGroupOutput struct {
+	Body struct {
+		Data GroupResponse `json:"data" doc:"Group created successfully"`
+	}
+}
+
+func (h *GroupHandler) CreateGroup(ctx context.Context, input *NewGroupInput) (*CreatedGroupOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *database.Group
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		group, err := tx.GroupRepository().Create(ctx, strings.TrimSpace(input.Body.Name))
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create group", err)
+		}
+
+		// The creator owns the group
+		if err := tx.GroupRepository().SetMember(ctx, group.ID, int64(userID), database.GroupRoleOwner); err != nil {
+			return huma.Error500InternalServerError("Failed to add group owner", err)
+		}
+
+		created, err = tx.GroupRepository().GetByID(ctx, group.ID)
+		if err != nil {
+			return err
+		}
+		created.Role = database.GroupRoleOwner
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedGroupOutput{}
+	resp.Body.Data = toGroupResponse(*created)
+	return resp, nil
+}
+
+type ListGroupOutput struct {
+	Body struct {
+		Data []GroupResponse `json:"data" doc:"Groups the user belongs to, by name"`
+	}
+}
+
+func (h *GroupHandler) ListGroup(ctx context.Context, input *struct{}) (*ListGroupOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := h.groupRepository.ListForUser(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list groups", err)
+	}
+
+	resp := &ListGroupOutput{}
+	resp.Body.Data = make([]GroupResponse, len(groups))
+	for index, group := range groups {
+		resp.Body.Data[index] = toGroupResponse(group)
+	}
+	return resp, nil
+}
+
+type GroupIDInput struct {
+	GroupID int64 `path:"groupId" doc:"Group ID"`
+}
+
+type DetailGroupOutput struct {
+	Body struct {
+		Data    GroupResponse         `json:"data" doc:"Group detail"`
+		Members []GroupMemberResponse `json:"members" doc:"Members, owners first"`
+	}
+}
+
+func (h *GroupHandler) DetailGroup(ctx context.Context, input *GroupIDInput) (*DetailGroupOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := requireGroupRole(ctx, h.groupRepository, input.GroupID, int64(userID), database.GroupRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := h.groupRepository.GetByID(ctx, input.GroupID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get group", err)
+	}
+	group.Role = member.Role
+
+	members, err := h.groupRepository.ListMembers(ctx, input.GroupID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list group members", err)
+	}
+
+	resp := &DetailGroupOutput{}
+	resp.Body.Data = toGroupResponse(*group)
+	resp.Body.Members = make([]GroupMemberResponse, len(members))
+	for index, member := range members {
+		resp.Body.Members[index] = toGroupMemberResponse(member)
+	}
+	return resp, nil
+}
+
+type UpdateGroupInput struct {
+	GroupID int64 `path:"groupId" doc:"Group ID"`
+	Body    GroupInputBody
+}
+
+type UpdatedGroupOutput struct {
+	Body struct {
+		Data GroupResponse `json:"data" doc:"Group updated successfully"`
+	}
+}
+
+func (h *GroupHandler) UpdateGroup(ctx context.Context, input *UpdateGroupInput) (*UpdatedGroupOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := requireGroupRole(ctx, h.groupRepository, input.GroupID, int64(userID), database.GroupRoleOwner); err != nil {
+		return nil, err
+	}
+
+	if err := h.groupRepository.Update(ctx, input.GroupID, strings.TrimSpace(input.Body.Name)); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to update group", err)
+	}
+
+	group, err := h.groupRepository.GetByID(ctx, input.GroupID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get group", err)
+	}
+	group.Role = database.GroupRoleOwner
+
+	resp := &UpdatedGroupOutput{}
+	resp.Body.Data = toGroupResponse(*group)
+	return resp, nil
+}
+
+// DeleteGroup removes a group once its members are settled up. Expenses
+// shared with it become private to the members who paid them again.
+func (h *GroupHandler) DeleteGroup(ctx context.Context, input *GroupIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if _, err := requireGroupRole(ctx, tx.GroupRepository(), input.GroupID, int64(userID), database.GroupRoleOwner); err != nil {
+			return err
+		}
+
+		err := tx.GroupRepository().Delete(ctx, input.GroupID)
+		if errors.Is(err, database.ErrGroupNotSettled) {
+			return huma.Error409Conflict("Group has open balances; settle up before deleting it")
+		}
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to delete group", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type NewGroupInvitationInput struct {
+	GroupID int64 `path:"groupId" doc:"Group ID"`
+	Body    struct {
+		Email string `json:"email" format:"email" doc:"Email address of the user to invite"`
+		Role  string `json:"role,omitempty" enum:"owner,member,viewer" default:"member" doc:"Role the user gets on accepting"`
+	}
+}
+
+type CreatedGroupInvitationOutput struct {
+	Body struct {
+		Data GroupInvitationResponse `json:"data" doc:"Invitation created successfully"`
+	}
+}
+
+// InviteToGroup invites an email address to a group. Inviting the same
+// address again replaces the role of the pending invitation.
+func (h *GroupHandler) InviteToGroup(ctx context.Context, input *NewGroupInvitationInput) (*CreatedGroupInvitationOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	role := input.Body.Role
+	if role == "" {
+		role = database.GroupRoleMember
+	}
+
+	var created *database.GroupInvitation
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if _, err := requireGroupRole(ctx, tx.GroupRepository(), input.GroupID, int64(userID), database.GroupRoleOwner); err != nil {
+			return err
+		}
+
+		// Someone already in the group needs a role change, not an invitation
+		if invitee, err := tx.UserRepository().GetByEmail(ctx, input.Body.Email); err == nil {
+			_, err := tx.GroupRepository().GetMember(ctx, input.GroupID, invitee.ID)
+			if err == nil {
+				return huma.Error409Conflict(input.Body.Email + " is already a member of this group")
+			}
+			if !errors.Is(err, database.ErrGroupMemberNotFound) {
+				return huma.Error500InternalServerError("Failed to get group member", err)
+			}
+		}
+
+		var err error
+		created, err = tx.GroupRepository().CreateInvitation(ctx, database.NewGroupInvitationInput{
+			GroupID:   input.GroupID,
+			Email:     strings.TrimSpace(input.Body.Email),
+			Role:      role,
+			InvitedBy: int64(userID),
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create invitation", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedGroupInvitationOutput{}
+	resp.Body.Data = toGroupInvitationResponse(*created)
+	return resp, nil
+}
+
+type ListGroupInvitationOutput struct {
+	Body struct {
+		Data []GroupInvitationResponse `json:"data" doc:"Pending invitations, most recent first"`
+	}
+}
+
+func (h *GroupHandler) ListGroupInvitation(ctx context.Context, input *GroupIDInput) (*ListGroupInvitationOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := requireGroupRole(ctx, h.groupRepository, input.GroupID, int64(userID), database.GroupRoleOwner); err != nil {
+		return nil, err
+	}
+
+	invitations, err := h.groupRepository.ListInvitations(ctx, input.GroupID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list invitations", err)
+	}
+
+	return toListGroupInvitationOutput(invitations), nil
+}
+
+// ListMyGroupInvitation lists the invitations waiting for the user's email
+// address.
+func (h *GroupHandler) ListMyGroupInvitation(ctx context.Context, input *struct{}) (*ListGroupInvitationOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.userRepository.GetByID(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get user", err)
+	}
+
+	invitations, err := h.groupRepository.ListInvitationsForEmail(ctx, user.Email)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list invitations", err)
+	}
+
+	return toListGroupInvitationOutput(invitations), nil
+}
+
+func toListGroupInvitationOutput(invitations []database.GroupInvitation) *ListGroupInvitationOutput {
+	resp := &ListGroupInvitationOutput{}
+	resp.Body.Data = make([]GroupInvitationResponse, len(invitations))
+	for index, invitation := range invitations {
+		resp.Body.Data[index] = toGroupInvitationResponse(invitation)
+	}
+	return resp
+}
+
+type GroupInvitationIDInput struct {
+	InvitationID int64 `path:"invitationId" doc:"Invitation ID"`
+}
+
+// getInvitationFor loads an invitation addressed to the user, or fails with
+// 404.
+func getInvitationFor(ctx context.Context, tx database.Repositories, userID, invitationID int64) (*database.GroupInvitation, *database.User, error) {
+	user, err := tx.UserRepository().GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, huma.Error500InternalServerError("Failed to get user", err)
+	}
+
+	invitation, err := tx.GroupRepository().GetInvitation(ctx, invitationID)
+	if err != nil || !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, nil, huma.Error404NotFound("Invitation not found")
+	}
+	return invitation, user, nil
+}
+
+type AcceptedGroupInvitationOutput struct {
+	Body struct {
+		Data GroupResponse `json:"data" doc:"Group joined"`
+	}
+}
+
+func (h *GroupHandler) AcceptGroupInvitation(ctx context.Context, input *GroupInvitationIDInput) (*AcceptedGroupInvitationOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var joined *database.Group
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		invitation, _, err := getInvitationFor(ctx, tx, int64(userID), input.InvitationID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.GroupRepository().SetMember(ctx, invitation.GroupID, int64(userID), invitation.Role); err != nil {
+			return huma.Error500InternalServerError("Failed to join group", err)
+		}
+		if err := tx.GroupRepository().DeleteInvitation(ctx, invitation.ID); err != nil {
+			return huma.Error500InternalServerError("Failed to delete invitation", err)
+		}
+
+		joined, err = tx.GroupRepository().GetByID(ctx, invitation.GroupID)
+		if err != nil {
+			return err
+		}
+		joined.Role = invitation.Role
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &AcceptedGroupInvitationOutput{}
+	resp.Body.Data = toGroupResponse(*joined)
+	return resp, nil
+}
+
+// DeleteGroupInvitation declines an invitation addressed to the user, or
+// withdraws one as an owner of the group.
+func (h *GroupHandler) DeleteGroupInvitation(ctx context.Context, input *GroupInvitationIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if _, _, err := getInvitationFor(ctx, tx, int64(userID), input.InvitationID); err != nil {
+			invitation, getErr := tx.GroupRepository().GetInvitation(ctx, input.InvitationID)
+			if getErr != nil {
+				return err
+			}
+			if _, err := requireGroupRole(ctx, tx.GroupRepository(), invitation.GroupID, int64(userID), database.GroupRoleOwner); err != nil {
+				return huma.Error404NotFound("Invitation not found")
+			}
+		}
+
+		if err := tx.GroupRepository().DeleteInvitation(ctx, input.InvitationID); err != nil {
+			return huma.Error500InternalServerError("Failed to delete invitation", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type GroupMemberIDInput struct {
+	GroupID int64 `path:"groupId" doc:"Group ID"`
+	UserID  int64 `path:"userId" doc:"User ID of the member"`
+}
+
+type UpdateGroupMemberInput struct {
+	GroupID int64 `path:"groupId" doc:"Group ID"`
+	UserID  int64 `path:"userId" doc:"User ID of the member"`
+	Body    struct {
+		Role string `json:"role" enum:"owner,member,viewer" doc:"New role of the member"`
+	}
+}
+
+type UpdatedGroupMemberOutput struct {
+	Body struct {
+		Data GroupMemberResponse `json:"data" doc:"Member updated successfully"`
+	}
+}
+
+// checkLastOwner keeps a group from losing its last owner.
+func checkLastOwner(ctx context.Context, groups database.GroupRepository, member *database.GroupMember) error {
+	if member.Role != database.GroupRoleOwner {
+		return nil
+	}
+
+	owners, err := groups.CountOwners(ctx, member.GroupID)
+	if err != nil {
+		return huma.Error500InternalServerError("Failed to count group owners", err)
+	}
+	if owners <= 1 {
+		return huma.Error409Conflict("A group needs an owner; make another member an owner or delete the group")
+	}
+	return nil
+}
+
+func (h *GroupHandler) UpdateGroupMember(ctx context.Context, input *UpdateGroupMemberInput) (*UpdatedGroupMemberOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *database.GroupMember
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		groups := tx.GroupRepository()
+		if _, err := requireGroupRole(ctx, groups, input.GroupID, int64(userID), database.GroupRoleOwner); err != nil {
+			return err
+		}
+
+		member, err := groups.GetMember(ctx, input.GroupID, input.UserID)
+		if errors.Is(err, database.ErrGroupMemberNotFound) {
+			return huma.Error404NotFound("Member not found")
+		}
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to get group member", err)
+		}
+
+		if input.Body.Role != database.GroupRoleOwner {
+			if err := checkLastOwner(ctx, groups, member); err != nil {
+				return err
+			}
+		}
+
+		if err := groups.SetMember(ctx, input.GroupID, input.UserID, input.Body.Role); err != nil {
+			return huma.Error500InternalServerError("Failed to update group member", err)
+		}
+
+		updated, err = groups.GetMember(ctx, input.GroupID, input.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UpdatedGroupMemberOutput{}
+	resp.Body.Data = toGroupMemberResponse(*updated)
+	return resp, nil
+}
+
+// RemoveGroupMember lets an owner remove a member, or any member leave, once
+// they are settled up. The member's expenses shared with the group become
+// private again, apart from bills.
+func (h *GroupHandler) RemoveGroupMember(ctx context.Context, input *GroupMemberIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		groups := tx.GroupRepository()
+		role := database.GroupRoleOwner
+		if input.UserID == int64(userID) {
+			role = database.GroupRoleViewer
+		}
+		if _, err := requireGroupRole(ctx, groups, input.GroupID, int64(userID), role); err != nil {
+			return err
+		}
+
+		member, err := groups.GetMember(ctx, input.GroupID, input.UserID)
+		if errors.Is(err, database.ErrGroupMemberNotFound) {
+			return huma.Error404NotFound("Member not found")
+		}
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to get group member", err)
+		}
+
+		if err := checkLastOwner(ctx, groups, member); err != nil {
+			return err
+		}
+		if err := checkSettled(ctx, groups, input.GroupID, input.UserID); err != nil {
+			return err
+		}
+
+		if err := groups.RemoveMember(ctx, input.GroupID, input.UserID); err != nil {
+			return huma.Error500InternalServerError("Failed to remove group member", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type ListGroupExpenseInput struct {
+	GroupID  int64   `path:"groupId" doc:"Group ID"`
+	Page     int     `query:"page" default:"1" doc:"Page number of pagination"`
+	Limit    int     `query:"limit" default:"10" doc:"Limit per page of pagination"`
+	Query    string  `query:"q" maxLength:"200" doc:"Search expense descriptions, matching word prefixes"`
+	Cursor   string  `query:"cursor" doc:"Continue after the page that returned this nextCursor. Takes precedence over page"`
+	Category []int64 `query:"category" doc:"Filter category"`
+}
+
+type ListGroupExpenseOutput struct {
+	Body struct {
+		Data []ExpenseResponse `json:"data" doc:"Expenses members shared with the group"`
+		Meta PageMeta          `json:"meta"`
+	}
+}
+
+func (h *GroupHandler) ListGroupExpense(ctx context.Context, input *ListGroupExpenseInput) (*ListGroupExpenseOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := requireGroupRole(ctx, h.groupRepository, input.GroupID, int64(userID), database.GroupRoleViewer); err != nil {
+		return nil, err
+	}
+
+	list, err := h.db.ExpenseRepository().List(ctx, database.ListExpenseInput{
+		GroupID:  input.GroupID,
+		Page:     input.Page,
+		Limit:    input.Limit,
+		Search:   input.Query,
+		Cursor:   input.Cursor,
+		Category: input.Category,
+	})
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, huma.Error400BadRequest("Invalid cursor")
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list group expenses", err)
+	}
+
+	resp := &ListGroupExpenseOutput{}
+	resp.Body.Data = toExpenseResponseList(list.Expenses)
+	resp.Body.Meta.Page = input.Page
+	resp.Body.Meta.Limit = input.Limit
+	resp.Body.Meta.NextCursor = list.NextCursor
+	resp.Body.Meta.HasMore = list.HasMore
+	resp.Body.Meta.TotalCount = list.TotalCount
+	resp.Body.Meta.Totals = toCurrencyTotals(list.Totals)
+
+	return resp, nil
+}
+
+type GroupOverviewInput struct {
+	GroupID int64  `path:"groupId" doc:"Group ID"`
+	Period  string `query:"period" enum:"today,month,year" default:"month" doc:"Period for overview (today, month, year)"`
+	Date    string `query:"date" doc:"Custom date for overview (YYYY-MM-DD format)"`
+}
+
+type GroupOverviewOutput struct {
+	Body struct {
+		ByCategory []GroupCategoryOverviewResponse `json:"byCategory" doc:"Shared spending by category name, combining members' categories of the same name"`
+		ByMember   []GroupMemberOverviewResponse   `json:"byMember" doc:"Shared spending by the member who paid"`
+		Meta       OverviewMeta                    `json:"meta"`
+	}
+}
+
+type GroupCategoryOverviewResponse struct {
+	CategoryName string  `json:"categoryName"`
+	TotalAmount  float64 `json:"totalAmount" doc:"Total in major units of the base currency"`
+	Count        int64   `json:"count"`
+	Percentage   float64 `json:"percentage"`
+}
+
+type GroupMemberOverviewResponse struct {
+	UserID      int64   `json:"userId"`
+	Email       string  `json:"email"`
+	TotalAmount float64 `json:"totalAmount" doc:"Total in major units of the base currency"`
+	Count       int64   `json:"count"`
+	Percentage  float64 `json:"percentage"`
+}
+
+// GetGroupOverview totals the period's expenses shared with the group by
+// category and by member, converted to the requesting user's base currency.
+func (h *GroupHandler) GetGroupOverview(ctx context.Context, input *GroupOverviewInput) (*GroupOverviewOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := requireGroupRole(ctx, h.groupRepository, input.GroupID, int64(userID), database.GroupRoleViewer); err != nil {
+		return nil, err
+	}
+
+	customDate, err := parseOverviewDate(input.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory, err := h.groupRepository.GetOverviewByCategory(ctx, input.GroupID, input.Period, customDate)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get group overview", err)
+	}
+	byMember, err := h.groupRepository.GetOverviewByMember(ctx, input.GroupID, input.Period, customDate)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get group overview", err)
+	}
+
+	converter, err := newBaseConverter(ctx, h.userRepository, h.exchangeRateRepository, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	codes, days := []string{}, []string{}
+	for _, overview := range byCategory {
+		codes = append(codes, overview.Currency)
+		days = append(days, overview.Day)
+	}
+	if err := converter.preload(ctx, codes, days); err != nil {
+		return nil, err
+	}
+
+	// Merge the per-currency, per-day rows into base currency totals
+	var totalAmount int64
+	var totalCount int64
+	categories := []GroupCategoryOverviewResponse{}
+	categoryAmounts := []int64{}
+	indexByCategory := map[string]int{}
+	for _, overview := range byCategory {
+		amount, _, err := converter.convert(ctx, overview.TotalAmount, overview.Currency, overview.Day)
+		if err != nil {
+			return nil, err
+		}
+
+		totalAmount += amount
+		totalCount += overview.ExpenseCount
+
+		key := strings.ToLower(overview.CategoryName)
+		index, ok := indexByCategory[key]
+		if !ok {
+			index = len(categories)
+			indexByCategory[key] = index
+			categories = append(categories, GroupCategoryOverviewResponse{CategoryName: overview.CategoryName})
+			categoryAmounts = append(categoryAmounts, 0)
+		}
+		categoryAmounts[index] += amount
+		categories[index].Count += overview.Count
+	}
+
+	// The same expenses again, by member, so reported once
+	unconverted := converter.unconvertedTotals()
+
+	members := []GroupMemberOverviewResponse{}
+	memberAmounts := []int64{}
+	indexByMember := map[int64]int{}
+	for _, overview := range byMember {
+		amount, _, err := converter.convert(ctx, overview.TotalAmount, overview.Currency, overview.Day)
+		if err != nil {
+			return nil, err
+		}
+
+		index, ok := indexByMember[overview.UserID]
+		if !ok {
+			index = len(members)
+			indexByMember[overview.UserID] = index
+			members = append(members, GroupMemberOverviewResponse{UserID: overview.UserID, Email: overview.Email})
+			memberAmounts = append(memberAmounts, 0)
+		}
+		memberAmounts[index] += amount
+		members[index].Count += overview.Count
+	}
+
+	percentage := func(amount int64) float64 {
+		if totalAmount <= 0 {
+			return 0
+		}
+		return float64(amount) / float64(totalAmount) * 100
+	}
+	for i := range categories {
+		categories[i].TotalAmount = converter.base.FromMinor(categoryAmounts[i])
+		categories[i].Percentage = percentage(categoryAmounts[i])
+	}
+	for i := range members {
+		members[i].TotalAmount = converter.base.FromMinor(memberAmounts[i])
+		members[i].Percentage = percentage(memberAmounts[i])
+	}
+
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].TotalAmount > categories[j].TotalAmount
+	})
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].TotalAmount > members[j].TotalAmount
+	})
+
+	resp := &GroupOverviewOutput{}
+	resp.Body.ByCategory = categories
+	resp.Body.ByMember = members
+	resp.Body.Meta.Period = input.Period
+	resp.Body.Meta.Currency = converter.base.Code
+	resp.Body.Meta.TotalAmount = totalAmount
+	resp.Body.Meta.TotalCount = totalCount
+	resp.Body.Meta.Unconverted = unconverted
+
+	return resp, nil
+}
+
+type GroupResponse struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Role        string    `json:"role" doc:"The requesting user's role: owner, member or viewer"`
+	MemberCount int64     `json:"memberCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func toGroupResponse(group database.Group) GroupResponse {
+	return GroupResponse{
+		ID:          group.ID,
+		Name:        group.Name,
+		Role:        group.Role,
+		MemberCount: group.MemberCount,
+		CreatedAt:   group.CreatedAt,
+		UpdatedAt:   group.UpdatedAt,
+	}
+}
+
+type GroupMemberResponse struct {
+	UserID   int64     `json:"userId"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+func toGroupMemberResponse(member database.GroupMember) GroupMemberResponse {
+	return GroupMemberResponse{
+		UserID:   member.UserID,
+		Email:    member.Email,
+		Role:     member.Role,
+		JoinedAt: member.CreatedAt,
+	}
+}
+
+type GroupInvitationResponse struct {
+	ID        int64     `json:"id"`
+	GroupID   int64     `json:"groupId"`
+	GroupName string    `json:"groupName"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func toGroupInvitationResponse(invitation database.GroupInvitation) GroupInvitationResponse {
+	return GroupInvitationResponse{
+		ID:        invitation.ID,
+		GroupID:   invitation.GroupID,
+		GroupName: invitation.GroupName,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
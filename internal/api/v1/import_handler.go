@@ -0,0 +1,339 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"gastoslog/internal/currency"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"gastoslog/internal/statement"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// maxStatementBytes caps the size of an uploaded statement.
+const maxStatementBytes = 5 << 20
+
+// Statuses of a previewed statement row
+const (
+	importRowExpense   = "expense"
+	importRowInflow    = "inflow"
+	importRowInvalid   = "invalid"
+	importRowDuplicate = "duplicate"
+)
+
+type ImportHandler struct {
+	db                      database.Service
+	importProfileRepository database.ImportProfileRepository
+}
+
+func NewImportHandler(db database.Service) *ImportHandler {
+	return &ImportHandler{
+		db:                      db,
+		importProfileRepository: db.ImportProfileRepository(),
+	}
+}
+
+type PreviewCSVImportInput struct {
+	ProfileID int64 `query:"profileId" required:"true" doc:"Import profile describing the file's layout"`
+	RawBody   huma.MultipartFormFiles[struct {
+		File huma.FormFile `form:"file" contentType:"text/csv,text/plain" required:"true" doc:"Bank or e-wallet CSV statement, up to 5 MB"`
+	}]
+}
+
+type PreviewImportOutput struct {
+	Body struct {
+		Data []ImportRowResponse `json:"data" doc:"Rows of the statement in file order"`
+		Meta ImportPreviewMeta   `json:"meta"`
+	}
+}
+
+type ImportRowResponse struct {
+	Line        int        `json:"line" doc:"1-based line of the row in the file"`
+	Status      string     `json:"status" enum:"expense,inflow,invalid,duplicate" doc:"Only expense rows can be imported. Inflows are money coming in, such as a salary or a refund. Duplicates were imported before"`
+	FITID       string     `json:"fitid,omitempty" doc:"Bank's ID of an OFX transaction"`
+	OccurredAt  *time.Time `json:"occurredAt" doc:"Null when the date could not be read"`
+	Amount      int64      `json:"amount" doc:"Amount in minor units of the profile's currency, positive for expenses and inflows alike"`
+	Description string     `json:"description"`
+	Error       string     `json:"error,omitempty" doc:"Why an invalid row could not be read"`
+}
+
+type ImportPreviewMeta struct {
+	Currency    string `json:"currency" doc:"Currency the rows are imported in"`
+	WalletID    *int64 `json:"walletId" doc:"Wallet the imported expenses are paid from"`
+	Account     string `json:"account,omitempty" doc:"Key of the OFX statement's account, to pass on when committing"`
+	Expenses    int    `json:"expenses" doc:"Number of rows that can be imported"`
+	Inflows     int    `json:"inflows"`
+	Invalid     int    `json:"invalid"`
+	Duplicates  int    `json:"duplicates"`
+	TotalAmount int64  `json:"totalAmount" doc:"Sum of the expense rows in minor units"`
+}
+
+// previewRows describes parsed statement rows in rowCurrency. Rows whose
+// FITID is in imported, or repeats an earlier row's, are duplicates.
+func previewRows(rows []statement.Row, rowCurrency currency.Currency, imported map[string]bool) ([]ImportRowResponse, ImportPreviewMeta) {
+	previews := make([]ImportRowResponse, len(rows))
+	meta := ImportPreviewMeta{Currency: rowCurrency.Code}
+	for index, row := range rows {
+		previewed := ImportRowResponse{
+			Line:        row.Line,
+			Status:      importRowExpense,
+			FITID:       row.FITID,
+			Amount:      rowCurrency.ToMinor(row.Amount),
+			Description: row.Description,
+		}
+		if !row.Date.IsZero() {
+			previewed.OccurredAt = &row.Date
+		}
+
+		switch {
+		case row.FITID != "" && imported[row.FITID]:
+			previewed.Status = importRowDuplicate
+			meta.Duplicates++
+		case row.Err != nil:
+			previewed.Status = importRowInvalid
+			previewed.Error = row.Err.Error()
+			meta.Invalid++
+		case row.Inflow:
+			previewed.Status = importRowInflow
+			meta.Inflows++
+		default:
+			meta.Expenses++
+			meta.TotalAmount += previewed.Amount
+		}
+
+		if row.FITID != "" {
+			imported[row.FITID] = true
+		}
+		previews[index] = previewed
+	}
+
+	return previews, meta
+}
+
+// PreviewCSVImport reads a statement without saving anything, so the user can
+// review its rows and pick a category for each before committing them.
+func (h *ImportHandler) PreviewCSVImport(ctx context.Context, input *PreviewCSVImportInput) (*PreviewImportOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := getOwnedImportProfile(ctx, h.importProfileRepository, int64(userID), input.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+
+	profileCurrency, err := currency.Lookup(profile.Currency)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Invalid import profile currency", err)
+	}
+
+	file := input.RawBody.Data().File
+	defer file.Close()
+	if file.Size > maxStatementBytes {
+		return nil, huma.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Statements are limited to %d MB", maxStatementBytes>>20))
+	}
+
+	rows, err := statement.ParseCSV(file, profileMapping(*profile))
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity("Failed to read statement", err)
+	}
+
+	resp := &PreviewImportOutput{}
+	resp.Body.Data, resp.Body.Meta = previewRows(rows, profileCurrency, map[string]bool{})
+	resp.Body.Meta.WalletID = nullInt64Ptr(profile.WalletID)
+	return resp, nil
+}
+
+type PreviewOFXImportInput struct {
+	WalletID int64 `query:"walletId" doc:"Wallet the statement belongs to. Imported expenses are paid from it, and its currency must match the statement's"`
+	RawBody  huma.MultipartFormFiles[struct {
+		File huma.FormFile `form:"file" contentType:"application/x-ofx,application/vnd.intu.qfx,text/plain" required:"true" doc:"OFX or QFX statement, up to 5 MB"`
+	}]
+}
+
+// PreviewOFXImport reads an OFX or QFX statement without saving anything.
+// Transactions already imported from the same account are marked as
+// duplicates.
+func (h *ImportHandler) PreviewOFXImport(ctx context.Context, input *PreviewOFXImportInput) (*PreviewImportOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	file := input.RawBody.Data().File
+	defer file.Close()
+	if file.Size > maxStatementBytes {
+		return nil, huma.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Statements are limited to %d MB", maxStatementBytes>>20))
+	}
+
+	parsed, err := statement.ParseOFX(file)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity("Failed to read statement", err)
+	}
+
+	walletID, statementCurrency, err := resolveEntryCurrency(ctx, h.db, int64(userID), input.WalletID, parsed.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	imported, err := importedFITIDs(ctx, h.db, int64(userID), parsed.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &PreviewImportOutput{}
+	resp.Body.Data, resp.Body.Meta = previewRows(parsed.Rows, statementCurrency, imported)
+	resp.Body.Meta.WalletID = nullInt64Ptr(walletID)
+	resp.Body.Meta.Account = parsed.Account
+	return resp, nil
+}
+
+// importedFITIDs returns the set of the account's transactions already
+// imported by the user.
+func importedFITIDs(ctx context.Context, repos database.Repositories, userID int64, account string) (map[string]bool, error) {
+	fitids, err := repos.ExpenseRepository().ListImportedFITIDs(ctx, userID, account)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list imported transactions", err)
+	}
+
+	imported := make(map[string]bool, len(fitids))
+	for _, fitid := range fitids {
+		imported[fitid] = true
+	}
+	return imported, nil
+}
+
+type CommitImportInput struct {
+	Body struct {
+		ProfileID int64            `json:"profileId,omitempty" doc:"Import profile a CSV statement was previewed with. Sets the rows' currency and wallet"`
+		WalletID  int64            `json:"walletId,omitempty" doc:"Without a profile, wallet the expenses are paid from"`
+		Currency  string           `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"Without a profile, ISO 4217 code of the rows. Defaults to the wallet's currency, or the user's base currency"`
+		Account   string           `json:"account,omitempty" maxLength:"64" doc:"Account key from an OFX preview. Rows whose fitid was already imported from it are skipped"`
+		Rows      []ImportRowInput `json:"rows" minItems:"1" maxItems:"1000" doc:"Previewed expense rows to import, each with its category"`
+	}
+}
+
+type ImportRowInput struct {
+	Line        int     `json:"line,omitempty" doc:"Line of the row in the previewed file, used in error messages"`
+	OccurredAt  string  `json:"occurredAt" doc:"When the expense happened, as YYYY-MM-DD or an RFC 3339 date-time"`
+	Amount      float64 `json:"amount" exclusiveMinimum:"0" doc:"Expense amount in major units, e.g. 12.50"`
+	Description string  `json:"description,omitempty"`
+	CategoryID  int64   `json:"categoryId" doc:"Category to file the expense under"`
+	FITID       string  `json:"fitid,omitempty" maxLength:"255" doc:"Bank's ID of an OFX transaction, from the preview"`
+}
+
+type CommitImportOutput struct {
+	Body struct {
+		Data CommitImportResponse `json:"data" doc:"Rows imported successfully"`
+	}
+}
+
+type CommitImportResponse struct {
+	Imported   int     `json:"imported" doc:"Number of expenses created"`
+	Skipped    int     `json:"skipped" doc:"Number of OFX rows skipped because they were imported before"`
+	ExpenseIDs []int64 `json:"expenseIds" doc:"IDs of the created expenses in row order"`
+}
+
+// CommitImport creates an expense for every row in one transaction, so
+// either the whole statement is imported or nothing is. Rows with a FITID
+// are remembered, and skipped when imported again.
+func (h *ImportHandler) CommitImport(ctx context.Context, input *CommitImportInput) (*CommitImportOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := CommitImportResponse{ExpenseIDs: make([]int64, 0, len(input.Body.Rows))}
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		walletID, code := input.Body.WalletID, input.Body.Currency
+		if input.Body.ProfileID != 0 {
+			profile, err := getOwnedImportProfile(ctx, tx.ImportProfileRepository(), int64(userID), input.Body.ProfileID)
+			if err != nil {
+				return err
+			}
+			walletID, code = profile.WalletID.Int64, profile.Currency
+		}
+
+		expenseWalletID, expenseCurrency, err := resolveEntryCurrency(ctx, tx, int64(userID), walletID, code)
+		if err != nil {
+			return err
+		}
+
+		imported, err := importedFITIDs(ctx, tx, int64(userID), input.Body.Account)
+		if err != nil {
+			return err
+		}
+
+		checked := map[int64]bool{}
+		for index, row := range input.Body.Rows {
+			line := row.Line
+			if line == 0 {
+				line = index + 1
+			}
+
+			if row.FITID != "" && imported[row.FITID] {
+				result.Skipped++
+				continue
+			}
+
+			occurredAt, err := parseDateTime(fmt.Sprintf("occurredAt of row %d", line), row.OccurredAt)
+			if err != nil {
+				return err
+			}
+			if occurredAt == nil {
+				return huma.Error422UnprocessableEntity(fmt.Sprintf("Row %d has no occurredAt", line))
+			}
+
+			if !checked[row.CategoryID] {
+				exist, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{CategoryID: row.CategoryID, UserID: int64(userID), Kind: database.CategoryKindExpense})
+				if err != nil {
+					return err
+				}
+				if !exist {
+					return huma.Error422UnprocessableEntity(fmt.Sprintf("Row %d: expense category %d not found", line, row.CategoryID))
+				}
+				checked[row.CategoryID] = true
+			}
+
+			created, err := tx.ExpenseRepository().Create(ctx, database.NewExpenseInput{
+				UserID:      int64(userID),
+				CategoryID:  row.CategoryID,
+				Amount:      expenseCurrency.ToMinor(row.Amount),
+				Currency:    expenseCurrency.Code,
+				Description: row.Description,
+				OccurredAt:  occurredAt,
+				WalletID:    expenseWalletID,
+			})
+			if err != nil {
+				return huma.Error500InternalServerError("Failed to create expense", err)
+			}
+			result.ExpenseIDs = append(result.ExpenseIDs, created.ID)
+
+			if row.FITID != "" {
+				err := tx.ExpenseRepository().MarkImported(ctx, database.MarkImportedInput{
+					ExpenseID: created.ID,
+					UserID:    int64(userID),
+					Account:   input.Body.Account,
+					FITID:     row.FITID,
+				})
+				if err != nil {
+					return huma.Error500InternalServerError("Failed to record imported transaction", err)
+				}
+				imported[row.FITID] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Imported = len(result.ExpenseIDs)
+	resp := &CommitImportOutput{}
+	resp.Body.Data = result
+	return resp, nil
+}
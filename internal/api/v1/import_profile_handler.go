@@ -0,0 +1,321 @@
+package v1
+
+import (
+	"context"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"gastoslog/internal/statement"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type ImportProfileHandler struct {
+	db                      database.Service
+	importProfileRepository database.ImportProfileRepository
+}
+
+func NewImportProfileHandler(db database.Service) *ImportProfileHandler {
+	return &ImportProfileHandler{
+		db:                      db,
+		importProfileRepository: db.ImportProfileRepository(),
+	}
+}
+
+// getOwnedImportProfile loads an import profile of the user, or fails with
+// 404.
+func getOwnedImportProfile(ctx context.Context, profiles database.ImportProfileRepository, userID, profileID int64) (*database.ImportProfile, error) {
+	exist, err := profiles.ExistWithUserID(ctx, database.ExistImportProfileWithUserIDInput{UserID: userID, ProfileID: profileID})
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, huma.Error404NotFound("Import profile not found")
+	}
+
+	profile, err := profiles.GetByID(ctx, profileID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get import profile", err)
+	}
+	return profile, nil
+}
+
+// checkImportProfileName makes sure no other import profile of the user is
+// called name. profileID is 0 for a new profile.
+func checkImportProfileName(ctx context.Context, tx database.Repositories, userID, profileID int64, name string) error {
+	profiles, err := tx.ImportProfileRepository().List(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, profile := range profiles {
+		if profile.ID != profileID && strings.EqualFold(profile.Name, name) {
+			return huma.Error409Conflict("An import profile named " + profile.Name + " already exists")
+		}
+	}
+	return nil
+}
+
+// profileMapping returns how to read statements laid out as the profile
+// says.
+func profileMapping(profile database.ImportProfile) statement.Mapping {
+	return statement.Mapping{
+		Delimiter:         profile.Delimiter,
+		HasHeader:         profile.HasHeader,
+		DateColumn:        profile.DateColumn,
+		AmountColumn:      profile.AmountColumn,
+		DescriptionColumn: profile.DescriptionColumn,
+		DateFormat:        profile.DateFormat,
+		ExpenseSign:       statement.Sign(profile.ExpenseSign),
+		DecimalSeparator:  profile.DecimalSeparator,
+	}
+}
+
+type ImportProfileInputBody struct {
+	Name              string `json:"name" minLength:"1" maxLength:"100" example:"BPI Savings"`
+	Delimiter         string `json:"delimiter,omitempty" maxLength:"1" doc:"Character separating fields, e.g. ; or a tab. Defaults to a comma"`
+	HasHeader         *bool  `json:"hasHeader,omitempty" doc:"Whether the first line names the columns. Defaults to true"`
+	DateColumn        string `json:"dateColumn" minLength:"1" maxLength:"100" example:"Posting Date" doc:"Header name of the date column, ignoring case, or its 1-based number"`
+	AmountColumn      string `json:"amountColumn" minLength:"1" maxLength:"100" example:"Amount" doc:"Header name or 1-based number of the amount column"`
+	DescriptionColumn string `json:"descriptionColumn,omitempty" maxLength:"100" example:"Description" doc:"Header name or 1-based number of the description column"`
+	DateFormat        string `json:"dateFormat,omitempty" maxLength:"40" example:"DD/MM/YYYY" doc:"How dates are written, using YYYY, YY, MMMM, MMM, MM, M, DD, D, HH, mm and ss. Defaults to YYYY-MM-DD"`
+	ExpenseSign       string `json:"expenseSign,omitempty" enum:"negative,positive" doc:"Sign of money spent: negative for most bank exports, positive for most credit card statements. Defaults to negative"`
+	DecimalSeparator  string `json:"decimalSeparator,omitempty" maxLength:"1" doc:"Either . or a comma. Defaults to ."`
+	Currency          string `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code of the statement. Defaults to the wallet's currency, or the user's base currency"`
+	WalletID          int64  `json:"walletId,omitempty" doc:"Wallet the statement belongs to. Imported expenses are paid from it"`
+}
+
+// resolveImportProfile checks a profile from a request and returns what to
+// save.
+func resolveImportProfile(ctx context.Context, tx database.Repositories, userID int64, body ImportProfileInputBody) (database.ImportProfileInput, error) {
+	mapping := statement.Mapping{
+		Delimiter:         body.Delimiter,
+		HasHeader:         body.HasHeader == nil || *body.HasHeader,
+		DateColumn:        strings.TrimSpace(body.DateColumn),
+		AmountColumn:      strings.TrimSpace(body.AmountColumn),
+		DescriptionColumn: strings.TrimSpace(body.DescriptionColumn),
+		DateFormat:        body.DateFormat,
+		ExpenseSign:       statement.Sign(body.ExpenseSign),
+		DecimalSeparator:  body.DecimalSeparator,
+	}
+	if err := mapping.Validate(); err != nil {
+		return database.ImportProfileInput{}, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	walletID, profileCurrency, err := resolveEntryCurrency(ctx, tx, userID, body.WalletID, body.Currency)
+	if err != nil {
+		return database.ImportProfileInput{}, err
+	}
+
+	return database.ImportProfileInput{
+		UserID:            userID,
+		Name:              body.Name,
+		Delimiter:         mapping.Delimiter,
+		HasHeader:         mapping.HasHeader,
+		DateColumn:        mapping.DateColumn,
+		AmountColumn:      mapping.AmountColumn,
+		DescriptionColumn: mapping.DescriptionColumn,
+		DateFormat:        mapping.DateFormat,
+		ExpenseSign:       string(mapping.ExpenseSign),
+		DecimalSeparator:  mapping.DecimalSeparator,
+		Currency:          profileCurrency.Code,
+		WalletID:          walletID,
+	}, nil
+}
+
+type NewImportProfileInput struct {
+	Body ImportProfileInputBody
+}
+
+type CreatedImportProfileOutput struct {
+	Body struct {
+		Data ImportProfileResponse `json:"data" doc:"Import profile created successfully"`
+	}
+}
+
+func (h *ImportProfileHandler) CreateImportProfile(ctx context.Context, input *NewImportProfileInput) (*CreatedImportProfileOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *database.ImportProfile
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if err := checkImportProfileName(ctx, tx, int64(userID), 0, input.Body.Name); err != nil {
+			return err
+		}
+
+		profile, err := resolveImportProfile(ctx, tx, int64(userID), input.Body)
+		if err != nil {
+			return err
+		}
+
+		created, err = tx.ImportProfileRepository().Create(ctx, profile)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create import profile", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedImportProfileOutput{}
+	resp.Body.Data = toImportProfileResponse(*created)
+	return resp, nil
+}
+
+type ListImportProfileInput struct {
+}
+
+type ListImportProfileOutput struct {
+	Body struct {
+		Data []ImportProfileResponse `json:"data" doc:"Import profiles by name"`
+	}
+}
+
+func (h *ImportProfileHandler) ListImportProfile(ctx context.Context, input *ListImportProfileInput) (*ListImportProfileOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := h.importProfileRepository.List(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list import profiles", err)
+	}
+
+	resp := &ListImportProfileOutput{}
+	resp.Body.Data = make([]ImportProfileResponse, len(profiles))
+	for index, profile := range profiles {
+		resp.Body.Data[index] = toImportProfileResponse(profile)
+	}
+	return resp, nil
+}
+
+type ImportProfileIDInput struct {
+	ProfileID int64 `path:"profileId" doc:"Import profile ID"`
+}
+
+type DetailImportProfileOutput struct {
+	Body struct {
+		Data ImportProfileResponse `json:"data" doc:"Import profile detail"`
+	}
+}
+
+func (h *ImportProfileHandler) DetailImportProfile(ctx context.Context, input *ImportProfileIDInput) (*DetailImportProfileOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := getOwnedImportProfile(ctx, h.importProfileRepository, int64(userID), input.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &DetailImportProfileOutput{}
+	resp.Body.Data = toImportProfileResponse(*profile)
+	return resp, nil
+}
+
+type UpdateImportProfileInput struct {
+	ProfileID int64 `path:"profileId" doc:"Import profile ID"`
+	Body      ImportProfileInputBody
+}
+
+type UpdatedImportProfileOutput struct {
+	Body struct {
+		Data ImportProfileResponse `json:"data" doc:"Import profile updated successfully"`
+	}
+}
+
+func (h *ImportProfileHandler) UpdateImportProfile(ctx context.Context, input *UpdateImportProfileInput) (*UpdatedImportProfileOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *database.ImportProfile
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		existing, err := getOwnedImportProfile(ctx, tx.ImportProfileRepository(), int64(userID), input.ProfileID)
+		if err != nil {
+			return err
+		}
+		if err := checkImportProfileName(ctx, tx, int64(userID), existing.ID, input.Body.Name); err != nil {
+			return err
+		}
+
+		profile, err := resolveImportProfile(ctx, tx, int64(userID), input.Body)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.ImportProfileRepository().Update(ctx, existing.ID, profile); err != nil {
+			return huma.Error500InternalServerError("Failed to update import profile", err)
+		}
+
+		updated, err = tx.ImportProfileRepository().GetByID(ctx, existing.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UpdatedImportProfileOutput{}
+	resp.Body.Data = toImportProfileResponse(*updated)
+	return resp, nil
+}
+
+func (h *ImportProfileHandler) DeleteImportProfile(ctx context.Context, input *ImportProfileIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := getOwnedImportProfile(ctx, h.importProfileRepository, int64(userID), input.ProfileID); err != nil {
+		return nil, err
+	}
+
+	if err := h.importProfileRepository.Delete(ctx, input.ProfileID); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete import profile", err)
+	}
+
+	return nil, nil
+}
+
+type ImportProfileResponse struct {
+	ID                int64     `json:"id"`
+	Name              string    `json:"name"`
+	Delimiter         string    `json:"delimiter"`
+	HasHeader         bool      `json:"hasHeader"`
+	DateColumn        string    `json:"dateColumn"`
+	AmountColumn      string    `json:"amountColumn"`
+	DescriptionColumn string    `json:"descriptionColumn"`
+	DateFormat        string    `json:"dateFormat"`
+	ExpenseSign       string    `json:"expenseSign"`
+	DecimalSeparator  string    `json:"decimalSeparator"`
+	Currency          string    `json:"currency"`
+	WalletID          *int64    `json:"walletId"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+func toImportProfileResponse(profile database.ImportProfile) ImportProfileResponse {
+	return ImportProfileResponse{
+		ID:                profile.ID,
+		Name:              profile.Name,
+		Delimiter:         profile.Delimiter,
+		HasHeader:         profile.HasHeader,
+		DateColumn:        profile.DateColumn,
+		AmountColumn:      profile.AmountColumn,
+		DescriptionColumn: profile.DescriptionColumn,
+		DateFormat:        profile.DateFormat,
+		ExpenseSign:       profile.ExpenseSign,
+		DecimalSeparator:  profile.DecimalSeparator,
+		Currency:          profile.Currency,
+		WalletID:          nullInt64Ptr(profile.WalletID),
+		CreatedAt:         profile.CreatedAt,
+		UpdatedAt:         profile.UpdatedAt,
+	}
+}
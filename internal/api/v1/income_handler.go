@@ -0,0 +1,374 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type IncomeHandler struct {
+	db               database.Service
+	incomeRepository database.IncomeRepository
+	userRepository   database.UserRepository
+}
+
+func NewIncomeHandler(db database.Service) *IncomeHandler {
+	return &IncomeHandler{
+		db:               db,
+		incomeRepository: db.IncomeRepository(),
+		userRepository:   db.UserRepository(),
+	}
+}
+
+type NewIncomeInput struct {
+	Body struct {
+		Amount      float64 `json:"amount" doc:"Income amount in major units, e.g. 12.50" minimum:"1"`
+		Currency    string  `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code. Defaults to the user's base currency"`
+		Description string  `json:"description,omitempty" doc:"Income description"`
+		CategoryID  int64   `json:"categoryId" doc:"Income category ID"`
+		OccurredAt  string  `json:"occurredAt,omitempty" doc:"When the income was received, as YYYY-MM-DD or an RFC 3339 date-time. Defaults to now"`
+		WalletID    int64   `json:"walletId,omitempty" doc:"Wallet the income was paid into. The currency then defaults to, and must match, the wallet's"`
+	}
+}
+
+type CreatedIncomeOutput struct {
+	Body struct {
+		Data IncomeResponse `json:"data" doc:"Income created successfully"`
+	}
+}
+
+func (h *IncomeHandler) CreateIncome(ctx context.Context, input *NewIncomeInput) (*CreatedIncomeOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	occurredAt, err := parseOccurredAt(input.Body.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdIncome *database.Income
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if err := checkCategory(ctx, tx, int64(userID), input.Body.CategoryID, database.CategoryKindIncome); err != nil {
+			return err
+		}
+
+		walletID, incomeCurrency, err := resolveEntryCurrency(ctx, tx, int64(userID), input.Body.WalletID, input.Body.Currency)
+		if err != nil {
+			return err
+		}
+
+		createdIncome, err = tx.IncomeRepository().Create(ctx, database.NewIncomeInput{
+			UserID:      int64(userID),
+			CategoryID:  input.Body.CategoryID,
+			Amount:      incomeCurrency.ToMinor(input.Body.Amount),
+			Currency:    incomeCurrency.Code,
+			Description: input.Body.Description,
+			OccurredAt:  occurredAt,
+			WalletID:    walletID,
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create income", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedIncomeOutput{}
+	resp.Body.Data = toIncomeResponse(*createdIncome)
+	return resp, nil
+}
+
+type ListIncomeInput struct {
+	Page     int     `query:"page" default:"1" doc:"Page number of pagination"`
+	Limit    int     `query:"limit" default:"10" doc:"Limit per page of pagination"`
+	Date     string  `query:"date" doc:"Filter by the day the income was received (YYYY-MM-DD format)"`
+	Category []int64 `query:"category" doc:"Filter category"`
+	Cursor   string  `query:"cursor" doc:"Continue after the page that returned this nextCursor. Takes precedence over page"`
+	Wallet   int64   `query:"wallet" doc:"Filter by the wallet the income was paid into"`
+}
+
+type ListIncomeOutput struct {
+	Body struct {
+		Data []IncomeResponse `json:"data" doc:"List of incomes, most recent first"`
+		Meta PageMeta         `json:"meta"`
+	}
+}
+
+func (h *IncomeHandler) ListIncome(ctx context.Context, input *ListIncomeInput) (*ListIncomeOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := parseOverviewDate(input.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := h.incomeRepository.List(ctx, database.ListIncomeInput{
+		UserID:   int64(userID),
+		Page:     input.Page,
+		Limit:    input.Limit,
+		Date:     date,
+		Category: input.Category,
+		Cursor:   input.Cursor,
+		WalletID: input.Wallet,
+	})
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, huma.Error400BadRequest("Invalid cursor")
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list incomes", err)
+	}
+
+	resp := &ListIncomeOutput{}
+	resp.Body.Data = make([]IncomeResponse, len(list.Incomes))
+	for index, income := range list.Incomes {
+		resp.Body.Data[index] = toIncomeResponse(income)
+	}
+	resp.Body.Meta.Page = input.Page
+	resp.Body.Meta.Limit = input.Limit
+	resp.Body.Meta.NextCursor = list.NextCursor
+	resp.Body.Meta.HasMore = list.HasMore
+	resp.Body.Meta.TotalCount = list.TotalCount
+	resp.Body.Meta.Totals = toCurrencyTotals(list.Totals)
+
+	return resp, nil
+}
+
+type IncomeIDInput struct {
+	IncomeID int64 `path:"incomeId" doc:"Income ID"`
+}
+
+// checkIncome makes sure the income belongs to the user and is not deleted.
+func checkIncome(ctx context.Context, incomes database.IncomeRepository, userID, incomeID int64) error {
+	exist, err := incomes.ExistWithUserID(ctx, database.ExistIncomeWithUserIDInput{UserID: userID, IncomeID: incomeID})
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return huma.Error404NotFound("Income not found")
+	}
+	return nil
+}
+
+type DetailIncomeOutput struct {
+	Body struct {
+		Data IncomeResponse `json:"data" doc:"Income detail"`
+	}
+}
+
+func (h *IncomeHandler) DetailIncome(ctx context.Context, input *IncomeIDInput) (*DetailIncomeOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkIncome(ctx, h.incomeRepository, int64(userID), input.IncomeID); err != nil {
+		return nil, err
+	}
+
+	income, err := h.incomeRepository.GetByID(ctx, input.IncomeID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get income", err)
+	}
+
+	resp := &DetailIncomeOutput{}
+	resp.Body.Data = toIncomeResponse(*income)
+	return resp, nil
+}
+
+type UpdateIncomeInput struct {
+	IncomeID int64 `path:"incomeId" doc:"Income ID"`
+	Body     struct {
+		CategoryID  int64   `json:"categoryId" doc:"Income category"`
+		Amount      float64 `json:"amount" minimum:"1" doc:"Income amount in major units, e.g. 12.50"`
+		Currency    string  `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code. Unchanged when omitted, unless the income moves to another wallet, whose currency it takes"`
+		Description string  `json:"description,omitempty"`
+		OccurredAt  string  `json:"occurredAt,omitempty" doc:"When the income was received, as YYYY-MM-DD or an RFC 3339 date-time. Unchanged when omitted"`
+		WalletID    *int64  `json:"walletId,omitempty" doc:"Wallet the income was paid into. Unchanged when omitted, 0 removes it"`
+	}
+}
+
+type UpdatedIncomeOutput struct {
+	Body struct {
+		Data IncomeResponse `json:"data" doc:"Income updated successfully"`
+	}
+}
+
+func (h *IncomeHandler) UpdateIncome(ctx context.Context, input *UpdateIncomeInput) (*UpdatedIncomeOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	occurredAt, err := parseOccurredAt(input.Body.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var updatedIncome *database.Income
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if err := checkIncome(ctx, tx.IncomeRepository(), int64(userID), input.IncomeID); err != nil {
+			return err
+		}
+		if err := checkCategory(ctx, tx, int64(userID), input.Body.CategoryID, database.CategoryKindIncome); err != nil {
+			return err
+		}
+
+		current, err := tx.IncomeRepository().GetByID(ctx, input.IncomeID)
+		if err != nil {
+			return err
+		}
+		walletID := current.WalletID.Int64
+		if input.Body.WalletID != nil {
+			walletID = *input.Body.WalletID
+		}
+
+		code := input.Body.Currency
+		if code == "" && walletID == current.WalletID.Int64 {
+			code = current.Currency
+		}
+		wallet, incomeCurrency, err := resolveEntryCurrency(ctx, tx, int64(userID), walletID, code)
+		if err != nil {
+			return err
+		}
+
+		err = tx.IncomeRepository().Update(ctx, database.UpdateIncomeInput{
+			IncomeID:    input.IncomeID,
+			UserID:      int64(userID),
+			CategoryID:  input.Body.CategoryID,
+			Amount:      incomeCurrency.ToMinor(input.Body.Amount),
+			Currency:    incomeCurrency.Code,
+			Description: input.Body.Description,
+			OccurredAt:  occurredAt,
+			WalletID:    wallet,
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to update income", err)
+		}
+
+		updatedIncome, err = tx.IncomeRepository().GetByID(ctx, input.IncomeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UpdatedIncomeOutput{}
+	resp.Body.Data = toIncomeResponse(*updatedIncome)
+	return resp, nil
+}
+
+func (h *IncomeHandler) DeleteIncome(ctx context.Context, input *IncomeIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkIncome(ctx, h.incomeRepository, int64(userID), input.IncomeID); err != nil {
+		return nil, err
+	}
+
+	if err := h.incomeRepository.Delete(ctx, input.IncomeID); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete income", err)
+	}
+
+	return nil, nil
+}
+
+type RestoredIncomeOutput struct {
+	Body struct {
+		Data IncomeResponse `json:"data" doc:"Income restored successfully"`
+	}
+}
+
+func (h *IncomeHandler) RestoreIncome(ctx context.Context, input *IncomeIDInput) (*RestoredIncomeOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var restoredIncome *database.Income
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		exist, err := tx.IncomeRepository().ExistDeletedWithUserID(ctx, database.ExistIncomeWithUserIDInput{
+			UserID:   int64(userID),
+			IncomeID: input.IncomeID,
+		})
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return huma.Error404NotFound("Income not found in trash")
+		}
+
+		if err := tx.IncomeRepository().Restore(ctx, input.IncomeID); err != nil {
+			return huma.Error500InternalServerError("Failed to restore income", err)
+		}
+
+		restoredIncome, err = tx.IncomeRepository().GetByID(ctx, input.IncomeID)
+		if err != nil {
+			return err
+		}
+
+		existCategory, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{CategoryID: restoredIncome.CategoryID, UserID: int64(userID)})
+		if err != nil {
+			return err
+		}
+		if !existCategory {
+			return huma.Error409Conflict("Restore the income category first")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &RestoredIncomeOutput{}
+	resp.Body.Data = toIncomeResponse(*restoredIncome)
+	return resp, nil
+}
+
+type IncomeResponse struct {
+	ID          int64            `json:"id"`
+	Amount      int64            `json:"amount" doc:"Amount in minor units of currency"`
+	Currency    string           `json:"currency" doc:"ISO 4217 code"`
+	Description string           `json:"description"`
+	CategoryID  int64            `json:"categoryId"`
+	Category    CategoryResponse `json:"category"`
+	OccurredAt  time.Time        `json:"occurredAt" doc:"When the income was received"`
+	WalletID    *int64           `json:"walletId" doc:"Wallet the income was paid into, if any"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+func toIncomeResponse(income database.Income) IncomeResponse {
+	return IncomeResponse{
+		ID:          income.ID,
+		Amount:      income.Amount,
+		Currency:    income.Currency,
+		Description: income.Description,
+		CategoryID:  income.CategoryID,
+		Category: CategoryResponse{
+			ID:          income.CategoryID,
+			Name:        income.CategoryName,
+			Description: income.CategoryDescription,
+			Kind:        database.CategoryKindIncome,
+			CreatedAt:   income.CategoryCreatedAt,
+			UpdatedAt:   income.CategoryUpdatedAt,
+		},
+		OccurredAt: income.OccurredAt,
+		WalletID:   nullInt64Ptr(income.WalletID),
+		CreatedAt:  income.CreatedAt,
+		UpdatedAt:  income.UpdatedAt,
+	}
+}
@@ -0,0 +1,27 @@
+package v1
+
+import "gastoslog/internal/database"
+
+// PageMeta describes one page of a list response. The totals cover every
+// item matching the filters, not just this page.
+type PageMeta struct {
+	Page       int             `json:"page" doc:"Page number of pagination"`
+	Limit      int             `json:"limit" doc:"Limit per page of pagination"`
+	NextCursor string          `json:"nextCursor,omitempty" doc:"Cursor for the next page, absent on the last page"`
+	HasMore    bool            `json:"hasMore" doc:"Whether another page follows"`
+	TotalCount int64           `json:"totalCount" doc:"Number of items matching the filters"`
+	Totals     []CurrencyTotal `json:"totals,omitempty" doc:"Sum of the matching amounts, one per currency. Absent for lists without amounts"`
+}
+
+type CurrencyTotal struct {
+	Currency string `json:"currency" doc:"ISO 4217 currency code"`
+	Amount   int64  `json:"amount" doc:"Sum in minor units of the currency"`
+}
+
+func toCurrencyTotals(totals []database.CurrencyTotal) []CurrencyTotal {
+	result := make([]CurrencyTotal, len(totals))
+	for i, total := range totals {
+		result[i] = CurrencyTotal{Currency: total.Currency, Amount: total.Amount}
+	}
+	return result
+}
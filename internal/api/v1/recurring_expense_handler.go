@@ -0,0 +1,400 @@
+package v1
+
+import (
+	"context"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"gastoslog/internal/recurrence"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type RecurringExpenseHandler struct {
+	db                         database.Service
+	recurringExpenseRepository database.RecurringExpenseRepository
+	userRepository             database.UserRepository
+}
+
+func NewRecurringExpenseHandler(db database.Service) *RecurringExpenseHandler {
+	return &RecurringExpenseHandler{
+		db:                         db,
+		recurringExpenseRepository: db.RecurringExpenseRepository(),
+		userRepository:             db.UserRepository(),
+	}
+}
+
+type RecurringExpenseInputBody struct {
+	Amount      float64 `json:"amount" doc:"Amount of every occurrence in major units, e.g. 12.50" minimum:"1"`
+	Currency    string  `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code. Defaults to the user's base currency"`
+	Description string  `json:"description,omitempty" doc:"Description of the generated expenses"`
+	CategoryID  int64   `json:"categoryId" doc:"Category ID"`
+	RRule       string  `json:"rrule" maxLength:"255" example:"FREQ=MONTHLY;BYMONTHDAY=1" doc:"RFC 5545 recurrence rule. Supports FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL"`
+	StartsAt    string  `json:"startsAt,omitempty" doc:"First occurrence, as YYYY-MM-DD or an RFC 3339 date-time. Its time of day applies to every occurrence. Defaults to now"`
+	EndsAt      string  `json:"endsAt,omitempty" doc:"No occurrences after this date-time, or after the end of this YYYY-MM-DD date"`
+	WalletID    int64   `json:"walletId,omitempty" doc:"Wallet the generated expenses are paid from. The currency then defaults to, and must match, the wallet's"`
+}
+
+// recurringSchedule is a validated request body.
+type recurringSchedule struct {
+	recurrence.Schedule
+	input database.NewRecurringExpenseInput
+}
+
+// parseRecurringExpense validates a request body into the fields of a rule.
+func (h *RecurringExpenseHandler) parseRecurringExpense(ctx context.Context, userID int64, body RecurringExpenseInputBody) (*recurringSchedule, error) {
+	rule, err := recurrence.Parse(body.RRule)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	startsAt, err := parseDateTime("startsAt", body.StartsAt)
+	if err != nil {
+		return nil, err
+	}
+	if startsAt == nil {
+		now := time.Now()
+		startsAt = &now
+	}
+	start := startsAt.UTC()
+
+	endsAt, err := parseDateTime("endsAt", body.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	if endsAt != nil && len(body.EndsAt) == len("2006-01-02") {
+		// A plain end date includes the whole day
+		endOfDay := endsAt.Add(24*time.Hour - time.Nanosecond)
+		endsAt = &endOfDay
+	}
+	if endsAt != nil && endsAt.Before(start) {
+		return nil, huma.Error400BadRequest("endsAt must not be before startsAt")
+	}
+
+	walletID, expenseCurrency, err := resolveEntryCurrency(ctx, h.db, userID, body.WalletID, body.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recurringSchedule{
+		Schedule: recurrence.Schedule{Rule: rule, Start: start, End: endsAt},
+		input: database.NewRecurringExpenseInput{
+			UserID:      userID,
+			CategoryID:  body.CategoryID,
+			Amount:      expenseCurrency.ToMinor(body.Amount),
+			Currency:    expenseCurrency.Code,
+			Description: body.Description,
+			RRule:       rule.String(),
+			StartsAt:    start,
+			EndsAt:      endsAt,
+			WalletID:    walletID,
+		},
+	}, nil
+}
+
+// nextOccurrence is the first occurrence after after, nil once the schedule
+// has ended.
+func nextOccurrence(schedule recurrence.Schedule, after time.Time) *time.Time {
+	next, ok := schedule.Next(after)
+	if !ok {
+		return nil
+	}
+	return &next
+}
+
+// checkCategory makes sure the category belongs to the user and files
+// entries of the given kind.
+func checkCategory(ctx context.Context, tx database.Repositories, userID, categoryID int64, kind string) error {
+	exist, err := tx.CategoryRepository().ExistWithUserID(ctx, database.ExistWithUserIDInput{CategoryID: categoryID, UserID: userID, Kind: kind})
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return huma.Error404NotFound("Category not found")
+	}
+	return nil
+}
+
+type NewRecurringExpenseInput struct {
+	Body RecurringExpenseInputBody
+}
+
+type CreatedRecurringExpenseOutput struct {
+	Body struct {
+		Data RecurringExpenseResponse `json:"data" doc:"Recurring expense created successfully"`
+	}
+}
+
+func (h *RecurringExpenseHandler) CreateRecurringExpense(ctx context.Context, input *NewRecurringExpenseInput) (*CreatedRecurringExpenseOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := h.parseRecurringExpense(ctx, int64(userID), input.Body)
+	if err != nil {
+		return nil, err
+	}
+	parsed.input.NextOccurrenceAt = nextOccurrence(parsed.Schedule, parsed.Start.Add(-time.Nanosecond))
+
+	var created *database.RecurringExpense
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if err := checkCategory(ctx, tx, int64(userID), parsed.input.CategoryID, database.CategoryKindExpense); err != nil {
+			return err
+		}
+
+		created, err = tx.RecurringExpenseRepository().Create(ctx, parsed.input)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create recurring expense", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedRecurringExpenseOutput{}
+	resp.Body.Data = toRecurringExpenseResponse(*created)
+	return resp, nil
+}
+
+type ListRecurringExpenseInput struct {
+}
+
+type ListRecurringExpenseOutput struct {
+	Body struct {
+		Data []RecurringExpenseResponse `json:"data" doc:"Recurring expenses, soonest next occurrence first"`
+	}
+}
+
+func (h *RecurringExpenseHandler) ListRecurringExpense(ctx context.Context, input *ListRecurringExpenseInput) (*ListRecurringExpenseOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recurringExpenses, err := h.recurringExpenseRepository.List(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list recurring expenses", err)
+	}
+
+	resp := &ListRecurringExpenseOutput{}
+	resp.Body.Data = make([]RecurringExpenseResponse, len(recurringExpenses))
+	for index, recurringExpense := range recurringExpenses {
+		resp.Body.Data[index] = toRecurringExpenseResponse(recurringExpense)
+	}
+
+	return resp, nil
+}
+
+type RecurringExpenseIDInput struct {
+	RecurringExpenseID int64 `path:"recurringExpenseId" doc:"Recurring expense ID"`
+}
+
+// getOwnedRecurringExpense loads a recurring expense of the user, or fails with 404.
+func getOwnedRecurringExpense(ctx context.Context, repository database.RecurringExpenseRepository, userID, id int64) (*database.RecurringExpense, error) {
+	exist, err := repository.ExistWithUserID(ctx, database.ExistRecurringExpenseWithUserIDInput{
+		UserID:             userID,
+		RecurringExpenseID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, huma.Error404NotFound("Recurring expense not found")
+	}
+
+	recurringExpense, err := repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get recurring expense", err)
+	}
+	return recurringExpense, nil
+}
+
+type DetailRecurringExpenseOutput struct {
+	Body struct {
+		Data RecurringExpenseResponse `json:"data" doc:"Recurring expense detail"`
+	}
+}
+
+func (h *RecurringExpenseHandler) DetailRecurringExpense(ctx context.Context, input *RecurringExpenseIDInput) (*DetailRecurringExpenseOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recurringExpense, err := getOwnedRecurringExpense(ctx, h.recurringExpenseRepository, int64(userID), input.RecurringExpenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &DetailRecurringExpenseOutput{}
+	resp.Body.Data = toRecurringExpenseResponse(*recurringExpense)
+	return resp, nil
+}
+
+type UpdateRecurringExpenseInput struct {
+	RecurringExpenseID int64 `path:"recurringExpenseId" doc:"Recurring expense ID"`
+	Body               RecurringExpenseInputBody
+}
+
+type UpdatedRecurringExpenseOutput struct {
+	Body struct {
+		Data RecurringExpenseResponse `json:"data" doc:"Recurring expense updated successfully"`
+	}
+}
+
+// UpdateRecurringExpense changes a rule from its next occurrence on.
+// Expenses it already generated are left alone and never generated again.
+func (h *RecurringExpenseHandler) UpdateRecurringExpense(ctx context.Context, input *UpdateRecurringExpenseInput) (*UpdatedRecurringExpenseOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := h.parseRecurringExpense(ctx, int64(userID), input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *database.RecurringExpense
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		current, err := getOwnedRecurringExpense(ctx, tx.RecurringExpenseRepository(), int64(userID), input.RecurringExpenseID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkCategory(ctx, tx, int64(userID), parsed.input.CategoryID, database.CategoryKindExpense); err != nil {
+			return err
+		}
+
+		after := parsed.Start.Add(-time.Nanosecond)
+		if current.LastOccurrenceAt != nil && current.LastOccurrenceAt.After(after) {
+			after = *current.LastOccurrenceAt
+		}
+
+		err = tx.RecurringExpenseRepository().Update(ctx, database.UpdateRecurringExpenseInput{
+			RecurringExpenseID: input.RecurringExpenseID,
+			UserID:             int64(userID),
+			CategoryID:         parsed.input.CategoryID,
+			Amount:             parsed.input.Amount,
+			Currency:           parsed.input.Currency,
+			Description:        parsed.input.Description,
+			RRule:              parsed.input.RRule,
+			StartsAt:           parsed.input.StartsAt,
+			EndsAt:             parsed.input.EndsAt,
+			NextOccurrenceAt:   nextOccurrence(parsed.Schedule, after),
+			WalletID:           parsed.input.WalletID,
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to update recurring expense", err)
+		}
+
+		updated, err = tx.RecurringExpenseRepository().GetByID(ctx, input.RecurringExpenseID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UpdatedRecurringExpenseOutput{}
+	resp.Body.Data = toRecurringExpenseResponse(*updated)
+	return resp, nil
+}
+
+// DeleteRecurringExpense stops a rule. Expenses it already generated stay.
+func (h *RecurringExpenseHandler) DeleteRecurringExpense(ctx context.Context, input *RecurringExpenseIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if _, err := getOwnedRecurringExpense(ctx, tx.RecurringExpenseRepository(), int64(userID), input.RecurringExpenseID); err != nil {
+			return err
+		}
+
+		if err := tx.RecurringExpenseRepository().Delete(ctx, input.RecurringExpenseID); err != nil {
+			return huma.Error500InternalServerError("Failed to delete recurring expense", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type PreviewRecurringExpenseInput struct {
+	RecurringExpenseID int64 `path:"recurringExpenseId" doc:"Recurring expense ID"`
+	Count              int   `query:"count" default:"5" minimum:"1" maximum:"100" doc:"Number of occurrences"`
+}
+
+type PreviewRecurringExpenseOutput struct {
+	Body struct {
+		Data []time.Time `json:"data" doc:"Upcoming occurrences that have not been generated yet, empty once the schedule has ended"`
+	}
+}
+
+func (h *RecurringExpenseHandler) PreviewRecurringExpense(ctx context.Context, input *PreviewRecurringExpenseInput) (*PreviewRecurringExpenseOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recurringExpense, err := getOwnedRecurringExpense(ctx, h.recurringExpenseRepository, int64(userID), input.RecurringExpenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &PreviewRecurringExpenseOutput{}
+	resp.Body.Data = []time.Time{}
+	if recurringExpense.NextOccurrenceAt == nil {
+		return resp, nil
+	}
+
+	rule, err := recurrence.Parse(recurringExpense.RRule)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Invalid stored recurrence rule", err)
+	}
+
+	schedule := recurrence.Schedule{Rule: rule, Start: recurringExpense.StartsAt.UTC(), End: recurringExpense.EndsAt}
+	resp.Body.Data = schedule.Upcoming(recurringExpense.NextOccurrenceAt.Add(-time.Nanosecond), input.Count)
+
+	return resp, nil
+}
+
+type RecurringExpenseResponse struct {
+	ID               int64      `json:"id"`
+	CategoryID       int64      `json:"categoryId"`
+	Amount           int64      `json:"amount" doc:"Amount in minor units of currency"`
+	Currency         string     `json:"currency"`
+	Description      string     `json:"description"`
+	RRule            string     `json:"rrule"`
+	StartsAt         time.Time  `json:"startsAt"`
+	EndsAt           *time.Time `json:"endsAt"`
+	LastOccurrenceAt *time.Time `json:"lastOccurrenceAt" doc:"Latest occurrence generated as an expense"`
+	NextOccurrenceAt *time.Time `json:"nextOccurrenceAt" doc:"Next occurrence to generate, null once the schedule has ended"`
+	WalletID         *int64     `json:"walletId" doc:"Wallet the generated expenses are paid from, if any"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
+
+func toRecurringExpenseResponse(recurringExpense database.RecurringExpense) RecurringExpenseResponse {
+	return RecurringExpenseResponse{
+		ID:               recurringExpense.ID,
+		CategoryID:       recurringExpense.CategoryID,
+		Amount:           recurringExpense.Amount,
+		Currency:         recurringExpense.Currency,
+		Description:      recurringExpense.Description,
+		RRule:            recurringExpense.RRule,
+		StartsAt:         recurringExpense.StartsAt,
+		EndsAt:           recurringExpense.EndsAt,
+		LastOccurrenceAt: recurringExpense.LastOccurrenceAt,
+		NextOccurrenceAt: recurringExpense.NextOccurrenceAt,
+		WalletID:         nullInt64Ptr(recurringExpense.WalletID),
+		CreatedAt:        recurringExpense.CreatedAt,
+		UpdatedAt:        recurringExpense.UpdatedAt,
+	}
+}
@@ -0,0 +1,410 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gastoslog/internal/currency"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"gastoslog/internal/settlement"
+	"sort"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type SettlementHandler struct {
+	db              database.Service
+	groupRepository database.GroupRepository
+}
+
+func NewSettlementHandler(db database.Service) *SettlementHandler {
+	return &SettlementHandler{
+		db:              db,
+		groupRepository: db.GroupRepository(),
+	}
+}
+
+type ExpenseShareInput struct {
+	Mode  string                  `json:"mode" enum:"equal,exact,percent" doc:"Divide the bill equally, by exact amounts or by percentages"`
+	Users []ExpenseShareUserInput `json:"users" minItems:"1" maxItems:"50" doc:"Group members who owe a share, the payer included if they have one"`
+}
+
+type ExpenseShareUserInput struct {
+	UserID  int64   `json:"userId"`
+	Amount  float64 `json:"amount,omitempty" minimum:"0" doc:"Share in major units of the expense's currency, for exact shares"`
+	Percent float64 `json:"percent,omitempty" minimum:"0" maximum:"100" doc:"Share as a percentage of the amount, for percent shares"`
+}
+
+// resolveShares divides a bill of amount, in minor units of
+// expenseCurrency, between members of the group it is shared with. Without
+// input the expense is not a bill.
+func resolveShares(ctx context.Context, tx database.Repositories, groupID sql.NullInt64, amount int64, expenseCurrency currency.Currency, input *ExpenseShareInput) ([]database.NewExpenseShareInput, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if !groupID.Valid {
+		return nil, huma.Error422UnprocessableEntity("Share a bill with a group by setting groupId")
+	}
+
+	seen := map[int64]bool{}
+	for _, user := range input.Users {
+		if seen[user.UserID] {
+			return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("User %d is listed twice", user.UserID))
+		}
+		seen[user.UserID] = true
+
+		_, err := tx.GroupRepository().GetMember(ctx, groupID.Int64, user.UserID)
+		if errors.Is(err, database.ErrGroupMemberNotFound) {
+			return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("User %d is not a member of the group", user.UserID))
+		}
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to get group member", err)
+		}
+	}
+
+	var amounts []int64
+	var err error
+	switch input.Mode {
+	case database.ShareModeEqual:
+		amounts, err = settlement.Equal(amount, len(input.Users))
+	case database.ShareModePercent:
+		percents := make([]float64, len(input.Users))
+		for i, user := range input.Users {
+			percents[i] = user.Percent
+		}
+		amounts, err = settlement.Percent(amount, percents)
+	default:
+		exact := make([]int64, len(input.Users))
+		for i, user := range input.Users {
+			exact[i] = expenseCurrency.ToMinor(user.Amount)
+		}
+		amounts, err = settlement.Exact(amount, exact)
+	}
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity("Invalid shares: " + err.Error())
+	}
+
+	shares := make([]database.NewExpenseShareInput, len(input.Users))
+	for i, user := range input.Users {
+		shares[i] = database.NewExpenseShareInput{UserID: user.UserID, Amount: amounts[i], Mode: input.Mode}
+		if input.Mode == database.ShareModePercent {
+			shares[i].Percent = sql.NullFloat64{Float64: user.Percent, Valid: true}
+		}
+	}
+	return shares, nil
+}
+
+type GroupBalanceOutput struct {
+	Body struct {
+		Data []GroupCurrencyBalanceResponse `json:"data" doc:"Balances and suggested settle-up transfers, per currency"`
+	}
+}
+
+type GroupCurrencyBalanceResponse struct {
+	Currency string                          `json:"currency" doc:"ISO 4217 code"`
+	Balances []GroupMemberBalanceResponse    `json:"balances" doc:"Members by what they are owed, most first"`
+	SettleUp []GroupSettleUpTransferResponse `json:"settleUp" doc:"Suggested payments that bring every balance to zero, at most one fewer than the members with a balance"`
+}
+
+type GroupMemberBalanceResponse struct {
+	UserID   int64  `json:"userId"`
+	Email    string `json:"email"`
+	Paid     int64  `json:"paid" doc:"Bills paid, in minor units"`
+	Owed     int64  `json:"owed" doc:"Shares of bills, own included, in minor units"`
+	Sent     int64  `json:"sent" doc:"Settlements paid to others, in minor units"`
+	Received int64  `json:"received" doc:"Settlements received from others, in minor units"`
+	Net      int64  `json:"net" doc:"What the member is owed, negative when they owe, in minor units"`
+}
+
+type GroupSettleUpTransferResponse struct {
+	FromUserID int64  `json:"fromUserId"`
+	FromEmail  string `json:"fromEmail"`
+	ToUserID   int64  `json:"toUserId"`
+	ToEmail    string `json:"toEmail"`
+	Amount     int64  `json:"amount" doc:"Amount in minor units"`
+}
+
+// groupBalances groups the balances by currency and suggests how to settle
+// each currency up.
+func groupBalances(balances []database.GroupBalance) []GroupCurrencyBalanceResponse {
+	responses := []GroupCurrencyBalanceResponse{}
+	indexByCurrency := map[string]int{}
+	emails := map[int64]string{}
+	for _, balance := range balances {
+		emails[balance.UserID] = balance.Email
+
+		index, ok := indexByCurrency[balance.Currency]
+		if !ok {
+			index = len(responses)
+			indexByCurrency[balance.Currency] = index
+			responses = append(responses, GroupCurrencyBalanceResponse{Currency: balance.Currency, Balances: []GroupMemberBalanceResponse{}})
+		}
+		responses[index].Balances = append(responses[index].Balances, GroupMemberBalanceResponse{
+			UserID:   balance.UserID,
+			Email:    balance.Email,
+			Paid:     balance.Paid,
+			Owed:     balance.Owed,
+			Sent:     balance.Sent,
+			Received: balance.Received,
+			Net:      balance.Net(),
+		})
+	}
+
+	for i := range responses {
+		sort.SliceStable(responses[i].Balances, func(a, b int) bool {
+			return responses[i].Balances[a].Net > responses[i].Balances[b].Net
+		})
+
+		nets := map[int64]int64{}
+		for _, balance := range responses[i].Balances {
+			nets[balance.UserID] = balance.Net
+		}
+
+		responses[i].SettleUp = []GroupSettleUpTransferResponse{}
+		for _, transfer := range settlement.SettleUp(nets) {
+			responses[i].SettleUp = append(responses[i].SettleUp, GroupSettleUpTransferResponse{
+				FromUserID: transfer.From,
+				FromEmail:  emails[transfer.From],
+				ToUserID:   transfer.To,
+				ToEmail:    emails[transfer.To],
+				Amount:     transfer.Amount,
+			})
+		}
+	}
+
+	return responses
+}
+
+// checkSettled keeps a member with an open balance in the group, so what
+// they owe or are owed is not lost.
+func checkSettled(ctx context.Context, groups database.GroupRepository, groupID, userID int64) error {
+	balances, err := groups.GetBalances(ctx, groupID)
+	if err != nil {
+		return huma.Error500InternalServerError("Failed to get group balances", err)
+	}
+
+	for _, balance := range balances {
+		if balance.UserID == userID && balance.Net() != 0 {
+			return huma.Error409Conflict(fmt.Sprintf("%s has an open balance in %s; settle up before leaving the group", balance.Email, balance.Currency))
+		}
+	}
+	return nil
+}
+
+// GetGroupBalance reports who owes whom in the group's shared bills, with
+// the settle-up transfers that would clear every balance.
+func (h *SettlementHandler) GetGroupBalance(ctx context.Context, input *GroupIDInput) (*GroupBalanceOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := requireGroupRole(ctx, h.groupRepository, input.GroupID, int64(userID), database.GroupRoleViewer); err != nil {
+		return nil, err
+	}
+
+	balances, err := h.groupRepository.GetBalances(ctx, input.GroupID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get group balances", err)
+	}
+
+	resp := &GroupBalanceOutput{}
+	resp.Body.Data = groupBalances(balances)
+	return resp, nil
+}
+
+type NewGroupSettlementInput struct {
+	GroupID int64 `path:"groupId" doc:"Group ID"`
+	Body    struct {
+		FromUserID int64   `json:"fromUserId,omitempty" doc:"Member who paid. Defaults to the current user"`
+		ToUserID   int64   `json:"toUserId" doc:"Member who was paid"`
+		Amount     float64 `json:"amount" exclusiveMinimum:"0" doc:"Amount in major units, e.g. 12.50"`
+		Currency   string  `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code of the balance settled. Defaults to the user's base currency"`
+		Note       string  `json:"note,omitempty" maxLength:"200"`
+		OccurredAt string  `json:"occurredAt,omitempty" doc:"When the payment was made, as YYYY-MM-DD or an RFC 3339 date-time. Defaults to now"`
+	}
+}
+
+type CreatedGroupSettlementOutput struct {
+	Body struct {
+		Data GroupSettlementResponse `json:"data" doc:"Settlement recorded successfully"`
+	}
+}
+
+// CreateGroupSettlement records a payment between two members, which moves
+// their balances towards zero. Members record payments they made or
+// received; owners may record any.
+func (h *SettlementHandler) CreateGroupSettlement(ctx context.Context, input *NewGroupSettlementInput) (*CreatedGroupSettlementOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	occurredAt, err := parseOccurredAt(input.Body.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	fromUserID := input.Body.FromUserID
+	if fromUserID == 0 {
+		fromUserID = int64(userID)
+	}
+	if fromUserID == input.Body.ToUserID {
+		return nil, huma.Error422UnprocessableEntity("A settlement needs two different members")
+	}
+
+	var created *database.GroupSettlement
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		groups := tx.GroupRepository()
+		member, err := requireGroupRole(ctx, groups, input.GroupID, int64(userID), database.GroupRoleMember)
+		if err != nil {
+			return err
+		}
+		if member.Role != database.GroupRoleOwner && fromUserID != int64(userID) && input.Body.ToUserID != int64(userID) {
+			return huma.Error403Forbidden("Only owners can record settlements between other members")
+		}
+
+		for _, id := range []int64{fromUserID, input.Body.ToUserID} {
+			_, err := groups.GetMember(ctx, input.GroupID, id)
+			if errors.Is(err, database.ErrGroupMemberNotFound) {
+				return huma.Error422UnprocessableEntity(fmt.Sprintf("User %d is not a member of the group", id))
+			}
+			if err != nil {
+				return huma.Error500InternalServerError("Failed to get group member", err)
+			}
+		}
+
+		settlementCurrency, err := resolveCurrency(ctx, tx.UserRepository(), int64(userID), input.Body.Currency)
+		if err != nil {
+			return err
+		}
+		amount := settlementCurrency.ToMinor(input.Body.Amount)
+		if amount <= 0 {
+			return huma.Error422UnprocessableEntity(fmt.Sprintf("amount is less than the smallest unit of %s", settlementCurrency.Code))
+		}
+
+		created, err = groups.CreateSettlement(ctx, database.NewGroupSettlementInput{
+			GroupID:    input.GroupID,
+			FromUserID: fromUserID,
+			ToUserID:   input.Body.ToUserID,
+			Amount:     amount,
+			Currency:   settlementCurrency.Code,
+			Note:       input.Body.Note,
+			OccurredAt: occurredAt,
+			CreatedBy:  int64(userID),
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to record settlement", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedGroupSettlementOutput{}
+	resp.Body.Data = toGroupSettlementResponse(*created)
+	return resp, nil
+}
+
+type ListGroupSettlementOutput struct {
+	Body struct {
+		Data []GroupSettlementResponse `json:"data" doc:"Settlements, most recent first"`
+	}
+}
+
+func (h *SettlementHandler) ListGroupSettlement(ctx context.Context, input *GroupIDInput) (*ListGroupSettlementOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := requireGroupRole(ctx, h.groupRepository, input.GroupID, int64(userID), database.GroupRoleViewer); err != nil {
+		return nil, err
+	}
+
+	settlements, err := h.groupRepository.ListSettlements(ctx, input.GroupID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list settlements", err)
+	}
+
+	resp := &ListGroupSettlementOutput{}
+	resp.Body.Data = make([]GroupSettlementResponse, len(settlements))
+	for index, recorded := range settlements {
+		resp.Body.Data[index] = toGroupSettlementResponse(recorded)
+	}
+	return resp, nil
+}
+
+type GroupSettlementIDInput struct {
+	GroupID      int64 `path:"groupId" doc:"Group ID"`
+	SettlementID int64 `path:"settlementId" doc:"Settlement ID"`
+}
+
+// DeleteGroupSettlement undoes a settlement recorded by mistake. Whoever
+// recorded it and owners may delete it.
+func (h *SettlementHandler) DeleteGroupSettlement(ctx context.Context, input *GroupSettlementIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		groups := tx.GroupRepository()
+		member, err := requireGroupRole(ctx, groups, input.GroupID, int64(userID), database.GroupRoleMember)
+		if err != nil {
+			return err
+		}
+
+		recorded, err := groups.GetSettlement(ctx, input.SettlementID)
+		if err != nil || recorded.GroupID != input.GroupID {
+			return huma.Error404NotFound("Settlement not found")
+		}
+		if member.Role != database.GroupRoleOwner && recorded.CreatedBy != int64(userID) {
+			return huma.Error403Forbidden("Only whoever recorded a settlement or an owner can delete it")
+		}
+
+		if err := groups.DeleteSettlement(ctx, input.SettlementID); err != nil {
+			return huma.Error500InternalServerError("Failed to delete settlement", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type GroupSettlementResponse struct {
+	ID         int64     `json:"id"`
+	FromUserID int64     `json:"fromUserId"`
+	FromEmail  string    `json:"fromEmail"`
+	ToUserID   int64     `json:"toUserId"`
+	ToEmail    string    `json:"toEmail"`
+	Amount     int64     `json:"amount" doc:"Amount in minor units of currency"`
+	Currency   string    `json:"currency" doc:"ISO 4217 code"`
+	Note       string    `json:"note"`
+	OccurredAt time.Time `json:"occurredAt"`
+	CreatedBy  int64     `json:"createdBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func toGroupSettlementResponse(recorded database.GroupSettlement) GroupSettlementResponse {
+	return GroupSettlementResponse{
+		ID:         recorded.ID,
+		FromUserID: recorded.FromUserID,
+		FromEmail:  recorded.FromEmail,
+		ToUserID:   recorded.ToUserID,
+		ToEmail:    recorded.ToEmail,
+		Amount:     recorded.Amount,
+		Currency:   recorded.Currency,
+		Note:       recorded.Note,
+		OccurredAt: recorded.OccurredAt,
+		CreatedBy:  recorded.CreatedBy,
+		CreatedAt:  recorded.CreatedAt,
+	}
+}
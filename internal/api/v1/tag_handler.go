@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"context"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type TagHandler struct {
+	tagRepository database.TagRepository
+}
+
+func NewTagHandler(db database.Service) *TagHandler {
+	return &TagHandler{tagRepository: db.TagRepository()}
+}
+
+type ListTagInput struct {
+}
+
+type ListTagOutput struct {
+	Body struct {
+		Data []TagResponse `json:"data" doc:"User's tags in alphabetical order"`
+	}
+}
+
+type TagResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (h *TagHandler) ListTag(ctx context.Context, input *ListTagInput) (*ListTagOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := h.tagRepository.List(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list tags", err)
+	}
+
+	resp := &ListTagOutput{}
+	resp.Body.Data = make([]TagResponse, len(tags))
+	for index, tag := range tags {
+		resp.Body.Data[index] = TagResponse{
+			ID:        tag.ID,
+			Name:      tag.Name,
+			CreatedAt: tag.CreatedAt,
+			UpdatedAt: tag.UpdatedAt,
+		}
+	}
+
+	return resp, nil
+}
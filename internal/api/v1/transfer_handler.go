@@ -0,0 +1,322 @@
+package v1
+
+import (
+	"context"
+	"gastoslog/internal/currency"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type TransferHandler struct {
+	db                 database.Service
+	transferRepository database.TransferRepository
+}
+
+func NewTransferHandler(db database.Service) *TransferHandler {
+	return &TransferHandler{
+		db:                 db,
+		transferRepository: db.TransferRepository(),
+	}
+}
+
+type TransferInputBody struct {
+	FromWalletID int64   `json:"fromWalletId" doc:"Wallet the money leaves"`
+	ToWalletID   int64   `json:"toWalletId" doc:"Wallet the money reaches"`
+	Amount       float64 `json:"amount" minimum:"1" doc:"Amount leaving the source wallet, in major units of its currency"`
+	ToAmount     float64 `json:"toAmount,omitempty" doc:"Amount reaching the destination wallet, in major units of its currency. Required between wallets in different currencies, otherwise it is the amount"`
+	Fee          float64 `json:"fee,omitempty" minimum:"0" doc:"Fee charged on top of the amount, in major units of the source wallet's currency"`
+	Description  string  `json:"description,omitempty"`
+	OccurredAt   string  `json:"occurredAt,omitempty" doc:"When the money moved, as YYYY-MM-DD or an RFC 3339 date-time. Defaults to now, and is unchanged when omitted on update"`
+}
+
+// parseTransfer validates a request body against the user's wallets into the
+// fields of a transfer in minor units.
+func parseTransfer(ctx context.Context, tx database.Repositories, userID int64, body TransferInputBody) (*database.NewTransferInput, error) {
+	if body.FromWalletID == body.ToWalletID {
+		return nil, huma.Error422UnprocessableEntity("A transfer needs two different wallets")
+	}
+
+	occurredAt, err := parseOccurredAt(body.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := getOwnedWallet(ctx, tx.WalletRepository(), userID, body.FromWalletID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := getOwnedWallet(ctx, tx.WalletRepository(), userID, body.ToWalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromCurrency, err := currency.Lookup(from.Currency)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Invalid wallet currency", err)
+	}
+	toCurrency, err := currency.Lookup(to.Currency)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Invalid wallet currency", err)
+	}
+
+	amount := fromCurrency.ToMinor(body.Amount)
+	toAmount := toCurrency.ToMinor(body.ToAmount)
+	switch {
+	case from.Currency == to.Currency && body.ToAmount == 0:
+		toAmount = amount
+	case from.Currency == to.Currency && toAmount != amount:
+		return nil, huma.Error422UnprocessableEntity("toAmount must equal amount between wallets in the same currency; record any charge as the fee")
+	case from.Currency != to.Currency && toAmount <= 0:
+		return nil, huma.Error422UnprocessableEntity("toAmount in " + to.Currency + " is required to transfer from " + from.Currency)
+	}
+
+	return &database.NewTransferInput{
+		UserID:       userID,
+		FromWalletID: from.ID,
+		ToWalletID:   to.ID,
+		Amount:       amount,
+		ToAmount:     toAmount,
+		Fee:          fromCurrency.ToMinor(body.Fee),
+		Description:  body.Description,
+		OccurredAt:   occurredAt,
+	}, nil
+}
+
+type NewTransferInput struct {
+	Body TransferInputBody
+}
+
+type CreatedTransferOutput struct {
+	Body struct {
+		Data TransferResponse `json:"data" doc:"Transfer created successfully"`
+	}
+}
+
+func (h *TransferHandler) CreateTransfer(ctx context.Context, input *NewTransferInput) (*CreatedTransferOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *database.Transfer
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		transfer, err := parseTransfer(ctx, tx, int64(userID), input.Body)
+		if err != nil {
+			return err
+		}
+
+		created, err = tx.TransferRepository().Create(ctx, *transfer)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create transfer", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedTransferOutput{}
+	resp.Body.Data = toTransferResponse(*created)
+	return resp, nil
+}
+
+type ListTransferInput struct {
+	Page   int   `query:"page" default:"1" doc:"Page number of pagination"`
+	Limit  int   `query:"limit" default:"10" doc:"Limit per page of pagination"`
+	Wallet int64 `query:"wallet" doc:"Filter by a wallet on either side of the transfer"`
+}
+
+type ListTransferOutput struct {
+	Body struct {
+		Data []TransferResponse `json:"data" doc:"Transfers, most recent first"`
+		Meta PageMeta           `json:"meta"`
+	}
+}
+
+func (h *TransferHandler) ListTransfer(ctx context.Context, input *ListTransferInput) (*ListTransferOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := h.transferRepository.List(ctx, database.ListTransferInput{
+		UserID:   int64(userID),
+		Page:     input.Page,
+		Limit:    input.Limit,
+		WalletID: input.Wallet,
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list transfers", err)
+	}
+
+	resp := &ListTransferOutput{}
+	resp.Body.Data = make([]TransferResponse, len(list.Transfers))
+	for index, transfer := range list.Transfers {
+		resp.Body.Data[index] = toTransferResponse(transfer)
+	}
+	resp.Body.Meta.Page = input.Page
+	resp.Body.Meta.Limit = input.Limit
+	resp.Body.Meta.HasMore = list.HasMore
+	resp.Body.Meta.TotalCount = list.TotalCount
+
+	return resp, nil
+}
+
+type TransferIDInput struct {
+	TransferID int64 `path:"transferId" doc:"Transfer ID"`
+}
+
+// checkTransfer makes sure the transfer belongs to the user.
+func checkTransfer(ctx context.Context, transfers database.TransferRepository, userID, transferID int64) error {
+	exist, err := transfers.ExistWithUserID(ctx, database.ExistTransferWithUserIDInput{UserID: userID, TransferID: transferID})
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return huma.Error404NotFound("Transfer not found")
+	}
+	return nil
+}
+
+type DetailTransferOutput struct {
+	Body struct {
+		Data TransferResponse `json:"data" doc:"Transfer detail"`
+	}
+}
+
+func (h *TransferHandler) DetailTransfer(ctx context.Context, input *TransferIDInput) (*DetailTransferOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTransfer(ctx, h.transferRepository, int64(userID), input.TransferID); err != nil {
+		return nil, err
+	}
+
+	transfer, err := h.transferRepository.GetByID(ctx, input.TransferID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get transfer", err)
+	}
+
+	resp := &DetailTransferOutput{}
+	resp.Body.Data = toTransferResponse(*transfer)
+	return resp, nil
+}
+
+type UpdateTransferInput struct {
+	TransferID int64 `path:"transferId" doc:"Transfer ID"`
+	Body       TransferInputBody
+}
+
+type UpdatedTransferOutput struct {
+	Body struct {
+		Data TransferResponse `json:"data" doc:"Transfer updated successfully"`
+	}
+}
+
+func (h *TransferHandler) UpdateTransfer(ctx context.Context, input *UpdateTransferInput) (*UpdatedTransferOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *database.Transfer
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if err := checkTransfer(ctx, tx.TransferRepository(), int64(userID), input.TransferID); err != nil {
+			return err
+		}
+
+		transfer, err := parseTransfer(ctx, tx, int64(userID), input.Body)
+		if err != nil {
+			return err
+		}
+
+		err = tx.TransferRepository().Update(ctx, database.UpdateTransferInput{
+			TransferID:   input.TransferID,
+			UserID:       int64(userID),
+			FromWalletID: transfer.FromWalletID,
+			ToWalletID:   transfer.ToWalletID,
+			Amount:       transfer.Amount,
+			ToAmount:     transfer.ToAmount,
+			Fee:          transfer.Fee,
+			Description:  transfer.Description,
+			OccurredAt:   transfer.OccurredAt,
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to update transfer", err)
+		}
+
+		updated, err = tx.TransferRepository().GetByID(ctx, input.TransferID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UpdatedTransferOutput{}
+	resp.Body.Data = toTransferResponse(*updated)
+	return resp, nil
+}
+
+func (h *TransferHandler) DeleteTransfer(ctx context.Context, input *TransferIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTransfer(ctx, h.transferRepository, int64(userID), input.TransferID); err != nil {
+		return nil, err
+	}
+
+	if err := h.transferRepository.Delete(ctx, input.TransferID); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete transfer", err)
+	}
+
+	return nil, nil
+}
+
+type TransferWalletResponse struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Currency string `json:"currency" doc:"ISO 4217 code"`
+}
+
+type TransferResponse struct {
+	ID          int64                  `json:"id"`
+	From        TransferWalletResponse `json:"from"`
+	To          TransferWalletResponse `json:"to"`
+	Amount      int64                  `json:"amount" doc:"Amount leaving the source wallet, in minor units of its currency"`
+	ToAmount    int64                  `json:"toAmount" doc:"Amount reaching the destination wallet, in minor units of its currency"`
+	Fee         int64                  `json:"fee" doc:"Fee in minor units of the source wallet's currency"`
+	Description string                 `json:"description"`
+	OccurredAt  time.Time              `json:"occurredAt"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	UpdatedAt   time.Time              `json:"updatedAt"`
+}
+
+func toTransferResponse(transfer database.Transfer) TransferResponse {
+	return TransferResponse{
+		ID: transfer.ID,
+		From: TransferWalletResponse{
+			ID:       transfer.FromWalletID,
+			Name:     transfer.FromWalletName,
+			Currency: transfer.FromWalletCurrency,
+		},
+		To: TransferWalletResponse{
+			ID:       transfer.ToWalletID,
+			Name:     transfer.ToWalletName,
+			Currency: transfer.ToWalletCurrency,
+		},
+		Amount:      transfer.Amount,
+		ToAmount:    transfer.ToAmount,
+		Fee:         transfer.Fee,
+		Description: transfer.Description,
+		OccurredAt:  transfer.OccurredAt,
+		CreatedAt:   transfer.CreatedAt,
+		UpdatedAt:   transfer.UpdatedAt,
+	}
+}
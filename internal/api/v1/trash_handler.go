@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"context"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type TrashHandler struct {
+	expenseRepository  database.ExpenseRepository
+	incomeRepository   database.IncomeRepository
+	categoryRepository database.CategoryRepository
+	retention          time.Duration
+}
+
+func NewTrashHandler(db database.Service, retention time.Duration) *TrashHandler {
+	return &TrashHandler{
+		expenseRepository:  db.ExpenseRepository(),
+		incomeRepository:   db.IncomeRepository(),
+		categoryRepository: db.CategoryRepository(),
+		retention:          retention,
+	}
+}
+
+type ListTrashInput struct {
+}
+
+type ListTrashOutput struct {
+	Body struct {
+		Expenses   []TrashedExpenseResponse  `json:"expenses" doc:"Deleted expenses, most recent first"`
+		Incomes    []TrashedIncomeResponse   `json:"incomes" doc:"Deleted incomes, most recent first"`
+		Categories []TrashedCategoryResponse `json:"categories" doc:"Deleted categories, most recent first"`
+	}
+}
+
+type TrashedExpenseResponse struct {
+	ExpenseResponse
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt" doc:"When the expense is permanently deleted"`
+}
+
+type TrashedIncomeResponse struct {
+	IncomeResponse
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt" doc:"When the income is permanently deleted"`
+}
+
+type TrashedCategoryResponse struct {
+	CategoryResponse
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt" doc:"When the category is permanently deleted"`
+}
+
+func (h *TrashHandler) ListTrash(ctx context.Context, input *ListTrashInput) (*ListTrashOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	expenses, err := h.expenseRepository.ListDeleted(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list deleted expenses", err)
+	}
+
+	incomes, err := h.incomeRepository.ListDeleted(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list deleted incomes", err)
+	}
+
+	categories, err := h.categoryRepository.ListDeleted(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list deleted categories", err)
+	}
+
+	resp := &ListTrashOutput{}
+	resp.Body.Expenses = make([]TrashedExpenseResponse, len(expenses))
+	for index, expense := range expenses {
+		resp.Body.Expenses[index] = TrashedExpenseResponse{
+			ExpenseResponse: toExpenseResponse(expense),
+			DeletedAt:       expense.DeletedAt.Time,
+			PurgeAt:         expense.DeletedAt.Time.Add(h.retention),
+		}
+	}
+
+	resp.Body.Incomes = make([]TrashedIncomeResponse, len(incomes))
+	for index, income := range incomes {
+		resp.Body.Incomes[index] = TrashedIncomeResponse{
+			IncomeResponse: toIncomeResponse(income),
+			DeletedAt:      income.DeletedAt.Time,
+			PurgeAt:        income.DeletedAt.Time.Add(h.retention),
+		}
+	}
+
+	resp.Body.Categories = make([]TrashedCategoryResponse, len(categories))
+	for index, category := range categories {
+		resp.Body.Categories[index] = TrashedCategoryResponse{
+			CategoryResponse: toCategoryResponse(category),
+			DeletedAt:        *category.DeletedAt,
+			PurgeAt:          category.DeletedAt.Add(h.retention),
+		}
+	}
+
+	return resp, nil
+}
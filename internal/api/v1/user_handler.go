@@ -2,9 +2,11 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"gastoslog/internal/account"
 	"gastoslog/internal/auth"
 	"gastoslog/internal/config"
+	"gastoslog/internal/currency"
 	"gastoslog/internal/middleware"
 	"time"
 
@@ -112,6 +114,38 @@ func (h *UserHandler) Me(ctx context.Context, input *MeInput) (*MeOutput, error)
 	return resp, nil
 }
 
+type UpdateMeInput struct {
+	Body struct {
+		BaseCurrency string `json:"baseCurrency" minLength:"3" maxLength:"3" doc:"ISO 4217 code overviews are reported in"`
+	}
+}
+
+type UpdateMeOutput struct {
+	Body struct {
+		User account.UserResponse `json:"user" doc:"Updated user"`
+	}
+}
+
+func (h *UserHandler) UpdateMe(ctx context.Context, input *UpdateMeInput) (*UpdateMeOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.userService.UpdateBaseCurrency(ctx, int64(userID), input.Body.BaseCurrency)
+	if errors.Is(err, currency.ErrUnknown) {
+		return nil, huma.Error422UnprocessableEntity("Unknown currency " + input.Body.BaseCurrency)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to update user", err)
+	}
+
+	resp := &UpdateMeOutput{}
+	resp.Body.User = *user
+
+	return resp, nil
+}
+
 type RefreshTokenInput struct {
 	Body struct {
 		RefreshToken string `json:"refresh_token" doc:"JWT refresh token"`
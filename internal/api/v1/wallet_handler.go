@@ -0,0 +1,386 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gastoslog/internal/currency"
+	"gastoslog/internal/database"
+	"gastoslog/internal/middleware"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type WalletHandler struct {
+	db               database.Service
+	walletRepository database.WalletRepository
+	userRepository   database.UserRepository
+}
+
+func NewWalletHandler(db database.Service) *WalletHandler {
+	return &WalletHandler{
+		db:               db,
+		walletRepository: db.WalletRepository(),
+		userRepository:   db.UserRepository(),
+	}
+}
+
+// resolveEntryCurrency checks that walletID, when not 0, belongs to the user
+// and resolves the currency of an expense or income paid from or into it.
+// Entries on a wallet are in the wallet's currency, which is also the
+// default; without a wallet the default is the user's base currency.
+func resolveEntryCurrency(ctx context.Context, repos database.Repositories, userID, walletID int64, code string) (sql.NullInt64, currency.Currency, error) {
+	if walletID == 0 {
+		entryCurrency, err := resolveCurrency(ctx, repos.UserRepository(), userID, code)
+		return sql.NullInt64{}, entryCurrency, err
+	}
+
+	wallet, err := getOwnedWallet(ctx, repos.WalletRepository(), userID, walletID)
+	if err != nil {
+		return sql.NullInt64{}, currency.Currency{}, err
+	}
+
+	if code != "" && !strings.EqualFold(code, wallet.Currency) {
+		return sql.NullInt64{}, currency.Currency{}, huma.Error422UnprocessableEntity(fmt.Sprintf("Wallet %s holds %s; entries paid from or into it must be in %s", wallet.Name, wallet.Currency, wallet.Currency))
+	}
+
+	entryCurrency, err := resolveCurrency(ctx, repos.UserRepository(), userID, wallet.Currency)
+	return sql.NullInt64{Int64: walletID, Valid: true}, entryCurrency, err
+}
+
+// getOwnedWallet loads a wallet of the user, or fails with 404.
+func getOwnedWallet(ctx context.Context, wallets database.WalletRepository, userID, walletID int64) (*database.Wallet, error) {
+	exist, err := wallets.ExistWithUserID(ctx, database.ExistWalletWithUserIDInput{UserID: userID, WalletID: walletID})
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, huma.Error404NotFound("Wallet not found")
+	}
+
+	wallet, err := wallets.GetByID(ctx, walletID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get wallet", err)
+	}
+	return wallet, nil
+}
+
+// checkWalletName makes sure no other wallet of the user is called name.
+// walletID is 0 for a new wallet.
+func checkWalletName(ctx context.Context, tx database.Repositories, userID, walletID int64, name string) error {
+	wallets, err := tx.WalletRepository().List(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, wallet := range wallets {
+		if wallet.ID != walletID && strings.EqualFold(wallet.Name, name) {
+			return huma.Error409Conflict("A wallet named " + wallet.Name + " already exists")
+		}
+	}
+	return nil
+}
+
+type NewWalletInput struct {
+	Body struct {
+		Name           string  `json:"name" minLength:"1" maxLength:"100" example:"GCash"`
+		Kind           string  `json:"kind" enum:"cash,bank,ewallet,credit_card,other" doc:"What the wallet is"`
+		Currency       string  `json:"currency,omitempty" minLength:"3" maxLength:"3" doc:"ISO 4217 code of the money it holds. Defaults to the user's base currency and cannot be changed later"`
+		OpeningBalance float64 `json:"openingBalance,omitempty" doc:"Balance before any recorded entry, in major units. Negative for money owed, e.g. on a credit card"`
+	}
+}
+
+type CreatedWalletOutput struct {
+	Body struct {
+		Data WalletResponse `json:"data" doc:"Wallet created successfully"`
+	}
+}
+
+func (h *WalletHandler) CreateWallet(ctx context.Context, input *NewWalletInput) (*CreatedWalletOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	walletCurrency, err := resolveCurrency(ctx, h.userRepository, int64(userID), input.Body.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *database.Wallet
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if err := checkWalletName(ctx, tx, int64(userID), 0, input.Body.Name); err != nil {
+			return err
+		}
+
+		created, err = tx.WalletRepository().Create(ctx, database.NewWalletInput{
+			UserID:         int64(userID),
+			Name:           input.Body.Name,
+			Kind:           input.Body.Kind,
+			Currency:       walletCurrency.Code,
+			OpeningBalance: walletCurrency.ToMinor(input.Body.OpeningBalance),
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to create wallet", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreatedWalletOutput{}
+	resp.Body.Data = toWalletResponse(*created)
+	return resp, nil
+}
+
+type ListWalletInput struct {
+}
+
+type ListWalletOutput struct {
+	Body struct {
+		Data []WalletResponse `json:"data" doc:"Wallets by name, with their current balances"`
+	}
+}
+
+func (h *WalletHandler) ListWallet(ctx context.Context, input *ListWalletInput) (*ListWalletOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wallets, err := h.walletRepository.List(ctx, int64(userID))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list wallets", err)
+	}
+
+	resp := &ListWalletOutput{}
+	resp.Body.Data = make([]WalletResponse, len(wallets))
+	for index, wallet := range wallets {
+		resp.Body.Data[index] = toWalletResponse(wallet)
+	}
+
+	return resp, nil
+}
+
+type WalletIDInput struct {
+	WalletID int64 `path:"walletId" doc:"Wallet ID"`
+}
+
+type DetailWalletOutput struct {
+	Body struct {
+		Data WalletResponse `json:"data" doc:"Wallet detail"`
+	}
+}
+
+func (h *WalletHandler) DetailWallet(ctx context.Context, input *WalletIDInput) (*DetailWalletOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := getOwnedWallet(ctx, h.walletRepository, int64(userID), input.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &DetailWalletOutput{}
+	resp.Body.Data = toWalletResponse(*wallet)
+	return resp, nil
+}
+
+type UpdateWalletInput struct {
+	WalletID int64 `path:"walletId" doc:"Wallet ID"`
+	Body     struct {
+		Name           string  `json:"name" minLength:"1" maxLength:"100"`
+		Kind           string  `json:"kind" enum:"cash,bank,ewallet,credit_card,other" doc:"What the wallet is"`
+		OpeningBalance float64 `json:"openingBalance,omitempty" doc:"Balance before any recorded entry, in major units of the wallet's currency"`
+	}
+}
+
+type UpdatedWalletOutput struct {
+	Body struct {
+		Data WalletResponse `json:"data" doc:"Wallet updated successfully"`
+	}
+}
+
+func (h *WalletHandler) UpdateWallet(ctx context.Context, input *UpdateWalletInput) (*UpdatedWalletOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *database.Wallet
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		wallet, err := getOwnedWallet(ctx, tx.WalletRepository(), int64(userID), input.WalletID)
+		if err != nil {
+			return err
+		}
+		if err := checkWalletName(ctx, tx, int64(userID), wallet.ID, input.Body.Name); err != nil {
+			return err
+		}
+
+		walletCurrency, err := currency.Lookup(wallet.Currency)
+		if err != nil {
+			return huma.Error500InternalServerError("Invalid wallet currency", err)
+		}
+
+		err = tx.WalletRepository().Update(ctx, database.UpdateWalletInput{
+			WalletID:       wallet.ID,
+			UserID:         int64(userID),
+			Name:           input.Body.Name,
+			Kind:           input.Body.Kind,
+			OpeningBalance: walletCurrency.ToMinor(input.Body.OpeningBalance),
+		})
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to update wallet", err)
+		}
+
+		updated, err = tx.WalletRepository().GetByID(ctx, wallet.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UpdatedWalletOutput{}
+	resp.Body.Data = toWalletResponse(*updated)
+	return resp, nil
+}
+
+func (h *WalletHandler) DeleteWallet(ctx context.Context, input *WalletIDInput) (*struct{}, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.WithTx(ctx, func(tx database.Repositories) error {
+		if _, err := getOwnedWallet(ctx, tx.WalletRepository(), int64(userID), input.WalletID); err != nil {
+			return err
+		}
+
+		count, err := tx.WalletRepository().CountEntries(ctx, input.WalletID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return huma.Error409Conflict(fmt.Sprintf("Move or delete the %d entries recorded against this wallet first, including trashed ones, recurring expenses and transfers", count))
+		}
+
+		if err := tx.WalletRepository().Delete(ctx, input.WalletID); err != nil {
+			return huma.Error500InternalServerError("Failed to delete wallet", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type WalletLedgerInput struct {
+	WalletID int64 `path:"walletId" doc:"Wallet ID"`
+	Page     int   `query:"page" default:"1" doc:"Page number of pagination"`
+	Limit    int   `query:"limit" default:"10" doc:"Limit per page of pagination"`
+}
+
+type WalletLedgerOutput struct {
+	Body struct {
+		Data   []LedgerEntryResponse `json:"data" doc:"Entries and transfers on the wallet, most recent first"`
+		Wallet WalletResponse        `json:"wallet"`
+		Meta   PageMeta              `json:"meta"`
+	}
+}
+
+type LedgerEntryResponse struct {
+	Type                  string    `json:"type" enum:"expense,income,transfer_in,transfer_out" doc:"Kind of entry; id refers to the expense, income or transfer"`
+	ID                    int64     `json:"id"`
+	Description           string    `json:"description"`
+	CategoryID            *int64    `json:"categoryId" doc:"Category of an expense or income, null for transfers"`
+	CategoryName          *string   `json:"categoryName"`
+	CounterpartWalletID   *int64    `json:"counterpartWalletId" doc:"The other wallet of a transfer, null otherwise"`
+	CounterpartWalletName *string   `json:"counterpartWalletName"`
+	OccurredAt            time.Time `json:"occurredAt"`
+	Amount                int64     `json:"amount" doc:"Change to the balance in minor units of the wallet's currency, negative for money going out. Transfers out include their fee"`
+	Balance               int64     `json:"balance" doc:"Balance right after the entry, in minor units of the wallet's currency"`
+}
+
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func (h *WalletHandler) GetWalletLedger(ctx context.Context, input *WalletLedgerInput) (*WalletLedgerOutput, error) {
+	userID, err := middleware.GetContextUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := getOwnedWallet(ctx, h.walletRepository, int64(userID), input.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := h.walletRepository.Ledger(ctx, database.LedgerInput{
+		WalletID:       wallet.ID,
+		OpeningBalance: wallet.OpeningBalance,
+		Page:           input.Page,
+		Limit:          input.Limit,
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get wallet ledger", err)
+	}
+
+	resp := &WalletLedgerOutput{}
+	resp.Body.Data = make([]LedgerEntryResponse, len(ledger.Entries))
+	for index, entry := range ledger.Entries {
+		resp.Body.Data[index] = LedgerEntryResponse{
+			Type:         entry.Type,
+			ID:           entry.ID,
+			Description:  entry.Description,
+			CategoryID:   nullInt64Ptr(entry.CategoryID),
+			CategoryName: nullStringPtr(entry.CategoryName),
+			OccurredAt:   entry.OccurredAt,
+			Amount:       entry.Amount,
+			Balance:      entry.Balance,
+
+			CounterpartWalletID:   nullInt64Ptr(entry.CounterpartWalletID),
+			CounterpartWalletName: nullStringPtr(entry.CounterpartWalletName),
+		}
+	}
+	resp.Body.Wallet = toWalletResponse(*wallet)
+	resp.Body.Meta.Page = input.Page
+	resp.Body.Meta.Limit = input.Limit
+	resp.Body.Meta.HasMore = ledger.HasMore
+	resp.Body.Meta.TotalCount = ledger.TotalCount
+
+	return resp, nil
+}
+
+type WalletResponse struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	Kind           string    `json:"kind" enum:"cash,bank,ewallet,credit_card,other"`
+	Currency       string    `json:"currency" doc:"ISO 4217 code"`
+	OpeningBalance int64     `json:"openingBalance" doc:"Balance before any recorded entry, in minor units of currency"`
+	Balance        int64     `json:"balance" doc:"Current balance in minor units of currency"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func toWalletResponse(wallet database.Wallet) WalletResponse {
+	return WalletResponse{
+		ID:             wallet.ID,
+		Name:           wallet.Name,
+		Kind:           wallet.Kind,
+		Currency:       wallet.Currency,
+		OpeningBalance: wallet.OpeningBalance,
+		Balance:        wallet.Balance,
+		CreatedAt:      wallet.CreatedAt,
+		UpdatedAt:      wallet.UpdatedAt,
+	}
+}
@@ -42,6 +42,16 @@ var (
 	DB_URL string
 )
 
+// Trash
+var (
+	TRASH_RETENTION_DAYS string
+)
+
+// Attachments
+var (
+	ATTACHMENTS_DIR string
+)
+
 func assignValuesByEnvFile() {
 	// General
 	APP_ENV = envs["APP_ENV"]
@@ -51,6 +61,12 @@ func assignValuesByEnvFile() {
 	// Database
 	DB_URL = envs["BLUEPRINT_DB_URL"]
 
+	// Trash
+	TRASH_RETENTION_DAYS = envs["TRASH_RETENTION_DAYS"]
+
+	// Attachments
+	ATTACHMENTS_DIR = envs["ATTACHMENTS_DIR"]
+
 	// JWT
 	if envs["AUTH_SECRET"] == "" {
 		fmt.Printf("AUTH_SECRET env missing")
@@ -69,6 +85,12 @@ func assignValuesBySecret() {
 	// Database
 	DB_URL = os.Getenv("BLUEPRINT_DB_URL")
 
+	// Trash
+	TRASH_RETENTION_DAYS = os.Getenv("TRASH_RETENTION_DAYS")
+
+	// Attachments
+	ATTACHMENTS_DIR = os.Getenv("ATTACHMENTS_DIR")
+
 	// JWT
 	if os.Getenv("AUTH_SECRET") == "" {
 		fmt.Printf("AUTH_SECRET env missing")
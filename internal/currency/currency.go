@@ -0,0 +1,97 @@
+package currency
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Default is the currency assumed for users and expenses recorded before
+// currencies were tracked.
+const Default = "PHP"
+
+var ErrUnknown = errors.New("unknown currency")
+
+// Currency is an ISO 4217 currency. Amounts are stored as integers in the
+// currency's minor unit, e.g. centavos for PHP and yen for JPY.
+type Currency struct {
+	Code       string
+	MinorUnits int
+}
+
+// minorUnits lists active ISO 4217 codes that do not use two decimal places.
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+
+	"CLF": 4, "UYW": 4,
+}
+
+// twoDecimal lists active ISO 4217 codes with two decimal places.
+var twoDecimal = []string{
+	"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN",
+	"BAM", "BBD", "BDT", "BGN", "BMD", "BND", "BOB", "BRL", "BSD", "BTN",
+	"BWP", "BYN", "BZD", "CAD", "CDF", "CHF", "CNY", "COP", "CRC", "CUP",
+	"CVE", "CZK", "DKK", "DOP", "DZD", "EGP", "ERN", "ETB", "EUR", "FJD",
+	"FKP", "GBP", "GEL", "GHS", "GIP", "GMD", "GTQ", "GYD", "HKD", "HNL",
+	"HTG", "HUF", "IDR", "ILS", "INR", "IRR", "JMD", "KES", "KGS", "KHR",
+	"KPW", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD", "LSL", "MAD", "MDL",
+	"MGA", "MKD", "MMK", "MNT", "MOP", "MRU", "MUR", "MVR", "MWK", "MXN",
+	"MYR", "MZN", "NAD", "NGN", "NIO", "NOK", "NPR", "NZD", "PAB", "PEN",
+	"PGK", "PHP", "PKR", "PLN", "QAR", "RON", "RSD", "RUB", "SAR", "SBD",
+	"SCR", "SDG", "SEK", "SGD", "SHP", "SLE", "SOS", "SRD", "SSP", "STN",
+	"SVC", "SYP", "SZL", "THB", "TJS", "TMT", "TOP", "TRY", "TTD", "TWD",
+	"TZS", "UAH", "USD", "UYU", "UZS", "VES", "WST", "XCD", "YER", "ZAR",
+	"ZMW", "ZWG",
+}
+
+func init() {
+	for _, code := range twoDecimal {
+		minorUnits[code] = 2
+	}
+}
+
+// Lookup returns the currency for an ISO 4217 code, ignoring case.
+func Lookup(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	units, ok := minorUnits[code]
+	if !ok {
+		return Currency{}, ErrUnknown
+	}
+	return Currency{Code: code, MinorUnits: units}, nil
+}
+
+// Codes returns every supported code in alphabetical order.
+func Codes() []string {
+	codes := make([]string, 0, len(minorUnits))
+	for code := range minorUnits {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func (c Currency) scale() float64 {
+	return math.Pow10(c.MinorUnits)
+}
+
+// ToMinor converts an amount in major units, e.g. 12.34 USD, to minor units,
+// rounding to the nearest one.
+func (c Currency) ToMinor(amount float64) int64 {
+	return int64(math.Round(amount * c.scale()))
+}
+
+// FromMinor converts an amount in minor units back to major units.
+func (c Currency) FromMinor(amount int64) float64 {
+	return float64(amount) / c.scale()
+}
+
+// Convert turns an amount in the minor units of from into the minor units of
+// to, where rate is the price of one unit of from in to.
+func Convert(amount int64, from, to Currency, rate float64) int64 {
+	return to.ToMinor(from.FromMinor(amount) * rate)
+}
@@ -0,0 +1,40 @@
+package currency
+
+import "testing"
+
+func TestMinorUnits(t *testing.T) {
+	tests := []struct {
+		code   string
+		amount float64
+		minor  int64
+	}{
+		{"php", 12.34, 1234},
+		{"JPY", 1500, 1500},
+		{"KWD", 1.234, 1234},
+		{"USD", 0.1 + 0.2, 30},
+	}
+
+	for _, tt := range tests {
+		c, err := Lookup(tt.code)
+		if err != nil {
+			t.Fatalf("Lookup(%q) failed: %v", tt.code, err)
+		}
+		if got := c.ToMinor(tt.amount); got != tt.minor {
+			t.Errorf("%s ToMinor(%v) = %d; want %d", c.Code, tt.amount, got, tt.minor)
+		}
+	}
+
+	if _, err := Lookup("XYZ"); err != ErrUnknown {
+		t.Errorf("expected ErrUnknown; got %v", err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	jpy, _ := Lookup("JPY")
+	php, _ := Lookup("PHP")
+
+	// 1,000 yen at 0.38 PHP per yen is 380.00 PHP
+	if got := Convert(1000, jpy, php, 0.38); got != 38000 {
+		t.Errorf("Convert = %d; want 38000", got)
+	}
+}
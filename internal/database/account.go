@@ -5,8 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"time"
-
-	"github.com/jmoiron/sqlx"
 )
 
 type User struct {
@@ -14,6 +12,7 @@ type User struct {
 	Email           string     `db:"email"`
 	PasswordHash    string     `db:"password_hash"`
 	Role            string     `db:"role"`
+	BaseCurrency    string     `db:"base_currency"`
 	EmailVerifiedAt *time.Time `db:"email_verified_at"`
 	CreatedAt       time.Time  `db:"created_at"`
 	UpdatedAt       time.Time  `db:"updated_at"`
@@ -28,13 +27,14 @@ type UserRepository interface {
 	UpdatePassword(ctx context.Context, id int64, newPassword string) error
 	UpdateLastLogin(ctx context.Context, id int64) error
 	VerifyEmail(ctx context.Context, id int64) error
+	UpdateBaseCurrency(ctx context.Context, id int64, currency string) error
 }
 
 type userRepository struct {
-	db *sqlx.DB
+	db DBTX
 }
 
-func NewUserRepository(db *sqlx.DB) UserRepository {
+func NewUserRepository(db DBTX) UserRepository {
 	return &userRepository{db: db}
 }
 
@@ -43,7 +43,7 @@ func (r *userRepository) Create(ctx context.Context, email, password, role strin
 	query := `
 		INSERT INTO users (email, password_hash, role, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, email_verified_at, created_at, updated_at, last_login_at`
+		RETURNING id, base_currency, email_verified_at, created_at, updated_at, last_login_at`
 
 	now := time.Now()
 	assignedRole := "user"
@@ -62,7 +62,7 @@ func (r *userRepository) Create(ctx context.Context, email, password, role strin
 
 	err := r.db.QueryRowContext(ctx, query,
 		email, password, "user", now, now,
-	).Scan(&user.ID, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
+	).Scan(&user.ID, &user.BaseCurrency, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
 
 	if err != nil {
 		return nil, err
@@ -147,3 +147,14 @@ func (r *userRepository) VerifyEmail(ctx context.Context, id int64) error {
 	_, err := r.db.ExecContext(ctx, query, now, id)
 	return err
 }
+
+func (r *userRepository) UpdateBaseCurrency(ctx context.Context, id int64, currency string) error {
+	query := `
+		UPDATE users
+		SET base_currency = $1,
+			updated_at = $2
+		WHERE id = $3`
+
+	_, err := r.db.ExecContext(ctx, query, currency, time.Now(), id)
+	return err
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Attachment is a file, such as a photo or PDF of a receipt, attached to an
+// expense. SHA256 is the key of its content in the attachment store.
+type Attachment struct {
+	ID          int64     `db:"id"`
+	ExpenseID   int64     `db:"expense_id"`
+	UserID      int64     `db:"user_id"`
+	SHA256      string    `db:"sha256"`
+	Filename    string    `db:"filename"`
+	ContentType string    `db:"content_type"`
+	Size        int64     `db:"size"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, input NewAttachmentInput) (*Attachment, error)
+	GetByID(ctx context.Context, id int64) (*Attachment, error)
+	ListByExpense(ctx context.Context, expenseID int64) ([]Attachment, error)
+	Delete(ctx context.Context, id int64) error
+	ExistWithUserID(ctx context.Context, input ExistAttachmentWithUserIDInput) (bool, error)
+	CountBySHA256(ctx context.Context, sha256 string) (int64, error)
+	PurgeByExpenses(ctx context.Context, deletedBefore time.Time) ([]string, error)
+}
+
+type attachmentRepository struct {
+	db DBTX
+}
+
+func NewAttachmentRepository(db DBTX) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+const attachmentColumns = `
+			id,
+			expense_id,
+			user_id,
+			sha256,
+			filename,
+			content_type,
+			size,
+			created_at`
+
+type NewAttachmentInput struct {
+	ExpenseID   int64
+	UserID      int64
+	SHA256      string
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+func (r *attachmentRepository) Create(ctx context.Context, input NewAttachmentInput) (*Attachment, error) {
+	query := `
+		INSERT INTO attachments (expense_id, user_id, sha256, filename, content_type, size, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query,
+		input.ExpenseID, input.UserID, input.SHA256, input.Filename, input.ContentType, input.Size, time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *attachmentRepository) GetByID(ctx context.Context, id int64) (*Attachment, error) {
+	var attachment Attachment
+	query := `SELECT` + attachmentColumns + ` FROM attachments WHERE id = $1`
+	err := r.db.GetContext(ctx, &attachment, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListByExpense returns the attachments of an expense in upload order.
+func (r *attachmentRepository) ListByExpense(ctx context.Context, expenseID int64) ([]Attachment, error) {
+	attachments := []Attachment{}
+	query := `
+		SELECT` + attachmentColumns + `
+		FROM attachments
+		WHERE expense_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &attachments, query, expenseID); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// Delete removes the attachment record only. The stored file is removed by
+// the caller once CountBySHA256 shows nothing else refers to it.
+func (r *attachmentRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = $1`, id)
+	return err
+}
+
+type ExistAttachmentWithUserIDInput struct {
+	UserID       int64 `doc:"User ID"`
+	AttachmentID int64 `doc:"Attachment ID"`
+}
+
+// ExistWithUserID reports whether the attachment belongs to the user and to
+// an expense of theirs that is not in the trash.
+func (r *attachmentRepository) ExistWithUserID(ctx context.Context, input ExistAttachmentWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM attachments
+		JOIN expenses ON expenses.id = attachments.expense_id
+		WHERE attachments.id = $1
+		AND attachments.user_id = $2
+		AND expenses.user_id = $2
+		AND expenses.deleted_at IS NULL
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.AttachmentID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// CountBySHA256 counts the attachments that refer to a stored file.
+func (r *attachmentRepository) CountBySHA256(ctx context.Context, sha256 string) (int64, error) {
+	var count int64
+	if err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM attachments WHERE sha256 = $1`, sha256); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// PurgeByExpenses removes the attachments of expenses that went to the trash
+// before deletedBefore, ahead of purging the expenses themselves. It returns
+// the keys of the files they referred to, for the caller to remove from the
+// store once no attachment refers to them.
+func (r *attachmentRepository) PurgeByExpenses(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	purged := `
+		SELECT id FROM expenses
+		WHERE deleted_at IS NOT NULL
+		AND deleted_at < $1`
+
+	keys := []string{}
+	if err := r.db.SelectContext(ctx, &keys, `SELECT DISTINCT sha256 FROM attachments WHERE expense_id IN (`+purged+`)`, deletedBefore); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE expense_id IN (`+purged+`)`, deletedBefore); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
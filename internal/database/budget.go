@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	BudgetPeriodWeek  = "week"
+	BudgetPeriodMonth = "month"
+	BudgetPeriodYear  = "year"
+)
+
+// Budget limits spending on a category and its subcategories, or on
+// everything when CategoryID is NULL, for each week, month or year.
+type Budget struct {
+	ID         int64         `db:"id"`
+	UserID     int64         `db:"user_id"`
+	CategoryID sql.NullInt64 `db:"category_id"`
+	Period     string        `db:"period"`
+	// Amount is in the minor unit of Currency
+	Amount    int64     `db:"amount"`
+	Currency  string    `db:"currency"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Window returns the UTC period [from, to) of the budget that contains on.
+// Weeks start on Monday.
+func (b Budget) Window(on time.Time) (from, to time.Time) {
+	on = on.UTC()
+	day := time.Date(on.Year(), on.Month(), on.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch b.Period {
+	case BudgetPeriodWeek:
+		from = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return from, from.AddDate(0, 0, 7)
+	case BudgetPeriodYear:
+		from = time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, 0)
+	default:
+		from = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, 0)
+	}
+}
+
+type BudgetRepository interface {
+	Create(ctx context.Context, input NewBudgetInput) (*Budget, error)
+	GetByID(ctx context.Context, id int64) (*Budget, error)
+	Update(ctx context.Context, input UpdateBudgetInput) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, userID int64) ([]Budget, error)
+	ExistWithUserID(ctx context.Context, input ExistBudgetWithUserIDInput) (bool, error)
+}
+
+type budgetRepository struct {
+	db DBTX
+}
+
+func NewBudgetRepository(db DBTX) BudgetRepository {
+	return &budgetRepository{db: db}
+}
+
+type NewBudgetInput struct {
+	UserID     int64
+	CategoryID sql.NullInt64
+	Period     string
+	// Amount is in the minor unit of Currency
+	Amount   int64
+	Currency string
+}
+
+func (r *budgetRepository) Create(ctx context.Context, input NewBudgetInput) (*Budget, error) {
+	query := `
+		INSERT INTO budgets (user_id, category_id, period, amount, currency, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, user_id, category_id, period, amount, currency, created_at, updated_at`
+
+	now := time.Now()
+
+	budget := &Budget{}
+	err := r.db.QueryRowContext(ctx, query,
+		input.UserID, input.CategoryID, input.Period, input.Amount, input.Currency, now, now,
+	).Scan(&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Period, &budget.Amount, &budget.Currency, &budget.CreatedAt, &budget.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return budget, nil
+}
+
+func (r *budgetRepository) GetByID(ctx context.Context, id int64) (*Budget, error) {
+	var budget Budget
+	query := `SELECT * FROM budgets WHERE id = $1`
+	err := r.db.GetContext(ctx, &budget, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("budget not found")
+		}
+		return nil, err
+	}
+	return &budget, nil
+}
+
+type UpdateBudgetInput struct {
+	BudgetID   int64
+	UserID     int64
+	CategoryID sql.NullInt64
+	Period     string
+	// Amount is in the minor unit of Currency
+	Amount   int64
+	Currency string
+}
+
+func (r *budgetRepository) Update(ctx context.Context, input UpdateBudgetInput) error {
+	query := `
+		UPDATE budgets
+		SET category_id = $1,
+			period = $2,
+			amount = $3,
+			currency = $4,
+			updated_at = $5
+		WHERE id = $6 AND user_id = $7`
+
+	_, err := r.db.ExecContext(ctx, query,
+		input.CategoryID, input.Period, input.Amount, input.Currency, time.Now(), input.BudgetID, input.UserID,
+	)
+	return err
+}
+
+func (r *budgetRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM budgets WHERE id = $1`, id)
+	return err
+}
+
+// List returns the user's budgets, the overall budget first.
+func (r *budgetRepository) List(ctx context.Context, userID int64) ([]Budget, error) {
+	budgets := []Budget{}
+	query := `
+		SELECT
+			id,
+			user_id,
+			category_id,
+			period,
+			amount,
+			currency,
+			created_at,
+			updated_at
+		FROM budgets
+		WHERE user_id = $1
+		ORDER BY category_id IS NOT NULL, id
+	`
+
+	if err := r.db.SelectContext(ctx, &budgets, query, userID); err != nil {
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
+type ExistBudgetWithUserIDInput struct {
+	UserID   int64 `doc:"User ID"`
+	BudgetID int64 `doc:"Budget ID"`
+}
+
+func (r *budgetRepository) ExistWithUserID(ctx context.Context, input ExistBudgetWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM budgets
+		WHERE id = $1
+		AND user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.BudgetID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -7,8 +7,13 @@ import (
 	"fmt"
 	"strings"
 	"time"
+)
 
-	"github.com/jmoiron/sqlx"
+// Category kinds. Expenses go in expense categories and incomes in income
+// categories.
+const (
+	CategoryKindExpense = "expense"
+	CategoryKindIncome  = "income"
 )
 
 type Category struct {
@@ -16,9 +21,12 @@ type Category struct {
 	UserID      int64          `db:"user_id"`
 	Name        string         `db:"name"`
 	Description sql.NullString `db:"description"`
-	CreatedAt   time.Time      `db:"created_at"`
-	UpdatedAt   time.Time      `db:"updated_at"`
-	DeletedAt   *time.Time     `db:"deleted_at"`
+	Kind        string         `db:"kind"`
+	// ParentID nests the category under another one, NULL at the top level
+	ParentID  sql.NullInt64 `db:"parent_id"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
+	DeletedAt *time.Time    `db:"deleted_at"`
 }
 
 type CategoryRepository interface {
@@ -26,15 +34,21 @@ type CategoryRepository interface {
 	GetByID(ctx context.Context, id int64) (*Category, error)
 	Update(ctx context.Context, category UpdateCategoryInput) error
 	Delete(ctx context.Context, id int64) error
-	List(ctx context.Context, input ListCategoryInput) ([]Category, error)
+	List(ctx context.Context, input ListCategoryInput) (*CategoryPage, error)
 	ExistWithUserID(ctx context.Context, input ExistWithUserIDInput) (bool, error)
+	ListDeleted(ctx context.Context, userID int64) ([]Category, error)
+	ExistDeletedWithUserID(ctx context.Context, input ExistWithUserIDInput) (bool, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	IsDescendant(ctx context.Context, categoryID, ancestorID int64) (bool, error)
+	ReparentChildren(ctx context.Context, categoryID int64, parentID sql.NullInt64) (int64, error)
 }
 
 type categoryRepository struct {
-	db *sqlx.DB
+	db DBTX
 }
 
-func NewCategoryRepository(db *sqlx.DB) CategoryRepository {
+func NewCategoryRepository(db DBTX) CategoryRepository {
 	return &categoryRepository{db: db}
 }
 
@@ -42,27 +56,34 @@ type NewCategoryInput struct {
 	UserID      int64
 	Name        string
 	Description string
+	// Kind defaults to CategoryKindExpense
+	Kind     string
+	ParentID sql.NullInt64
 }
 
 func (r *categoryRepository) Create(ctx context.Context, input NewCategoryInput) (*Category, error) {
 	query := `
-		INSERT INTO categories (user_id, name, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, user_id, name, description, created_at, updated_at, deleted_at`
+		INSERT INTO categories (user_id, name, description, kind, parent_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, user_id, name, description, kind, parent_id, created_at, updated_at, deleted_at`
 
 	now := time.Now()
+	if input.Kind == "" {
+		input.Kind = CategoryKindExpense
+	}
 
 	category := &Category{
 		UserID:      input.UserID,
 		Name:        input.Name,
 		Description: sql.NullString{String: input.Description},
+		ParentID:    input.ParentID,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 
 	err := r.db.QueryRowContext(ctx, query,
-		input.UserID, input.Name, input.Description, now, now,
-	).Scan(&category.ID, &category.UserID, &category.Name, &category.Description, &category.CreatedAt, &category.UpdatedAt, &category.DeletedAt)
+		input.UserID, input.Name, input.Description, input.Kind, input.ParentID, now, now,
+	).Scan(&category.ID, &category.UserID, &category.Name, &category.Description, &category.Kind, &category.ParentID, &category.CreatedAt, &category.UpdatedAt, &category.DeletedAt)
 
 	if err != nil {
 		return nil, err
@@ -89,6 +110,7 @@ type UpdateCategoryInput struct {
 	UserID      int64
 	Name        string
 	Description string
+	ParentID    sql.NullInt64
 }
 
 func (r *categoryRepository) Update(ctx context.Context, updateWith UpdateCategoryInput) error {
@@ -96,12 +118,13 @@ func (r *categoryRepository) Update(ctx context.Context, updateWith UpdateCatego
 		UPDATE categories
 		SET name = $1,
 			description = $2,
-			updated_at = $3
-		WHERE id = $4 AND user_id = $5`
+			parent_id = $3,
+			updated_at = $4
+		WHERE id = $5 AND user_id = $6`
 
 	now := time.Now()
 	_, err := r.db.ExecContext(ctx, query,
-		updateWith.Name, updateWith.Description, now, updateWith.CategoryID, updateWith.UserID,
+		updateWith.Name, updateWith.Description, updateWith.ParentID, now, updateWith.CategoryID, updateWith.UserID,
 	)
 	return err
 }
@@ -122,11 +145,28 @@ type ListCategoryInput struct {
 	Page   int   `json:"page"`
 	Limit  int   `json:"limit"`
 	Search string
+	Cursor string
+	// Kind lists only categories of that kind when set
+	Kind string
+	// All returns every matching category on one page, e.g. to build a tree
+	All bool
 }
 
-func (r *categoryRepository) List(ctx context.Context, input ListCategoryInput) ([]Category, error) {
-	categories := []Category{}
+// CategoryPage is one page of a list. Totals sums the active expenses of
+// every matching category, not just this page, per currency, and
+// IncomeTotals their incomes.
+type CategoryPage struct {
+	Categories   []Category
+	NextCursor   string
+	HasMore      bool
+	TotalCount   int64
+	Totals       []CurrencyTotal
+	IncomeTotals []CurrencyTotal
+}
 
+// List returns categories ordered by name. Pages continue from an opaque
+// cursor keyed on (name, id).
+func (r *categoryRepository) List(ctx context.Context, input ListCategoryInput) (*CategoryPage, error) {
 	defaultLimit := 10
 	if input.Limit == 0 {
 		input.Limit = defaultLimit
@@ -136,56 +176,112 @@ func (r *categoryRepository) List(ctx context.Context, input ListCategoryInput)
 	if input.Page == 0 {
 		input.Page = defaultPage
 	}
-	offset := (input.Page - 1) * input.Limit
 
-	baseQuery := `
-		SELECT
-			id,
-			name,
-			description,
-			created_at,
-			updated_at
-		FROM categories
-		WHERE user_id = $1
-		AND deleted_at IS NULL
-	`
+	after, err := decodeCursor(input.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
 	args := []interface{}{input.UserID}
-	conditions := []string{}
+	conditions := []string{"categories.user_id = $1", "categories.deleted_at IS NULL"}
 
 	if input.Search != "" {
-		condition := fmt.Sprintf(`name LIKE $%d`, len(args)+1)
+		condition := fmt.Sprintf(`categories.name %s $%d`, dialectOf(r.db.DriverName()).like(), len(args)+1)
 		conditions = append(conditions, condition)
 		args = append(args, "%"+input.Search+"%")
 	}
 
-	fullQuery := baseQuery
+	if input.Kind != "" {
+		conditions = append(conditions, fmt.Sprintf("categories.kind = $%d", len(args)+1))
+		args = append(args, input.Kind)
+	}
 
-	if len(conditions) > 0 {
-		fullQuery += " AND " + strings.Join(conditions, " AND ")
+	page := &CategoryPage{Categories: []Category{}}
 
+	countQuery := `SELECT COUNT(*) FROM categories WHERE ` + strings.Join(conditions, " AND ")
+	if err := r.db.GetContext(ctx, &page.TotalCount, countQuery, args...); err != nil {
+		return nil, err
 	}
 
-	limitArgIndex := len(args) + 1
-	offsetArgIndex := len(args) + 2
+	totalsQuery := `
+		SELECT
+			expense_lines.currency,
+			SUM(expense_lines.amount) as amount
+		FROM (` + expenseLines + `
+		) expense_lines
+		JOIN categories ON categories.id = expense_lines.category_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		AND expense_lines.deleted_at IS NULL
+		GROUP BY expense_lines.currency
+		ORDER BY expense_lines.currency`
+
+	if err := r.db.SelectContext(ctx, &page.Totals, totalsQuery, args...); err != nil {
+		return nil, err
+	}
+
+	incomeTotalsQuery := `
+		SELECT
+			incomes.currency,
+			SUM(incomes.amount) as amount
+		FROM incomes
+		JOIN categories ON categories.id = incomes.category_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		AND incomes.deleted_at IS NULL
+		GROUP BY incomes.currency
+		ORDER BY incomes.currency`
+
+	if err := r.db.SelectContext(ctx, &page.IncomeTotals, incomeTotalsQuery, args...); err != nil {
+		return nil, err
+	}
 
-	fullQuery += fmt.Sprintf(`
-			ORDER BY name ASC
-			LIMIT $%d OFFSET $%d
-		`, limitArgIndex, offsetArgIndex)
+	offset := (input.Page - 1) * input.Limit
+	if input.Cursor != "" {
+		offset = 0
+		condition := fmt.Sprintf("(categories.name > $%d OR (categories.name = $%d AND categories.id > $%d))", len(args)+1, len(args)+1, len(args)+2)
+		conditions = append(conditions, condition)
+		args = append(args, after.Key, after.ID)
+	}
 
-	args = append(args, input.Limit, offset)
+	fullQuery := fmt.Sprintf(`
+		SELECT
+			categories.id,
+			categories.name,
+			categories.description,
+			categories.kind,
+			categories.parent_id,
+			categories.created_at,
+			categories.updated_at
+		FROM categories
+		WHERE %s
+		ORDER BY categories.name ASC, categories.id ASC
+	`, strings.Join(conditions, " AND "))
+
+	if !input.All {
+		// Fetch one extra row to learn whether another page follows
+		fullQuery += fmt.Sprintf("LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+		args = append(args, input.Limit+1, offset)
+	}
 
-	err := r.db.SelectContext(ctx, &categories, fullQuery, args...)
-	if err != nil {
+	if err := r.db.SelectContext(ctx, &page.Categories, fullQuery, args...); err != nil {
 		return nil, err
 	}
 
-	return categories, nil
+	if !input.All && len(page.Categories) > input.Limit {
+		page.Categories = page.Categories[:input.Limit]
+		page.HasMore = true
+
+		last := page.Categories[len(page.Categories)-1]
+		page.NextCursor = encodeCursor(cursor{Key: last.Name, ID: last.ID})
+	}
+
+	return page, nil
 }
 
 type ExistWithUserIDInput struct {
 	UserID     int64 `doc:"User ID"`
 	CategoryID int64 `doc:"Category ID"`
+	// Kind also requires the category to be of that kind when set
+	Kind string `doc:"Category kind"`
 }
 
 func (r *categoryRepository) ExistWithUserID(ctx context.Context, input ExistWithUserIDInput) (bool, error) {
@@ -197,6 +293,52 @@ func (r *categoryRepository) ExistWithUserID(ctx context.Context, input ExistWit
 		WHERE id = $1
 		AND user_id = $2
 		AND deleted_at IS NULL
+		AND ($3 = '' OR kind = $3)
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.CategoryID, input.UserID, input.Kind)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *categoryRepository) ListDeleted(ctx context.Context, userID int64) ([]Category, error) {
+	categories := []Category{}
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			description,
+			kind,
+			parent_id,
+			created_at,
+			updated_at,
+			deleted_at
+		FROM categories
+		WHERE user_id = $1
+		AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &categories, query, userID); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (r *categoryRepository) ExistDeletedWithUserID(ctx context.Context, input ExistWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM categories
+		WHERE id = $1
+		AND user_id = $2
+		AND deleted_at IS NOT NULL
 	`
 
 	err := r.db.GetContext(ctx, &count, query, input.CategoryID, input.UserID)
@@ -206,3 +348,110 @@ func (r *categoryRepository) ExistWithUserID(ctx context.Context, input ExistWit
 
 	return count > 0, nil
 }
+
+func (r *categoryRepository) Restore(ctx context.Context, id int64) error {
+	query := `
+		UPDATE categories
+		SET deleted_at = NULL,
+			updated_at = $1
+		WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
+
+// purgeableCategories selects the categories soft-deleted before $1 that no
+// expense, split line or income refers to any more, even one that is itself
+// in the trash.
+const purgeableCategories = `
+	SELECT id FROM categories
+	WHERE deleted_at IS NOT NULL
+	AND deleted_at < $1
+	AND NOT EXISTS (
+		SELECT 1 FROM expenses WHERE expenses.category_id = categories.id
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM expense_splits WHERE expense_splits.category_id = categories.id
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM incomes WHERE incomes.category_id = categories.id
+	)`
+
+// Purge permanently removes categories that were soft-deleted before
+// deletedBefore, along with their budgets and recurring expenses. Categories
+// still referenced by an expense, split line or income are kept until that
+// entry is purged.
+func (r *categoryRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := withTx(ctx, r.db, func(tx DBTX) error {
+		// SQLite does not enforce the cascades, so delete the budgets and
+		// recurring expenses explicitly, detaching the expenses recorded
+		// from them
+		queries := []string{
+			`DELETE FROM budgets WHERE category_id IN (` + purgeableCategories + `)`,
+			`UPDATE expenses SET recurring_expense_id = NULL WHERE recurring_expense_id IN (
+				SELECT id FROM recurring_expenses WHERE category_id IN (` + purgeableCategories + `)
+			)`,
+			`DELETE FROM recurring_expenses WHERE category_id IN (` + purgeableCategories + `)`,
+		}
+		for _, query := range queries {
+			if _, err := tx.ExecContext(ctx, query, deletedBefore); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, `DELETE FROM categories WHERE id IN (`+purgeableCategories+`)`, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		purged, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// IsDescendant reports whether categoryID is ancestorID itself or nested
+// anywhere below it. Making a category the child of one of its descendants
+// would create a cycle.
+func (r *categoryRepository) IsDescendant(ctx context.Context, categoryID, ancestorID int64) (bool, error) {
+	var count int
+	query := `
+		WITH RECURSIVE lineage (id, parent_id) AS (
+			SELECT id, parent_id FROM categories WHERE id = $1
+			UNION
+			SELECT categories.id, categories.parent_id
+			FROM categories
+			INNER JOIN lineage ON categories.id = lineage.parent_id
+		)
+		SELECT COUNT(*) FROM lineage WHERE id = $2
+	`
+
+	if err := r.db.GetContext(ctx, &count, query, categoryID, ancestorID); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// ReparentChildren moves the direct children of categoryID, including ones
+// in the trash, under parentID. Deleting a category uses it to hand its
+// subcategories to the grandparent so no category hangs off a deleted one.
+func (r *categoryRepository) ReparentChildren(ctx context.Context, categoryID int64, parentID sql.NullInt64) (int64, error) {
+	query := `
+		UPDATE categories
+		SET parent_id = $1,
+			updated_at = $2
+		WHERE parent_id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, parentID, time.Now(), categoryID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
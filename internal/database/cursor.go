@@ -0,0 +1,41 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor marks where a page of a list ended. Clients receive it as an opaque
+// token, which lets a list use keyset pagination for its natural order and
+// fall back to an offset where the order is not stable, like ranked search.
+type cursor struct {
+	Key    string `json:"k,omitempty"`
+	ID     int64  `json:"i,omitempty"`
+	Offset int    `json:"o,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(token string) (cursor, error) {
+	var c cursor
+	if token == "" {
+		return c, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(raw, &c); err != nil || c.Offset < 0 {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/joho/godotenv/autoload"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -23,12 +25,47 @@ type Service interface {
 	// It returns an error if the connection cannot be closed.
 	Close() error
 
+	Repositories
+
+	// WithTx runs fn inside a single transaction. The repositories passed to
+	// fn are bound to that transaction, which is committed when fn returns
+	// nil and rolled back when it returns an error or panics.
+	WithTx(ctx context.Context, fn func(tx Repositories) error) error
+}
+
+// Repositories hands out repositories that share one connection, either
+// the pool or an open transaction.
+type Repositories interface {
 	UserRepository() UserRepository
 	CategoryRepository() CategoryRepository
 	ExpenseRepository() ExpenseRepository
+	ExchangeRateRepository() ExchangeRateRepository
+	TagRepository() TagRepository
+	BudgetRepository() BudgetRepository
+	RecurringExpenseRepository() RecurringExpenseRepository
+	IncomeRepository() IncomeRepository
+	WalletRepository() WalletRepository
+	TransferRepository() TransferRepository
+	GroupRepository() GroupRepository
+	AttachmentRepository() AttachmentRepository
+	ImportProfileRepository() ImportProfileRepository
+}
+
+// DBTX is implemented by both *sqlx.DB and *sqlx.Tx, so repositories work
+// the same inside and outside a transaction.
+type DBTX interface {
+	sqlx.ExtContext
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+type repositories struct {
+	db DBTX
 }
 
 type service struct {
+	repositories
 	db *sqlx.DB
 }
 
@@ -43,21 +80,50 @@ func New() Service {
 		return dbInstance
 	}
 
-	db, err := sqlx.Open("sqlite3", dburl)
+	db, err := Open(dburl)
 	if err != nil {
 		// This will not be a connection error, but a DSN parse error or
 		// another initialization error.
 		log.Fatal(err)
 	}
 
-	initializeSchema(db)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
 
-	dbInstance = &service{
-		db: db,
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
 	}
+	for _, migration := range applied {
+		log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+	}
+
+	dbInstance = newService(db)
 	return dbInstance
 }
 
+func newService(db *sqlx.DB) *service {
+	return &service{
+		repositories: repositories{db: db},
+		db:           db,
+	}
+}
+
+// NewService wraps an open connection without migrating it, e.g. to build
+// the routes in tests.
+func NewService(db *sqlx.DB) Service {
+	return newService(db)
+}
+
+// Open connects to the database at dsn without touching its schema.
+// The driver is chosen from the DSN scheme, see parseDSN.
+func Open(dsn string) (*sqlx.DB, error) {
+	driverName, dataSource, _ := parseDSN(dsn)
+	return sqlx.Open(driverName, dataSource)
+}
+
 // Health checks the health of the database connection by pinging the database.
 // It returns a map with keys indicating various health statistics.
 func (s *service) Health() map[string]string {
@@ -114,90 +180,95 @@ func (s *service) Health() map[string]string {
 // If the connection is successfully closed, it returns nil.
 // If an error occurs while closing the connection, it returns the error.
 func (s *service) Close() error {
-	log.Printf("Disconnected from database: %s", dburl)
+	log.Printf("Disconnected from database: %s", describeDSN(dburl))
 	return s.db.Close()
 }
 
-func (s *service) UserRepository() UserRepository {
-	return NewUserRepository(s.db)
+func (s *service) WithTx(ctx context.Context, fn func(tx Repositories) error) error {
+	return withTx(ctx, s.db, func(tx DBTX) error {
+		return fn(&repositories{db: tx})
+	})
 }
 
-func (s *service) CategoryRepository() CategoryRepository {
-	return NewCategoryRepository(s.db)
+// withTx runs fn in a new transaction when db is the connection pool, and
+// directly on db when it already is a transaction, so a repository method
+// that writes several statements never leaves half of them applied.
+func withTx(ctx context.Context, db DBTX, fn func(tx DBTX) error) (err error) {
+	pool, ok := db.(*sqlx.DB)
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := pool.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+
+		err = tx.Commit()
+	}()
+
+	return fn(tx)
 }
 
-func (s *service) ExpenseRepository() ExpenseRepository {
-	return NewExpenseRepository(s.db)
+func (r *repositories) UserRepository() UserRepository {
+	return NewUserRepository(r.db)
 }
 
-func initializeSchema(db *sqlx.DB) {
-	userSchema := `-- Users table stores user account information
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-		email TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('admin', 'user')),
-		email_verified_at DATETIME,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		last_login_at DATETIME
-	);
+func (r *repositories) CategoryRepository() CategoryRepository {
+	return NewCategoryRepository(r.db)
+}
 
-	CREATE INDEX IF NOT EXISTS idx_users_email ON users (email);
-	CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
-	CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at);
-	CREATE INDEX IF NOT EXISTS idx_users_last_login ON users (last_login_at);
-	`
+func (r *repositories) ExpenseRepository() ExpenseRepository {
+	return NewExpenseRepository(r.db)
+}
 
-	_, err := db.Exec(userSchema)
-	if err != nil {
-		log.Fatalf("Failed to initialize users table: %v", err)
-	}
+func (r *repositories) ExchangeRateRepository() ExchangeRateRepository {
+	return NewExchangeRateRepository(r.db)
+}
 
-	categorySchema := `-- Categories table for user-specific expense categories
-	CREATE TABLE IF NOT EXISTS categories (
-		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		name TEXT NOT NULL,
-		description TEXT,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		deleted_at DATETIME,
-		CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		CONSTRAINT unique_user_category UNIQUE (user_id, name)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_categories_user_id ON categories (user_id);
-	CREATE INDEX IF NOT EXISTS idx_categories_name ON categories (name);
-	CREATE INDEX IF NOT EXISTS idx_categories_created_at ON categories (created_at);
-	CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);`
-
-	_, err = db.Exec(categorySchema)
-	if err != nil {
-		log.Fatalf("Failed to initialize categories table: %v", err)
-	}
+func (r *repositories) TagRepository() TagRepository {
+	return NewTagRepository(r.db)
+}
 
-	expenseSchema := `-- Expenses table for user-specific expense records
-	CREATE TABLE IF NOT EXISTS expenses (
-		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		category_id INTEGER NOT NULL,
-		amount INTEGER NOT NULL,
-		description TEXT,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		deleted_at DATETIME,
-		CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		CONSTRAINT fk_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_expenses_user_id ON expenses (user_id);
-	CREATE INDEX IF NOT EXISTS idx_expenses_category_id ON expenses (category_id);
-	CREATE INDEX IF NOT EXISTS idx_expenses_created_at ON expenses (created_at);
-	CREATE INDEX IF NOT EXISTS idx_expenses_deleted_at ON expenses (deleted_at);`
-
-	_, err = db.Exec(expenseSchema)
-	if err != nil {
-		log.Fatalf("Failed to initialize expenses table: %v", err)
-	}
+func (r *repositories) BudgetRepository() BudgetRepository {
+	return NewBudgetRepository(r.db)
+}
+
+func (r *repositories) RecurringExpenseRepository() RecurringExpenseRepository {
+	return NewRecurringExpenseRepository(r.db)
+}
+
+func (r *repositories) IncomeRepository() IncomeRepository {
+	return NewIncomeRepository(r.db)
+}
+
+func (r *repositories) WalletRepository() WalletRepository {
+	return NewWalletRepository(r.db)
+}
+
+func (r *repositories) TransferRepository() TransferRepository {
+	return NewTransferRepository(r.db)
+}
+
+func (r *repositories) GroupRepository() GroupRepository {
+	return NewGroupRepository(r.db)
+}
+
+func (r *repositories) AttachmentRepository() AttachmentRepository {
+	return NewAttachmentRepository(r.db)
+}
+
+func (r *repositories) ImportProfileRepository() ImportProfileRepository {
+	return NewImportProfileRepository(r.db)
 }
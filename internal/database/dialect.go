@@ -0,0 +1,115 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Dialect identifies the SQL flavour spoken by the connected database.
+// Repositories write portable `$n` placeholders and only reach for the
+// dialect where the date functions differ.
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+// parseDSN picks the driver from the DSN scheme. postgres:// and
+// postgresql:// select PostgreSQL; sqlite:// or a plain file path select
+// SQLite, which keeps existing BLUEPRINT_DB_URL values working.
+func parseDSN(dsn string) (driverName string, dataSource string, dialect Dialect) {
+	scheme, rest, found := strings.Cut(dsn, "://")
+	if !found {
+		return "sqlite3", dsn, DialectSQLite
+	}
+
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return "postgres", dsn, DialectPostgres
+	case "sqlite", "sqlite3":
+		return "sqlite3", rest, DialectSQLite
+	default:
+		return "sqlite3", dsn, DialectSQLite
+	}
+}
+
+// describeDSN names the database a DSN points at for logs, leaving out
+// credentials and connection options.
+func describeDSN(dsn string) string {
+	_, dataSource, dialect := parseDSN(dsn)
+	if dialect == DialectPostgres {
+		parsed, err := url.Parse(dataSource)
+		if err != nil {
+			return string(dialect)
+		}
+		return fmt.Sprintf("%s %s%s", dialect, parsed.Host, parsed.Path)
+	}
+
+	path, _, _ := strings.Cut(dataSource, "?")
+	return fmt.Sprintf("%s %s", dialect, path)
+}
+
+// dialectOf maps a sqlx driver name back to its dialect.
+func dialectOf(driverName string) Dialect {
+	if driverName == "postgres" {
+		return DialectPostgres
+	}
+	return DialectSQLite
+}
+
+// like returns the operator the SQLite LIKE behaves as: case-insensitive
+// for ASCII. PostgreSQL's LIKE is case-sensitive, so it uses ILIKE.
+func (d Dialect) like() string {
+	if d == DialectPostgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// periodCondition returns a predicate matching column against the day,
+// month or year of the timestamp bound to placeholder argIndex. A NULL
+// argument falls back to the current date.
+//
+// "today" is a range from the start of the day to the start of the next, so
+// an index on column can be used.
+func (d Dialect) periodCondition(column string, period string, argIndex int) (string, error) {
+	param := fmt.Sprintf("$%d", argIndex)
+
+	if d == DialectPostgres {
+		var unit string
+		switch period {
+		case "today":
+			day := fmt.Sprintf("date_trunc('day', COALESCE(%s::timestamptz, CURRENT_DATE))", param)
+			return fmt.Sprintf("%s >= %s AND %s < %s + INTERVAL '1 day'", column, day, column, day), nil
+		case "month":
+			unit = "month"
+		case "year":
+			unit = "year"
+		default:
+			return "", fmt.Errorf("invalid period %q", period)
+		}
+
+		return fmt.Sprintf("date_trunc('%s', %s) = date_trunc('%s', COALESCE(%s::timestamptz, CURRENT_DATE))", unit, column, unit, param), nil
+	}
+
+	switch period {
+	case "today":
+		return fmt.Sprintf("%s >= DATE(COALESCE(%s, CURRENT_DATE)) AND %s < DATE(COALESCE(%s, CURRENT_DATE), '+1 day')", column, param, column, param), nil
+	case "month":
+		return fmt.Sprintf("STRFTIME('%%Y-%%m', %s) = STRFTIME('%%Y-%%m', COALESCE(%s, CURRENT_DATE))", column, param), nil
+	case "year":
+		return fmt.Sprintf("STRFTIME('%%Y', %s) = STRFTIME('%%Y', COALESCE(%s, CURRENT_DATE))", column, param), nil
+	default:
+		return "", fmt.Errorf("invalid period %q", period)
+	}
+}
+
+// dayOf formats the UTC calendar day of a timestamp column as YYYY-MM-DD.
+func (d Dialect) dayOf(column string) string {
+	if d == DialectPostgres {
+		return fmt.Sprintf("TO_CHAR(%s AT TIME ZONE 'UTC', 'YYYY-MM-DD')", column)
+	}
+	return fmt.Sprintf("DATE(%s)", column)
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// pivotCurrency is the reference currency of the ECB rates. Pairs without a
+// stored rate are converted through it.
+const pivotCurrency = "EUR"
+
+const rateDateLayout = "2006-01-02"
+
+// ExchangeRate prices one unit of BaseCurrency in QuoteCurrency on RateDate.
+type ExchangeRate struct {
+	RateDate      time.Time `db:"rate_date"`
+	BaseCurrency  string    `db:"base_currency"`
+	QuoteCurrency string    `db:"quote_currency"`
+	Rate          float64   `db:"rate"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+type ExchangeRateRepository interface {
+	Upsert(ctx context.Context, rate ExchangeRate) error
+	List(ctx context.Context, on time.Time) ([]ExchangeRate, error)
+	Rate(ctx context.Context, from, to string, on time.Time) (float64, error)
+	Table(ctx context.Context, currencies []string, since, until time.Time) (*RateTable, error)
+}
+
+type exchangeRateRepository struct {
+	db DBTX
+}
+
+func NewExchangeRateRepository(db DBTX) ExchangeRateRepository {
+	return &exchangeRateRepository{db: db}
+}
+
+// Upsert stores a rate, replacing any rate for the same pair and day.
+func (r *exchangeRateRepository) Upsert(ctx context.Context, rate ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (rate_date, base_currency, quote_currency, rate, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (base_currency, quote_currency, rate_date)
+		DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at`
+
+	_, err := r.db.ExecContext(ctx, query,
+		rate.RateDate.Format(rateDateLayout), rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, time.Now(),
+	)
+	return err
+}
+
+// List returns the rate of every pair in effect on the given day, which is
+// the most recent rate dated on or before it.
+func (r *exchangeRateRepository) List(ctx context.Context, on time.Time) ([]ExchangeRate, error) {
+	rates := []ExchangeRate{}
+	query := `
+		SELECT
+			r.rate_date,
+			r.base_currency,
+			r.quote_currency,
+			r.rate,
+			r.updated_at
+		FROM exchange_rates r
+		JOIN (
+			SELECT
+				base_currency,
+				quote_currency,
+				MAX(rate_date) as rate_date
+			FROM exchange_rates
+			WHERE rate_date <= $1
+			GROUP BY base_currency, quote_currency
+		) latest ON latest.base_currency = r.base_currency
+			AND latest.quote_currency = r.quote_currency
+			AND latest.rate_date = r.rate_date
+		ORDER BY r.base_currency ASC, r.quote_currency ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &rates, query, on.Format(rateDateLayout)); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// Rate returns how many units of to buy one unit of from on the given day,
+// falling back to the nearest earlier rate. Pairs are looked up in either
+// direction and, failing that, through EUR. It returns ErrRateNotFound when
+// no rate is dated on or before the day.
+func (r *exchangeRateRepository) Rate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	return crossRate(from, to, func(from, to string) (float64, error) {
+		return r.pairRate(ctx, from, to, on)
+	})
+}
+
+// crossRate converts from to to with pairRate, directly or through EUR.
+func crossRate(from, to string, pairRate func(from, to string) (float64, error)) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	rate, err := pairRate(from, to)
+	if !errors.Is(err, ErrRateNotFound) || from == pivotCurrency || to == pivotCurrency {
+		return rate, err
+	}
+
+	toPivot, err := pairRate(from, pivotCurrency)
+	if err != nil {
+		return 0, err
+	}
+
+	fromPivot, err := pairRate(pivotCurrency, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return toPivot * fromPivot, nil
+}
+
+func (r *exchangeRateRepository) pairRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	query := `
+		SELECT
+			base_currency,
+			rate
+		FROM exchange_rates
+		WHERE ((base_currency = $1 AND quote_currency = $2)
+			OR (base_currency = $2 AND quote_currency = $1))
+		AND rate_date <= $3
+		ORDER BY rate_date DESC, updated_at DESC
+		LIMIT 1
+	`
+
+	var found struct {
+		BaseCurrency string  `db:"base_currency"`
+		Rate         float64 `db:"rate"`
+	}
+	err := r.db.GetContext(ctx, &found, query, from, to, on.Format(rateDateLayout))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrRateNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if found.BaseCurrency == from {
+		return found.Rate, nil
+	}
+	return 1 / found.Rate, nil
+}
+
+// RateTable holds rates read ahead of time, so that converting many amounts
+// takes one query instead of one per amount.
+type RateTable struct {
+	currencies map[string]bool
+	since      string
+	until      string
+	// rates are keyed by the pair's currencies in order, most recent first
+	rates map[[2]string][]ExchangeRate
+}
+
+// Table reads the rates between currencies, and EUR to go through, that are
+// in effect on the days from since to until: those dated in that range, and
+// the latest of each pair before it.
+func (r *exchangeRateRepository) Table(ctx context.Context, currencies []string, since, until time.Time) (*RateTable, error) {
+	table := &RateTable{
+		currencies: map[string]bool{pivotCurrency: true},
+		since:      since.Format(rateDateLayout),
+		until:      until.Format(rateDateLayout),
+		rates:      map[[2]string][]ExchangeRate{},
+	}
+	for _, code := range currencies {
+		table.currencies[code] = true
+	}
+
+	args := []interface{}{table.until, table.since}
+	placeholders := []string{}
+	for code := range table.currencies {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)+1))
+		args = append(args, code)
+	}
+	inCurrencies := strings.Join(placeholders, ",")
+
+	rates := []ExchangeRate{}
+	query := fmt.Sprintf(`
+		SELECT
+			rate_date,
+			base_currency,
+			quote_currency,
+			rate,
+			updated_at
+		FROM exchange_rates
+		WHERE rate_date <= $1
+		AND rate_date >= COALESCE((
+			SELECT MAX(earlier.rate_date)
+			FROM exchange_rates earlier
+			WHERE earlier.base_currency = exchange_rates.base_currency
+			AND earlier.quote_currency = exchange_rates.quote_currency
+			AND earlier.rate_date <= $2
+		), $2)
+		AND base_currency IN (%s)
+		AND quote_currency IN (%s)
+		ORDER BY rate_date DESC, updated_at DESC
+	`, inCurrencies, inCurrencies)
+
+	if err := r.db.SelectContext(ctx, &rates, query, args...); err != nil {
+		return nil, err
+	}
+
+	for _, rate := range rates {
+		key := pairKey(rate.BaseCurrency, rate.QuoteCurrency)
+		table.rates[key] = append(table.rates[key], rate)
+	}
+
+	return table, nil
+}
+
+func pairKey(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// Covers reports whether the table was read for converting from code on
+// the given day.
+func (t *RateTable) Covers(code string, on time.Time) bool {
+	day := on.Format(rateDateLayout)
+	return t.currencies[code] && t.since <= day && day <= t.until
+}
+
+// Rate works like ExchangeRateRepository.Rate with the table's rates.
+func (t *RateTable) Rate(from, to string, on time.Time) (float64, error) {
+	day := on.Format(rateDateLayout)
+	return crossRate(from, to, func(from, to string) (float64, error) {
+		for _, rate := range t.rates[pairKey(from, to)] {
+			if rate.RateDate.Format(rateDateLayout) > day {
+				continue
+			}
+			if rate.BaseCurrency == from {
+				return rate.Rate, nil
+			}
+			return 1 / rate.Rate, nil
+		}
+		return 0, ErrRateNotFound
+	})
+}
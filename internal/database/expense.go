@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/guregu/null/v6"
-	"github.com/jmoiron/sqlx"
 )
 
 type Expense struct {
@@ -17,57 +16,148 @@ type Expense struct {
 	UserID      int64     `db:"user_id"`
 	CategoryID  int64     `db:"category_id"`
 	Amount      int64     `db:"amount"`
+	Currency    string    `db:"currency"`
 	Description string    `db:"description"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
-	DeletedAt   null.Time `db:"deleted_at"`
+	OccurredAt  time.Time `db:"occurred_at"`
+	// WalletID is the wallet the expense was paid from, if any
+	WalletID sql.NullInt64 `db:"wallet_id"`
+	// GroupID is the group the expense is shared with, if any
+	GroupID   sql.NullInt64 `db:"group_id"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
+	DeletedAt null.Time     `db:"deleted_at"`
 }
 
 type ExpenseRepository interface {
 	Create(ctx context.Context, input NewExpenseInput) (*Expense, error)
+	CreateRecurring(ctx context.Context, input NewExpenseInput, recurringExpenseID int64) (bool, error)
 	GetByID(ctx context.Context, id int64) (*RawExpense, error)
 	Update(ctx context.Context, expense UpdateExpenseInput) error
 	Delete(ctx context.Context, id int64) error
-	List(ctx context.Context, input ListExpenseInput) ([]RawExpense, error)
+	List(ctx context.Context, input ListExpenseInput) (*ExpensePage, error)
 	ExistWithUserID(ctx context.Context, input ExistExpenseWithUserIDInput) (bool, error)
-	GetOverviewByCategory(ctx context.Context, userID int64, period string, customDate *time.Time) ([]CategoryExpenseOverview, error)
+	GetOverviewByCategory(ctx context.Context, userID int64, period string, customDate *time.Time, walletID int64) ([]CategoryExpenseOverview, error)
+	GetOverviewByCategoryBetween(ctx context.Context, userID int64, from, to time.Time) ([]CategoryExpenseOverview, error)
+	GetOverviewByTag(ctx context.Context, userID int64, period string, customDate *time.Time, walletID int64) ([]TagExpenseOverview, error)
+	GetDailyTotals(ctx context.Context, userID int64, period string, customDate *time.Time, walletID int64) ([]DailyTotal, error)
+	ListDeleted(ctx context.Context, userID int64) ([]RawExpense, error)
+	ExistDeletedWithUserID(ctx context.Context, input ExistExpenseWithUserIDInput) (bool, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	CountByCategory(ctx context.Context, categoryID int64) (int64, error)
+	ReassignCategory(ctx context.Context, fromCategoryID, toCategoryID int64) (int64, error)
+	DeleteByCategory(ctx context.Context, categoryID int64) (int64, error)
+	RestoreByCategory(ctx context.Context, categoryID int64) error
+	SetSplits(ctx context.Context, expenseID int64, splits []NewExpenseSplitInput) error
+	SetShares(ctx context.Context, expenseID int64, shares []NewExpenseShareInput) error
+	MarkImported(ctx context.Context, input MarkImportedInput) error
+	ListImportedFITIDs(ctx context.Context, userID int64, account string) ([]string, error)
 }
 
 type expenseRepository struct {
-	db *sqlx.DB
+	db DBTX
 }
 
-func NewExpenseRepository(db *sqlx.DB) ExpenseRepository {
+func NewExpenseRepository(db DBTX) ExpenseRepository {
 	return &expenseRepository{db: db}
 }
 
+// ExpenseSplit is a line of an expense split across categories. Amount is
+// in the minor unit of the expense's currency.
+type ExpenseSplit struct {
+	ID           int64  `db:"id"`
+	ExpenseID    int64  `db:"expense_id"`
+	CategoryID   int64  `db:"category_id"`
+	CategoryName string `db:"category_name"`
+	Amount       int64  `db:"amount"`
+	Description  string `db:"description"`
+}
+
+// Ways a shared bill is divided between the users who owe it
+const (
+	ShareModeEqual   = "equal"
+	ShareModeExact   = "exact"
+	ShareModePercent = "percent"
+)
+
+// ExpenseShare is what a user owes of a bill shared in a group. The user who
+// recorded the expense paid it. Amount is in the minor unit of the
+// expense's currency.
+type ExpenseShare struct {
+	ID        int64           `db:"id"`
+	ExpenseID int64           `db:"expense_id"`
+	UserID    int64           `db:"user_id"`
+	Email     string          `db:"email"`
+	Amount    int64           `db:"amount"`
+	Mode      string          `db:"mode"`
+	Percent   sql.NullFloat64 `db:"percent"`
+}
+
+// expenseLines expands every expense into what it is attributed to: its
+// split lines when it has any, otherwise the expense as a whole. Lines taken
+// off with their category are left out. Category totals and overviews read
+// it in place of expenses. first_line is 1 on one line per expense, so
+// summing it counts expenses.
+const expenseLines = `
+			SELECT
+				expenses.id,
+				expenses.user_id,
+				COALESCE(expense_splits.category_id, expenses.category_id) as category_id,
+				COALESCE(expense_splits.amount, expenses.amount) as amount,
+				CASE
+					WHEN expense_splits.id IS NULL THEN 1
+					WHEN expense_splits.id = (SELECT MIN(earliest.id) FROM expense_splits earliest WHERE earliest.expense_id = expenses.id AND earliest.deleted_at IS NULL) THEN 1
+					ELSE 0
+				END as first_line,
+				expenses.currency,
+				expenses.occurred_at,
+				expenses.wallet_id,
+				expenses.group_id,
+				expenses.deleted_at
+			FROM expenses
+			LEFT JOIN expense_splits ON expense_splits.expense_id = expenses.id AND expense_splits.deleted_at IS NULL`
+
 type NewExpenseInput struct {
-	UserID      int64
-	CategoryID  int64
+	UserID     int64
+	CategoryID int64
+	// Amount is in the minor unit of Currency
 	Amount      int64
+	Currency    string
 	Description string
+	// OccurredAt defaults to the time of creation
+	OccurredAt *time.Time
+	WalletID   sql.NullInt64
+	GroupID    sql.NullInt64
 }
 
 func (r *expenseRepository) Create(ctx context.Context, input NewExpenseInput) (*Expense, error) {
 	query := `
-		INSERT INTO expenses (user_id, category_id, amount, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, user_id, category_id, amount, description, created_at, updated_at
+		INSERT INTO expenses (user_id, category_id, amount, currency, description, occurred_at, wallet_id, group_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, user_id, category_id, amount, currency, description, occurred_at, wallet_id, group_id, created_at, updated_at
 	`
 
 	now := time.Now()
+	occurredAt := now
+	if input.OccurredAt != nil {
+		occurredAt = *input.OccurredAt
+	}
 
 	expense := &Expense{
 		UserID:      input.UserID,
 		CategoryID:  input.CategoryID,
 		Amount:      input.Amount,
+		Currency:    input.Currency,
 		Description: input.Description,
+		OccurredAt:  occurredAt,
+		WalletID:    input.WalletID,
+		GroupID:     input.GroupID,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 	err := r.db.QueryRowContext(ctx, query,
-		input.UserID, input.CategoryID, input.Amount, input.Description, now, now,
-	).Scan(&expense.ID, &expense.UserID, &expense.CategoryID, &expense.Amount, &expense.Description, &expense.CreatedAt, &expense.UpdatedAt)
+		input.UserID, input.CategoryID, input.Amount, input.Currency, input.Description, occurredAt.UTC(), input.WalletID, input.GroupID, now, now,
+	).Scan(&expense.ID, &expense.UserID, &expense.CategoryID, &expense.Amount, &expense.Currency, &expense.Description, &expense.OccurredAt, &expense.WalletID, &expense.GroupID, &expense.CreatedAt, &expense.UpdatedAt)
 
 	if err != nil {
 		return nil, err
@@ -76,13 +166,44 @@ func (r *expenseRepository) Create(ctx context.Context, input NewExpenseInput) (
 	return expense, nil
 }
 
+// CreateRecurring inserts the occurrence of a recurring expense at
+// input.OccurredAt. It reports false, without an error, when that occurrence
+// was already generated.
+func (r *expenseRepository) CreateRecurring(ctx context.Context, input NewExpenseInput, recurringExpenseID int64) (bool, error) {
+	query := `
+		INSERT INTO expenses (user_id, category_id, amount, currency, description, occurred_at, wallet_id, recurring_expense_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT DO NOTHING
+	`
+
+	if input.OccurredAt == nil {
+		return false, errors.New("recurring expense occurrence needs an occurred at time")
+	}
+
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query,
+		input.UserID, input.CategoryID, input.Amount, input.Currency, input.Description, input.OccurredAt.UTC(), input.WalletID, recurringExpenseID, now, now,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	return inserted > 0, err
+}
+
 func (r *expenseRepository) GetByID(ctx context.Context, id int64) (*RawExpense, error) {
 	var expense RawExpense
 	query := `
 		SELECT
 			expenses.id,
 			expenses.amount,
+			expenses.currency,
 			expenses.description,
+			expenses.occurred_at,
+			expenses.wallet_id,
+			expenses.user_id,
+			expenses.group_id,
 			expenses.created_at,
 			expenses.updated_at,
 			expenses.category_id,
@@ -102,15 +223,26 @@ func (r *expenseRepository) GetByID(ctx context.Context, id int64) (*RawExpense,
 		}
 		return nil, err
 	}
-	return &expense, nil
+
+	expenses := []RawExpense{expense}
+	if err := r.loadDetails(ctx, expenses); err != nil {
+		return nil, err
+	}
+	return &expenses[0], nil
 }
 
 type UpdateExpenseInput struct {
-	ExpenseID   int64
-	CategoryID  int64
-	UserID      int64
+	ExpenseID  int64
+	CategoryID int64
+	UserID     int64
+	// Amount is in the minor unit of Currency
 	Amount      int64
+	Currency    string
 	Description string
+	// OccurredAt is left unchanged when nil
+	OccurredAt *time.Time
+	WalletID   sql.NullInt64
+	GroupID    sql.NullInt64
 }
 
 func (r *expenseRepository) Update(ctx context.Context, updateWith UpdateExpenseInput) error {
@@ -119,8 +251,12 @@ func (r *expenseRepository) Update(ctx context.Context, updateWith UpdateExpense
 		SET amount = $1,
 			description = $2,
 			updated_at = $3,
-			category_id = $4
-		WHERE id = $5 AND user_id = $6`
+			category_id = $4,
+			occurred_at = COALESCE($5, occurred_at),
+			currency = $6,
+			wallet_id = $7,
+			group_id = $8
+		WHERE id = $9 AND user_id = $10`
 
 	now := time.Now()
 	description := ""
@@ -128,8 +264,14 @@ func (r *expenseRepository) Update(ctx context.Context, updateWith UpdateExpense
 		description = updateWith.Description
 	}
 
+	var occurredAt *time.Time
+	if updateWith.OccurredAt != nil {
+		utc := updateWith.OccurredAt.UTC()
+		occurredAt = &utc
+	}
+
 	_, err := r.db.ExecContext(ctx, query,
-		updateWith.Amount, description, now, updateWith.CategoryID, updateWith.ExpenseID, updateWith.UserID,
+		updateWith.Amount, description, now, updateWith.CategoryID, occurredAt, updateWith.Currency, updateWith.WalletID, updateWith.GroupID, updateWith.ExpenseID, updateWith.UserID,
 	)
 	return err
 }
@@ -146,30 +288,82 @@ func (r *expenseRepository) Delete(ctx context.Context, id int64) error {
 }
 
 type ListExpenseInput struct {
-	UserID   int64      `json:"user_id" doc:"User ID"`
-	Page     int        `json:"page"`
-	Limit    int        `json:"limit"`
-	Date     *time.Time `json:"date"`
+	UserID int64      `json:"user_id" doc:"User ID"`
+	Page   int        `json:"page"`
+	Limit  int        `json:"limit"`
+	Date   *time.Time `json:"date"`
+	// From and To list expenses that occurred between the two days,
+	// including both. Either may be nil for an open range.
+	From     *time.Time `json:"from"`
+	To       *time.Time `json:"to"`
 	Category []int64    `json:"category"`
+	Search   string     `json:"search"`
+	Cursor   string     `json:"cursor"`
+	// Tags holds normalized tag names. Expenses match when they carry any
+	// of them, or all of them when MatchAllTags is set.
+	Tags         []string `json:"tags"`
+	MatchAllTags bool     `json:"match_all_tags"`
+	// WalletID lists only expenses paid from that wallet when set
+	WalletID int64 `json:"wallet_id"`
+	// GroupID lists the expenses shared with that group, by any member,
+	// instead of the user's own
+	GroupID int64 `json:"group_id"`
+	// OldestFirst lists expenses in the order they occurred instead of the
+	// most recent first
+	OldestFirst bool `json:"oldest_first"`
+}
+
+// ExpensePage is one page of a list. The totals cover every expense matching
+// the filters, not just this page, with one sum per currency.
+type ExpensePage struct {
+	Expenses   []RawExpense
+	NextCursor string
+	HasMore    bool
+	TotalCount int64
+	Totals     []CurrencyTotal
+}
+
+// CurrencyTotal sums amounts of one currency, in its minor unit.
+type CurrencyTotal struct {
+	Currency string `db:"currency"`
+	Amount   int64  `db:"amount"`
 }
 
 type RawExpense struct {
 	ID          int64          `db:"id"`
 	Amount      int64          `db:"amount"`
+	Currency    string         `db:"currency"`
 	Description sql.NullString `db:"description"`
+	OccurredAt  time.Time      `db:"occurred_at"`
+	WalletID    sql.NullInt64  `db:"wallet_id"`
+	UserID      int64          `db:"user_id"`
+	GroupID     sql.NullInt64  `db:"group_id"`
 	CreatedAt   time.Time      `db:"created_at"`
 	UpdatedAt   time.Time      `db:"updated_at"`
+	DeletedAt   null.Time      `db:"deleted_at"`
 
 	CategoryID          int64     `db:"category_id"`
 	CategoryName        string    `db:"category_name"`
 	CategoryDescription string    `db:"category_description"`
 	CategoryCreatedAt   time.Time `db:"category_created_at"`
 	CategoryUpdatedAt   time.Time `db:"category_updated_at"`
-}
 
-func (r *expenseRepository) List(ctx context.Context, input ListExpenseInput) ([]RawExpense, error) {
-	expenses := []RawExpense{}
+	// Tags holds the names of the expense's tags in alphabetical order
+	Tags []string `db:"-"`
+	// Splits holds the lines of a split expense, empty when it is not split
+	Splits []ExpenseSplit `db:"-"`
+	// Shares holds who owes what of a shared bill, empty for other expenses
+	Shares []ExpenseShare `db:"-"`
+
+	// Snippet is the description with search matches highlighted. It is only
+	// set when listing with a search term.
+	Snippet sql.NullString `db:"snippet"`
+}
 
+// List returns expenses by when they occurred, most recent first. Pages
+// continue from an opaque cursor keyed on (occurred_at, id) so rows inserted
+// meanwhile neither repeat nor shift the next page. Searches are ordered by relevance and page by offset.
+func (r *expenseRepository) List(ctx context.Context, input ListExpenseInput) (*ExpensePage, error) {
 	defaultLimit := 10
 	if input.Limit == 0 {
 		input.Limit = defaultLimit
@@ -179,72 +373,221 @@ func (r *expenseRepository) List(ctx context.Context, input ListExpenseInput) ([
 	if input.Page == 0 {
 		input.Page = defaultPage
 	}
-	offset := (input.Page - 1) * input.Limit
 
-	baseQuery := `
-		SELECT
-			expenses.id,
-			expenses.amount,
-			expenses.description,
-			expenses.created_at,
-			expenses.updated_at,
-			expenses.category_id,
-			categories.name as category_name,
-			categories.description as category_description,
-			categories.created_at as category_created_at,
-			categories.updated_at as category_updated_at
-		FROM expenses
-		JOIN categories ON categories.id = expenses.category_id
-		WHERE expenses.deleted_at IS NULL
-		AND expenses.user_id = $1		
-	`
+	after, err := decodeCursor(input.Cursor)
+	if err != nil {
+		return nil, err
+	}
 
 	args := []interface{}{input.UserID}
-	conditions := []string{}
+	conditions := []string{"expenses.deleted_at IS NULL", "expenses.user_id = $1"}
+	tagsCondition := "AND tags.user_id = $1"
+	if input.GroupID != 0 {
+		args[0] = input.GroupID
+		conditions[1] = "expenses.group_id = $1"
+		tagsCondition = ""
+	}
+
+	var search expenseSearch
+	searching := false
+	amountColumn := "matching.amount"
+	snippetColumn := ""
+	orderBy := "expenses.occurred_at DESC, expenses.id DESC"
+	keysetOperator := "<"
+	if input.OldestFirst {
+		orderBy = "expenses.occurred_at ASC, expenses.id ASC"
+		keysetOperator = ">"
+	}
+
+	if terms := searchTerms(input.Search); len(terms) > 0 {
+		dialect := dialectOf(r.db.DriverName())
+		search = dialect.expenseSearch(len(args) + 1)
+		conditions = append(conditions, search.condition)
+		args = append(args, dialect.fullTextQuery(terms))
+
+		searching = true
+		snippetColumn = ",\n\t\t\t" + search.snippet + " as snippet"
+		orderBy = search.rank + " ASC, " + orderBy
+	}
 
 	if input.Date != nil {
 		startOfDay := input.Date.In(time.UTC).Truncate((24 * time.Hour))
 		endOfDay := startOfDay.Add(24 * time.Hour).Add(-time.Millisecond)
 
-		condition := fmt.Sprintf("expenses.created_at BETWEEN $%d AND $%d", len(args)+1, len(args)+2)
+		condition := fmt.Sprintf("expenses.occurred_at BETWEEN $%d AND $%d", len(args)+1, len(args)+2)
 		conditions = append(conditions, condition)
 		args = append(args, startOfDay, endOfDay)
 	}
 
+	if input.From != nil {
+		conditions = append(conditions, fmt.Sprintf("expenses.occurred_at >= $%d", len(args)+1))
+		args = append(args, input.From.In(time.UTC).Truncate(24*time.Hour))
+	}
+
+	if input.To != nil {
+		conditions = append(conditions, fmt.Sprintf("expenses.occurred_at < $%d", len(args)+1))
+		args = append(args, input.To.In(time.UTC).Truncate(24*time.Hour).Add(24*time.Hour))
+	}
+
 	if len(input.Category) > 0 {
 		placeholders := make([]string, len(input.Category))
 		for i := range input.Category {
 			placeholders[i] = fmt.Sprintf("$%d", len(args)+1+i)
 		}
-		condition := fmt.Sprintf("expenses.category_id IN (%s)", strings.Join(placeholders, ","))
+		// A split expense matches when any of its lines is in a category
+		inCategories := strings.Join(placeholders, ",")
+		condition := fmt.Sprintf(`(expenses.category_id IN (%s) OR expenses.id IN (
+			SELECT expense_splits.expense_id FROM expense_splits WHERE expense_splits.category_id IN (%s) AND expense_splits.deleted_at IS NULL
+		))`, inCategories, inCategories)
 		conditions = append(conditions, condition)
 
+		// and only those lines count towards the total
+		amountColumn = fmt.Sprintf(`CASE
+				WHEN EXISTS (SELECT 1 FROM expense_splits WHERE expense_splits.expense_id = matching.id AND expense_splits.deleted_at IS NULL)
+				THEN (SELECT SUM(expense_splits.amount) FROM expense_splits WHERE expense_splits.expense_id = matching.id AND expense_splits.category_id IN (%s) AND expense_splits.deleted_at IS NULL)
+				ELSE matching.amount
+			END`, inCategories)
+
 		for _, catID := range input.Category {
 			args = append(args, catID)
 		}
 	}
 
-	fullQuery := baseQuery
+	if input.WalletID != 0 {
+		conditions = append(conditions, fmt.Sprintf("expenses.wallet_id = $%d", len(args)+1))
+		args = append(args, input.WalletID)
+	}
+
+	if len(input.Tags) > 0 {
+		placeholders := make([]string, len(input.Tags))
+		for i, tag := range input.Tags {
+			placeholders[i] = fmt.Sprintf("$%d", len(args)+1)
+			args = append(args, tag)
+		}
+
+		having := ""
+		if input.MatchAllTags {
+			having = fmt.Sprintf("HAVING COUNT(DISTINCT tags.id) = %d", len(input.Tags))
+		}
+
+		condition := fmt.Sprintf(`expenses.id IN (
+			SELECT expense_tags.expense_id
+			FROM expense_tags
+			JOIN tags ON tags.id = expense_tags.tag_id
+			WHERE tags.name IN (%s)
+			%s
+			GROUP BY expense_tags.expense_id
+			%s
+		)`, strings.Join(placeholders, ","), tagsCondition, having)
+		conditions = append(conditions, condition)
+	}
+
+	fromQuery := `
+		FROM expenses
+		JOIN categories ON categories.id = expenses.category_id
+		` + search.join
+
+	page := &ExpensePage{Expenses: []RawExpense{}}
+
+	// SQLite numbers placeholders by first appearance, so the filters come
+	// before the amount column that repeats the category ones
+	totalsQuery := `
+		WITH matching AS (
+			SELECT
+				expenses.id,
+				expenses.amount,
+				expenses.currency
+		` + fromQuery + `
+			WHERE ` + strings.Join(conditions, " AND ") + `
+		)
+		SELECT
+			matching.currency,
+			COUNT(*) as count,
+			COALESCE(SUM(` + amountColumn + `), 0) as amount
+		FROM matching
+		GROUP BY matching.currency
+		ORDER BY matching.currency`
+
+	var totals []struct {
+		CurrencyTotal
+		Count int64 `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &totals, totalsQuery, args...); err != nil {
+		return nil, err
+	}
+	page.Totals = make([]CurrencyTotal, len(totals))
+	for i, total := range totals {
+		page.Totals[i] = total.CurrencyTotal
+		page.TotalCount += total.Count
+	}
+
+	offset := 0
+	switch {
+	case searching:
+		offset = after.Offset
+	case after.Key != "":
+		occurredAt, err := time.Parse(time.RFC3339Nano, after.Key)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
 
-	if len(conditions) > 0 {
-		fullQuery += " AND " + strings.Join(conditions, " AND ")
+		condition := fmt.Sprintf("(expenses.occurred_at %[1]s $%[2]d OR (expenses.occurred_at = $%[2]d AND expenses.id %[1]s $%[3]d))", keysetOperator, len(args)+1, len(args)+2)
+		conditions = append(conditions, condition)
+		args = append(args, occurredAt.UTC(), after.ID)
+	default:
+		offset = (input.Page - 1) * input.Limit
 	}
 
 	limitArgIndex := len(args) + 1
 	offsetArgIndex := len(args) + 2
 
-	fullQuery += fmt.Sprintf(`
-			ORDER BY expenses.created_at DESC
-			LIMIT $%d OFFSET $%d
-		`, limitArgIndex, offsetArgIndex)
+	fullQuery := fmt.Sprintf(`
+		SELECT
+			expenses.id,
+			expenses.amount,
+			expenses.currency,
+			expenses.description,
+			expenses.occurred_at,
+			expenses.wallet_id,
+			expenses.user_id,
+			expenses.group_id,
+			expenses.created_at,
+			expenses.updated_at,
+			expenses.category_id,
+			categories.name as category_name,
+			categories.description as category_description,
+			categories.created_at as category_created_at,
+			categories.updated_at as category_updated_at%s
+		%s
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, snippetColumn, fromQuery, strings.Join(conditions, " AND "), orderBy, limitArgIndex, offsetArgIndex)
 
-	args = append(args, input.Limit, offset)
+	// Fetch one extra row to learn whether another page follows
+	args = append(args, input.Limit+1, offset)
 
-	if err := r.db.SelectContext(ctx, &expenses, fullQuery, args...); err != nil {
+	if err := r.db.SelectContext(ctx, &page.Expenses, fullQuery, args...); err != nil {
 		return nil, err
 	}
 
-	return expenses, nil
+	if err := r.loadDetails(ctx, page.Expenses); err != nil {
+		return nil, err
+	}
+
+	if len(page.Expenses) > input.Limit {
+		page.Expenses = page.Expenses[:input.Limit]
+		page.HasMore = true
+
+		last := page.Expenses[len(page.Expenses)-1]
+		if searching {
+			page.NextCursor = encodeCursor(cursor{Offset: offset + input.Limit})
+		} else {
+			page.NextCursor = encodeCursor(cursor{Key: last.OccurredAt.Format(time.RFC3339Nano), ID: last.ID})
+		}
+	}
+
+	return page, nil
 }
 
 type ExistExpenseWithUserIDInput struct {
@@ -271,85 +614,685 @@ func (r *expenseRepository) ExistWithUserID(ctx context.Context, input ExistExpe
 	return count > 0, nil
 }
 
+// CategoryExpenseOverview totals a category's expenses in one currency on
+// one day, so each row can be converted at that day's exchange rate. RootID
+// and RootName name the top-level category it is nested under, or the
+// category itself when it has no parent.
 type CategoryExpenseOverview struct {
-	CategoryID   int64  `db:"category_id"`
-	CategoryName string `db:"category_name"`
-	TotalAmount  int64  `db:"total_amount"`
-	Count        int64  `db:"count"`
-}
-
-func (r *expenseRepository) GetOverviewByCategory(ctx context.Context, userID int64, period string, customDate *time.Time) ([]CategoryExpenseOverview, error) {
-	var query string
-	var args []interface{}
-
-	switch period {
-	case "today":
-		nextDay := customDate.AddDate(0, 0, 1)
-
-		query = `
-			SELECT 
-				c.id as category_id,
-				c.name as category_name,
-				SUM(e.amount) as total_amount,
-				COUNT(e.id) as count
-			FROM categories c
-			INNER JOIN expenses e ON c.id = e.category_id 
-				AND e.user_id = $1 
-				AND e.deleted_at IS NULL
-				AND e.created_at >= DATE($2)
-				AND DATE(e.created_at) <= DATE($3)
-			WHERE c.user_id = $1 
-				AND c.deleted_at IS NULL
-			GROUP BY c.id, c.name
-			ORDER BY total_amount DESC
-		`
-		args = []interface{}{userID, customDate, nextDay}
-	case "month":
-		query = `
-			SELECT 
-				c.id as category_id,
-				c.name as category_name,
-				SUM(e.amount) as total_amount,
-				COUNT(e.id) as count
-			FROM categories c
-			INNER JOIN expenses e ON c.id = e.category_id 
-				AND e.user_id = $1 
-				AND e.deleted_at IS NULL
-				AND STRFTIME('%Y-%m', e.created_at) = STRFTIME('%Y-%m', COALESCE($2, CURRENT_DATE))
-			WHERE c.user_id = $1 
-				AND c.deleted_at IS NULL
-			GROUP BY c.id, c.name
-			ORDER BY total_amount DESC
-		`
-		args = []interface{}{userID, customDate}
-	case "year":
-		query = `
-			SELECT 
-				c.id as category_id,
-				c.name as category_name,
-				SUM(e.amount) as total_amount,
-				COUNT(e.id) as count
-			FROM categories c
-			INNER JOIN expenses e ON c.id = e.category_id 
-				AND e.user_id = $1 
-				AND e.deleted_at IS NULL
-				AND STRFTIME('%Y', e.created_at) = STRFTIME('%Y', COALESCE($2, CURRENT_DATE))
-			WHERE c.user_id = $1 
-				AND c.deleted_at IS NULL
-			GROUP BY c.id, c.name
-			ORDER BY total_amount DESC
-		`
-		args = []interface{}{userID, customDate}
-	default:
+	CategoryID   int64         `db:"category_id"`
+	CategoryName string        `db:"category_name"`
+	ParentID     sql.NullInt64 `db:"parent_id"`
+	RootID       int64         `db:"root_id"`
+	RootName     string        `db:"root_name"`
+	Currency     string        `db:"currency"`
+	Day          string        `db:"day"`
+	TotalAmount  int64         `db:"total_amount"`
+	// Count is the number of expenses with a line in the category, while
+	// ExpenseCount counts a split expense on one of its rows only, so it
+	// adds up across rows
+	Count        int64 `db:"count"`
+	ExpenseCount int64 `db:"expense_count"`
+}
+
+// GetOverviewByCategory groups the period's expenses by category, currency
+// and day. A walletID other than 0 only counts expenses paid from it.
+func (r *expenseRepository) GetOverviewByCategory(ctx context.Context, userID int64, period string, customDate *time.Time, walletID int64) ([]CategoryExpenseOverview, error) {
+	dialect := dialectOf(r.db.DriverName())
+	periodCondition, err := dialect.periodCondition("e.occurred_at", period, 2)
+	if err != nil {
 		return nil, errors.New("invalid period. Must be 'today', 'month', or 'year'")
 	}
 
+	condition, args := withWallet(periodCondition, "e.wallet_id", walletID, userID, customDate)
+	return r.categoryOverview(ctx, condition, args...)
+}
+
+// GetOverviewByCategoryBetween is GetOverviewByCategory for expenses that
+// occurred in [from, to), e.g. a budget's week.
+func (r *expenseRepository) GetOverviewByCategoryBetween(ctx context.Context, userID int64, from, to time.Time) ([]CategoryExpenseOverview, error) {
+	return r.categoryOverview(ctx, "e.occurred_at >= $2 AND e.occurred_at < $3", userID, from.UTC(), to.UTC())
+}
+
+// categoryOverview groups the user's expenses matching condition by
+// category, currency and day, attributing split expenses line by line.
+// Expenses kept under a deleted category still count towards it. The user
+// ID is bound to $1.
+func (r *expenseRepository) categoryOverview(ctx context.Context, condition string, args ...interface{}) ([]CategoryExpenseOverview, error) {
+	dialect := dialectOf(r.db.DriverName())
+	query := fmt.Sprintf(`
+		WITH RECURSIVE category_roots (id, root_id, root_name) AS (
+			SELECT id, id, name FROM categories WHERE user_id = $1 AND parent_id IS NULL
+			UNION ALL
+			SELECT categories.id, category_roots.root_id, category_roots.root_name
+			FROM categories
+			INNER JOIN category_roots ON categories.parent_id = category_roots.id
+		)
+		SELECT 
+			c.id as category_id,
+			c.name as category_name,
+			c.parent_id,
+			COALESCE(r.root_id, c.id) as root_id,
+			COALESCE(r.root_name, c.name) as root_name,
+			e.currency,
+			%s as day,
+			SUM(e.amount) as total_amount,
+			COUNT(DISTINCT e.id) as count,
+			SUM(e.first_line) as expense_count
+		FROM categories c
+		INNER JOIN (%s
+		) e ON c.id = e.category_id
+			AND e.user_id = $1
+			AND e.deleted_at IS NULL
+			AND %s
+		LEFT JOIN category_roots r ON r.id = c.id
+		WHERE c.user_id = $1
+		GROUP BY c.id, c.name, c.parent_id, r.root_id, r.root_name, e.currency, day
+		ORDER BY total_amount DESC
+	`, dialect.dayOf("e.occurred_at"), expenseLines, condition)
+
 	var overviews []CategoryExpenseOverview
 	err := r.db.SelectContext(ctx, &overviews, query, args...)
 	if err != nil {
-		fmt.Println("Errir getting overview by category: %w", err)
 		return nil, fmt.Errorf("Failed to get overview by category: %w", err)
 	}
 
 	return overviews, nil
 }
+
+// TagExpenseOverview totals a tag's expenses in one currency on one day. An
+// expense with several tags counts towards each of them.
+type TagExpenseOverview struct {
+	TagID       int64  `db:"tag_id"`
+	TagName     string `db:"tag_name"`
+	Currency    string `db:"currency"`
+	Day         string `db:"day"`
+	TotalAmount int64  `db:"total_amount"`
+	Count       int64  `db:"count"`
+}
+
+func (r *expenseRepository) GetOverviewByTag(ctx context.Context, userID int64, period string, customDate *time.Time, walletID int64) ([]TagExpenseOverview, error) {
+	dialect := dialectOf(r.db.DriverName())
+	periodCondition, err := dialect.periodCondition("e.occurred_at", period, 2)
+	if err != nil {
+		return nil, errors.New("invalid period. Must be 'today', 'month', or 'year'")
+	}
+	periodCondition, args := withWallet(periodCondition, "e.wallet_id", walletID, userID, customDate)
+
+	query := fmt.Sprintf(`
+		SELECT
+			t.id as tag_id,
+			t.name as tag_name,
+			e.currency,
+			%s as day,
+			SUM(e.amount) as total_amount,
+			COUNT(e.id) as count
+		FROM tags t
+		INNER JOIN expense_tags et ON et.tag_id = t.id
+		INNER JOIN expenses e ON e.id = et.expense_id
+			AND e.user_id = $1
+			AND e.deleted_at IS NULL
+			AND %s
+		WHERE t.user_id = $1
+		GROUP BY t.id, t.name, e.currency, day
+		ORDER BY total_amount DESC
+	`, dialect.dayOf("e.occurred_at"), periodCondition)
+
+	overviews := []TagExpenseOverview{}
+	if err := r.db.SelectContext(ctx, &overviews, query, args...); err != nil {
+		return nil, fmt.Errorf("Failed to get overview by tag: %w", err)
+	}
+
+	return overviews, nil
+}
+
+// GetDailyTotals totals the user's active expenses in the period by
+// currency and day, counting the same expenses as GetOverviewByCategory.
+func (r *expenseRepository) GetDailyTotals(ctx context.Context, userID int64, period string, customDate *time.Time, walletID int64) ([]DailyTotal, error) {
+	return dailyTotals(ctx, r.db, "expenses", userID, period, customDate, walletID)
+}
+
+// loadDetails fills in the tags and split lines of each expense.
+func (r *expenseRepository) loadDetails(ctx context.Context, expenses []RawExpense) error {
+	if err := r.loadTags(ctx, expenses); err != nil {
+		return err
+	}
+	if err := r.loadSplits(ctx, expenses); err != nil {
+		return err
+	}
+	return r.loadShares(ctx, expenses)
+}
+
+// loadSplits fills in the split lines of each expense with a single query.
+func (r *expenseRepository) loadSplits(ctx context.Context, expenses []RawExpense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(expenses))
+	placeholders := make([]string, len(expenses))
+	indexByID := make(map[int64]int, len(expenses))
+	for i, expense := range expenses {
+		args[i] = expense.ID
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		indexByID[expense.ID] = i
+		expenses[i].Splits = []ExpenseSplit{}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			expense_splits.id,
+			expense_splits.expense_id,
+			expense_splits.category_id,
+			categories.name as category_name,
+			expense_splits.amount,
+			expense_splits.description
+		FROM expense_splits
+		JOIN categories ON categories.id = expense_splits.category_id
+		WHERE expense_splits.expense_id IN (%s)
+		AND expense_splits.deleted_at IS NULL
+		ORDER BY expense_splits.id ASC
+	`, strings.Join(placeholders, ","))
+
+	var splits []ExpenseSplit
+	if err := r.db.SelectContext(ctx, &splits, query, args...); err != nil {
+		return err
+	}
+
+	for _, split := range splits {
+		index := indexByID[split.ExpenseID]
+		expenses[index].Splits = append(expenses[index].Splits, split)
+	}
+
+	return nil
+}
+
+// loadShares fills in the shares of each shared bill with a single query.
+func (r *expenseRepository) loadShares(ctx context.Context, expenses []RawExpense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(expenses))
+	placeholders := make([]string, len(expenses))
+	indexByID := make(map[int64]int, len(expenses))
+	for i, expense := range expenses {
+		args[i] = expense.ID
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		indexByID[expense.ID] = i
+		expenses[i].Shares = []ExpenseShare{}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			expense_shares.id,
+			expense_shares.expense_id,
+			expense_shares.user_id,
+			users.email,
+			expense_shares.amount,
+			expense_shares.mode,
+			expense_shares.percent
+		FROM expense_shares
+		JOIN users ON users.id = expense_shares.user_id
+		WHERE expense_shares.expense_id IN (%s)
+		ORDER BY expense_shares.id ASC
+	`, strings.Join(placeholders, ","))
+
+	var shares []ExpenseShare
+	if err := r.db.SelectContext(ctx, &shares, query, args...); err != nil {
+		return err
+	}
+
+	for _, share := range shares {
+		index := indexByID[share.ExpenseID]
+		expenses[index].Shares = append(expenses[index].Shares, share)
+	}
+
+	return nil
+}
+
+type NewExpenseShareInput struct {
+	UserID int64
+	// Amount is in the minor unit of the expense's currency
+	Amount int64
+	Mode   string
+	// Percent is set for shares given as a percentage
+	Percent sql.NullFloat64
+}
+
+// SetShares replaces the shares of a bill. No shares leave the expense
+// paid by and for the user who recorded it.
+func (r *expenseRepository) SetShares(ctx context.Context, expenseID int64, shares []NewExpenseShareInput) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM expense_shares WHERE expense_id = $1`, expenseID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO expense_shares (expense_id, user_id, amount, mode, percent)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	for _, share := range shares {
+		if _, err := r.db.ExecContext(ctx, query, expenseID, share.UserID, share.Amount, share.Mode, share.Percent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type MarkImportedInput struct {
+	ExpenseID int64
+	UserID    int64
+	// Account identifies the statement's account, see
+	// statement.OFXStatement
+	Account string
+	FITID   string
+}
+
+// MarkImported records the statement transaction an expense was imported
+// from.
+func (r *expenseRepository) MarkImported(ctx context.Context, input MarkImportedInput) error {
+	query := `
+		INSERT INTO imported_transactions (expense_id, user_id, account, fitid, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, input.ExpenseID, input.UserID, input.Account, input.FITID, time.Now())
+	return err
+}
+
+// ListImportedFITIDs returns the FITIDs of the account's transactions that
+// were imported as expenses, trashed ones included.
+func (r *expenseRepository) ListImportedFITIDs(ctx context.Context, userID int64, account string) ([]string, error) {
+	fitids := []string{}
+	query := `
+		SELECT fitid
+		FROM imported_transactions
+		WHERE user_id = $1
+		AND account = $2
+	`
+
+	if err := r.db.SelectContext(ctx, &fitids, query, userID, account); err != nil {
+		return nil, err
+	}
+
+	return fitids, nil
+}
+
+type NewExpenseSplitInput struct {
+	CategoryID int64
+	// Amount is in the minor unit of the expense's currency
+	Amount      int64
+	Description string
+}
+
+// SetSplits replaces the split lines of an expense. No lines leave the
+// expense attributed to its own category.
+func (r *expenseRepository) SetSplits(ctx context.Context, expenseID int64, splits []NewExpenseSplitInput) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM expense_splits WHERE expense_id = $1`, expenseID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO expense_splits (expense_id, category_id, amount, description)
+		VALUES ($1, $2, $3, $4)`
+
+	for _, split := range splits {
+		if _, err := r.db.ExecContext(ctx, query, expenseID, split.CategoryID, split.Amount, split.Description); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadTags fills in the tags of each expense with a single query.
+func (r *expenseRepository) loadTags(ctx context.Context, expenses []RawExpense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(expenses))
+	placeholders := make([]string, len(expenses))
+	indexByID := make(map[int64]int, len(expenses))
+	for i, expense := range expenses {
+		args[i] = expense.ID
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		indexByID[expense.ID] = i
+		expenses[i].Tags = []string{}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			expense_tags.expense_id,
+			tags.name
+		FROM expense_tags
+		JOIN tags ON tags.id = expense_tags.tag_id
+		WHERE expense_tags.expense_id IN (%s)
+		ORDER BY tags.name ASC
+	`, strings.Join(placeholders, ","))
+
+	var rows []struct {
+		ExpenseID int64  `db:"expense_id"`
+		Name      string `db:"name"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		index := indexByID[row.ExpenseID]
+		expenses[index].Tags = append(expenses[index].Tags, row.Name)
+	}
+
+	return nil
+}
+
+func (r *expenseRepository) ListDeleted(ctx context.Context, userID int64) ([]RawExpense, error) {
+	expenses := []RawExpense{}
+	query := `
+		SELECT
+			expenses.id,
+			expenses.amount,
+			expenses.currency,
+			expenses.description,
+			expenses.occurred_at,
+			expenses.wallet_id,
+			expenses.user_id,
+			expenses.group_id,
+			expenses.created_at,
+			expenses.updated_at,
+			expenses.deleted_at,
+			expenses.category_id,
+			categories.name as category_name,
+			categories.description as category_description,
+			categories.created_at as category_created_at,
+			categories.updated_at as category_updated_at
+		FROM expenses
+		JOIN categories ON categories.id = expenses.category_id
+		WHERE expenses.user_id = $1
+		AND expenses.deleted_at IS NOT NULL
+		ORDER BY expenses.deleted_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &expenses, query, userID); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadDetails(ctx, expenses); err != nil {
+		return nil, err
+	}
+
+	return expenses, nil
+}
+
+func (r *expenseRepository) ExistDeletedWithUserID(ctx context.Context, input ExistExpenseWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM expenses
+		WHERE id = $1
+		AND user_id = $2
+		AND deleted_at IS NOT NULL
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.ExpenseID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *expenseRepository) Restore(ctx context.Context, id int64) error {
+	query := `
+		UPDATE expenses
+		SET deleted_at = NULL,
+			updated_at = $1
+		WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
+
+// Purge permanently removes expenses that were soft-deleted before
+// deletedBefore and returns how many rows were removed.
+func (r *expenseRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := withTx(ctx, r.db, func(tx DBTX) error {
+		// SQLite does not enforce the cascade, so detach tags, split lines,
+		// shares and imported transactions explicitly
+		for _, table := range []string{"expense_tags", "expense_splits", "expense_shares", "imported_transactions"} {
+			query := `
+				DELETE FROM ` + table + `
+				WHERE expense_id IN (
+					SELECT id FROM expenses
+					WHERE deleted_at IS NOT NULL
+					AND deleted_at < $1
+				)`
+
+			if _, err := tx.ExecContext(ctx, query, deletedBefore); err != nil {
+				return err
+			}
+		}
+
+		// and split lines deleted with their category
+		splitsQuery := `
+			DELETE FROM expense_splits
+			WHERE deleted_at IS NOT NULL
+			AND deleted_at < $1`
+
+		if _, err := tx.ExecContext(ctx, splitsQuery, deletedBefore); err != nil {
+			return err
+		}
+
+		query := `
+			DELETE FROM expenses
+			WHERE deleted_at IS NOT NULL
+			AND deleted_at < $1`
+
+		result, err := tx.ExecContext(ctx, query, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		purged, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+func (r *expenseRepository) CountByCategory(ctx context.Context, categoryID int64) (int64, error) {
+	var count int64
+	query := `
+		SELECT
+			COUNT(*)
+		FROM expenses
+		WHERE (category_id = $1 OR id IN (
+			SELECT expense_id FROM expense_splits WHERE category_id = $1 AND deleted_at IS NULL
+		))
+		AND deleted_at IS NULL
+	`
+
+	err := r.db.GetContext(ctx, &count, query, categoryID)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ReassignCategory moves every active expense and split line from one
+// category to another and returns how many expenses were moved.
+func (r *expenseRepository) ReassignCategory(ctx context.Context, fromCategoryID, toCategoryID int64) (int64, error) {
+	moved, err := r.CountByCategory(ctx, fromCategoryID)
+	if err != nil {
+		return 0, err
+	}
+
+	splitsQuery := `
+		UPDATE expense_splits
+		SET category_id = $1
+		WHERE category_id = $2
+		AND deleted_at IS NULL
+		AND expense_id IN (SELECT id FROM expenses WHERE deleted_at IS NULL)`
+
+	if _, err := r.db.ExecContext(ctx, splitsQuery, toCategoryID, fromCategoryID); err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE expenses
+		SET category_id = $1,
+			updated_at = $2
+		WHERE category_id = $3
+		AND deleted_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, toCategoryID, time.Now(), fromCategoryID); err != nil {
+		return 0, err
+	}
+
+	return moved, nil
+}
+
+// categoryDeletedAt is the deletion time of category $1, or $2 while it is
+// not deleted. Entries a category's deletion takes with it are stamped with
+// it, which tells them apart from entries deleted on their own when the
+// category is restored.
+const categoryDeletedAt = `COALESCE((SELECT deleted_at FROM categories WHERE id = $1), $2)`
+
+// refileSplitExpenses files the active split expenses with a line in
+// category $2 under their first remaining line, as they were created. It
+// runs after a category's lines are taken off or brought back.
+const refileSplitExpenses = `
+	UPDATE expenses
+	SET category_id = (
+			SELECT expense_splits.category_id
+			FROM expense_splits
+			WHERE expense_splits.expense_id = expenses.id
+			AND expense_splits.deleted_at IS NULL
+			ORDER BY expense_splits.id ASC
+			LIMIT 1
+		),
+		updated_at = $1
+	WHERE deleted_at IS NULL
+	AND id IN (SELECT expense_id FROM expense_splits WHERE category_id = $2)
+	AND EXISTS (
+		SELECT 1 FROM expense_splits
+		WHERE expense_splits.expense_id = expenses.id
+		AND expense_splits.deleted_at IS NULL
+	)`
+
+// DeleteByCategory soft-deletes every active expense in a category and
+// returns how many expenses were deleted or changed. Split expenses only
+// lose their lines in the category, keeping their amount and shares, and
+// are deleted whole once no other line is left. RestoreByCategory undoes it.
+func (r *expenseRepository) DeleteByCategory(ctx context.Context, categoryID int64) (int64, error) {
+	var affected int64
+	err := withTx(ctx, r.db, func(tx DBTX) error {
+		now := time.Now()
+
+		// Expenses filed under the category, or split only across it
+		query := `
+			UPDATE expenses
+			SET deleted_at = ` + categoryDeletedAt + `
+			WHERE deleted_at IS NULL
+			AND (
+				(category_id = $1 AND NOT EXISTS (
+					SELECT 1 FROM expense_splits
+					WHERE expense_splits.expense_id = expenses.id
+					AND expense_splits.deleted_at IS NULL
+				))
+				OR (EXISTS (
+					SELECT 1 FROM expense_splits
+					WHERE expense_splits.expense_id = expenses.id
+					AND expense_splits.category_id = $1
+					AND expense_splits.deleted_at IS NULL
+				) AND NOT EXISTS (
+					SELECT 1 FROM expense_splits
+					WHERE expense_splits.expense_id = expenses.id
+					AND expense_splits.category_id <> $1
+					AND expense_splits.deleted_at IS NULL
+				))
+			)`
+
+		result, err := tx.ExecContext(ctx, query, categoryID, now)
+		if err != nil {
+			return err
+		}
+		if affected, err = result.RowsAffected(); err != nil {
+			return err
+		}
+
+		// Split expenses with lines in other categories too
+		var split int64
+		countQuery := `
+			SELECT COUNT(DISTINCT expense_splits.expense_id)
+			FROM expense_splits
+			JOIN expenses ON expenses.id = expense_splits.expense_id
+			WHERE expense_splits.category_id = $1
+			AND expense_splits.deleted_at IS NULL
+			AND expenses.deleted_at IS NULL`
+
+		if err := tx.GetContext(ctx, &split, countQuery, categoryID); err != nil {
+			return err
+		}
+		affected += split
+
+		splitsQuery := `
+			UPDATE expense_splits
+			SET deleted_at = ` + categoryDeletedAt + `
+			WHERE category_id = $1
+			AND deleted_at IS NULL
+			AND expense_id IN (SELECT id FROM expenses WHERE deleted_at IS NULL)`
+
+		if _, err := tx.ExecContext(ctx, splitsQuery, categoryID, now); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, refileSplitExpenses, now, categoryID)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, nil
+}
+
+// RestoreByCategory brings back the expenses and split lines deleted with a
+// category, before the category itself is restored.
+func (r *expenseRepository) RestoreByCategory(ctx context.Context, categoryID int64) error {
+	return withTx(ctx, r.db, func(tx DBTX) error {
+		query := `
+			UPDATE expenses
+			SET deleted_at = NULL,
+				updated_at = $1
+			WHERE deleted_at = (SELECT deleted_at FROM categories WHERE id = $2)
+			AND (category_id = $2 OR EXISTS (
+				SELECT 1 FROM expense_splits
+				WHERE expense_splits.expense_id = expenses.id
+				AND expense_splits.category_id = $2
+			))`
+
+		if _, err := tx.ExecContext(ctx, query, time.Now(), categoryID); err != nil {
+			return err
+		}
+
+		splitsQuery := `
+			UPDATE expense_splits
+			SET deleted_at = NULL
+			WHERE category_id = $1
+			AND deleted_at = (SELECT deleted_at FROM categories WHERE id = $1)`
+
+		if _, err := tx.ExecContext(ctx, splitsQuery, categoryID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, refileSplitExpenses, time.Now(), categoryID)
+		return err
+	})
+}
@@ -0,0 +1,637 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Group member roles, from most to least privileged
+const (
+	GroupRoleOwner  = "owner"
+	GroupRoleMember = "member"
+	GroupRoleViewer = "viewer"
+)
+
+// GroupRoleRank orders roles so a check can require a role or a more
+// privileged one.
+var GroupRoleRank = map[string]int{
+	GroupRoleViewer: 1,
+	GroupRoleMember: 2,
+	GroupRoleOwner:  3,
+}
+
+// Group is a household or other set of users who look at the expenses they
+// share with it together.
+type Group struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+
+	// Role is the requesting user's role, only set when listing their groups
+	Role        string `db:"role"`
+	MemberCount int64  `db:"member_count"`
+}
+
+type GroupMember struct {
+	GroupID   int64     `db:"group_id"`
+	UserID    int64     `db:"user_id"`
+	Email     string    `db:"email"`
+	Role      string    `db:"role"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type GroupInvitation struct {
+	ID        int64     `db:"id"`
+	GroupID   int64     `db:"group_id"`
+	GroupName string    `db:"group_name"`
+	Email     string    `db:"email"`
+	Role      string    `db:"role"`
+	InvitedBy int64     `db:"invited_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type GroupRepository interface {
+	Create(ctx context.Context, name string) (*Group, error)
+	GetByID(ctx context.Context, id int64) (*Group, error)
+	Update(ctx context.Context, id int64, name string) error
+	Delete(ctx context.Context, id int64) error
+	ListForUser(ctx context.Context, userID int64) ([]Group, error)
+	GetMember(ctx context.Context, groupID, userID int64) (*GroupMember, error)
+	ListMembers(ctx context.Context, groupID int64) ([]GroupMember, error)
+	SetMember(ctx context.Context, groupID, userID int64, role string) error
+	RemoveMember(ctx context.Context, groupID, userID int64) error
+	CountOwners(ctx context.Context, groupID int64) (int64, error)
+	CreateInvitation(ctx context.Context, input NewGroupInvitationInput) (*GroupInvitation, error)
+	GetInvitation(ctx context.Context, id int64) (*GroupInvitation, error)
+	ListInvitations(ctx context.Context, groupID int64) ([]GroupInvitation, error)
+	ListInvitationsForEmail(ctx context.Context, email string) ([]GroupInvitation, error)
+	DeleteInvitation(ctx context.Context, id int64) error
+	GetOverviewByCategory(ctx context.Context, groupID int64, period string, customDate *time.Time) ([]GroupCategoryOverview, error)
+	GetOverviewByMember(ctx context.Context, groupID int64, period string, customDate *time.Time) ([]GroupMemberOverview, error)
+	GetBalances(ctx context.Context, groupID int64) ([]GroupBalance, error)
+	CreateSettlement(ctx context.Context, input NewGroupSettlementInput) (*GroupSettlement, error)
+	GetSettlement(ctx context.Context, id int64) (*GroupSettlement, error)
+	ListSettlements(ctx context.Context, groupID int64) ([]GroupSettlement, error)
+	DeleteSettlement(ctx context.Context, id int64) error
+}
+
+type groupRepository struct {
+	db DBTX
+}
+
+func NewGroupRepository(db DBTX) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+// ErrGroupMemberNotFound is returned by GetMember when the user is not in
+// the group.
+var ErrGroupMemberNotFound = errors.New("group member not found")
+
+// ErrGroupNotSettled is returned when deleting a group whose members still
+// owe each other.
+var ErrGroupNotSettled = errors.New("group has open balances")
+
+func (r *groupRepository) Create(ctx context.Context, name string) (*Group, error) {
+	query := `
+		INSERT INTO groups (name, created_at, updated_at)
+		VALUES ($1, $2, $3)
+		RETURNING id`
+
+	now := time.Now()
+
+	var id int64
+	if err := r.db.QueryRowContext(ctx, query, name, now, now).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *groupRepository) GetByID(ctx context.Context, id int64) (*Group, error) {
+	var group Group
+	query := `
+		SELECT
+			groups.id,
+			groups.name,
+			groups.created_at,
+			groups.updated_at,
+			'' as role,
+			(SELECT COUNT(*) FROM group_members WHERE group_members.group_id = groups.id) as member_count
+		FROM groups
+		WHERE groups.id = $1`
+
+	err := r.db.GetContext(ctx, &group, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("group not found")
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *groupRepository) Update(ctx context.Context, id int64, name string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE groups SET name = $1, updated_at = $2 WHERE id = $3`, name, time.Now(), id)
+	return err
+}
+
+// Delete removes a group with its members, invitations and settlements.
+// Expenses shared with it go back to being private, without their shares.
+// It fails with ErrGroupNotSettled while any balance is open, as the debts
+// would go with the shares.
+func (r *groupRepository) Delete(ctx context.Context, id int64) error {
+	balances, err := r.GetBalances(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, balance := range balances {
+		if balance.Net() != 0 {
+			return ErrGroupNotSettled
+		}
+	}
+
+	// SQLite does not enforce the cascades, so clean up explicitly
+	queries := []string{
+		`DELETE FROM expense_shares WHERE expense_id IN (SELECT id FROM expenses WHERE group_id = $1)`,
+		`UPDATE expenses SET group_id = NULL WHERE group_id = $1`,
+		`DELETE FROM group_settlements WHERE group_id = $1`,
+		`DELETE FROM group_invitations WHERE group_id = $1`,
+		`DELETE FROM group_members WHERE group_id = $1`,
+		`DELETE FROM groups WHERE id = $1`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListForUser returns the groups the user belongs to, by name, with the
+// user's role in each.
+func (r *groupRepository) ListForUser(ctx context.Context, userID int64) ([]Group, error) {
+	groups := []Group{}
+	query := `
+		SELECT
+			groups.id,
+			groups.name,
+			groups.created_at,
+			groups.updated_at,
+			group_members.role,
+			(SELECT COUNT(*) FROM group_members members WHERE members.group_id = groups.id) as member_count
+		FROM groups
+		JOIN group_members ON group_members.group_id = groups.id
+		WHERE group_members.user_id = $1
+		ORDER BY groups.name, groups.id
+	`
+
+	if err := r.db.SelectContext(ctx, &groups, query, userID); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+const groupMemberColumns = `
+			group_members.group_id,
+			group_members.user_id,
+			users.email,
+			group_members.role,
+			group_members.created_at,
+			group_members.updated_at`
+
+// GetMember returns the user's membership of a group, or
+// ErrGroupMemberNotFound.
+func (r *groupRepository) GetMember(ctx context.Context, groupID, userID int64) (*GroupMember, error) {
+	var member GroupMember
+	query := `
+		SELECT` + groupMemberColumns + `
+		FROM group_members
+		JOIN users ON users.id = group_members.user_id
+		WHERE group_members.group_id = $1
+		AND group_members.user_id = $2`
+
+	err := r.db.GetContext(ctx, &member, query, groupID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListMembers returns the members of a group, owners first.
+func (r *groupRepository) ListMembers(ctx context.Context, groupID int64) ([]GroupMember, error) {
+	members := []GroupMember{}
+	query := `
+		SELECT` + groupMemberColumns + `
+		FROM group_members
+		JOIN users ON users.id = group_members.user_id
+		WHERE group_members.group_id = $1
+		ORDER BY CASE group_members.role WHEN 'owner' THEN 0 WHEN 'member' THEN 1 ELSE 2 END, users.email
+	`
+
+	if err := r.db.SelectContext(ctx, &members, query, groupID); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// SetMember adds the user to a group with role, or changes the role of an
+// existing member.
+func (r *groupRepository) SetMember(ctx context.Context, groupID, userID int64, role string) error {
+	query := `
+		INSERT INTO group_members (group_id, user_id, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (group_id, user_id) DO UPDATE SET role = excluded.role, updated_at = excluded.updated_at`
+
+	_, err := r.db.ExecContext(ctx, query, groupID, userID, role, time.Now())
+	return err
+}
+
+// RemoveMember takes the user out of a group. Their expenses shared with it
+// go back to being private, except bills others have a share of, which stay
+// so the group's balances keep adding up.
+func (r *groupRepository) RemoveMember(ctx context.Context, groupID, userID int64) error {
+	query := `
+		UPDATE expenses SET group_id = NULL
+		WHERE group_id = $1
+		AND user_id = $2
+		AND NOT EXISTS (SELECT 1 FROM expense_shares WHERE expense_shares.expense_id = expenses.id)`
+
+	if _, err := r.db.ExecContext(ctx, query, groupID, userID); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+	return err
+}
+
+func (r *groupRepository) CountOwners(ctx context.Context, groupID int64) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM group_members WHERE group_id = $1 AND role = 'owner'`
+	if err := r.db.GetContext(ctx, &count, query, groupID); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+type NewGroupInvitationInput struct {
+	GroupID   int64
+	Email     string
+	Role      string
+	InvitedBy int64
+}
+
+// CreateInvitation invites an email address to a group, replacing the role
+// of a pending invitation to the same address.
+func (r *groupRepository) CreateInvitation(ctx context.Context, input NewGroupInvitationInput) (*GroupInvitation, error) {
+	query := `
+		INSERT INTO group_invitations (group_id, email, role, invited_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (group_id, email) DO UPDATE SET role = excluded.role, invited_by = excluded.invited_by
+		RETURNING id`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query,
+		input.GroupID, strings.ToLower(input.Email), input.Role, input.InvitedBy, time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetInvitation(ctx, id)
+}
+
+const groupInvitationColumns = `
+			group_invitations.id,
+			group_invitations.group_id,
+			groups.name as group_name,
+			group_invitations.email,
+			group_invitations.role,
+			group_invitations.invited_by,
+			group_invitations.created_at`
+
+func (r *groupRepository) GetInvitation(ctx context.Context, id int64) (*GroupInvitation, error) {
+	var invitation GroupInvitation
+	query := `
+		SELECT` + groupInvitationColumns + `
+		FROM group_invitations
+		JOIN groups ON groups.id = group_invitations.group_id
+		WHERE group_invitations.id = $1`
+
+	err := r.db.GetContext(ctx, &invitation, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("group invitation not found")
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *groupRepository) ListInvitations(ctx context.Context, groupID int64) ([]GroupInvitation, error) {
+	return r.listInvitations(ctx, "group_invitations.group_id = $1", groupID)
+}
+
+// ListInvitationsForEmail returns the pending invitations to an email
+// address, compared case-insensitively.
+func (r *groupRepository) ListInvitationsForEmail(ctx context.Context, email string) ([]GroupInvitation, error) {
+	return r.listInvitations(ctx, "group_invitations.email = $1", strings.ToLower(email))
+}
+
+func (r *groupRepository) listInvitations(ctx context.Context, condition string, args ...interface{}) ([]GroupInvitation, error) {
+	invitations := []GroupInvitation{}
+	query := `
+		SELECT` + groupInvitationColumns + `
+		FROM group_invitations
+		JOIN groups ON groups.id = group_invitations.group_id
+		WHERE ` + condition + `
+		ORDER BY group_invitations.created_at DESC, group_invitations.id DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &invitations, query, args...); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (r *groupRepository) DeleteInvitation(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM group_invitations WHERE id = $1`, id)
+	return err
+}
+
+// GroupCategoryOverview totals the expenses shared with a group under
+// categories of one name, in one currency on one day. Members keep their
+// own categories, so categories with the same name are combined.
+type GroupCategoryOverview struct {
+	CategoryName string `db:"category_name"`
+	Currency     string `db:"currency"`
+	Day          string `db:"day"`
+	TotalAmount  int64  `db:"total_amount"`
+	Count        int64  `db:"count"`
+	// ExpenseCount counts a split expense on one of its rows only
+	ExpenseCount int64 `db:"expense_count"`
+}
+
+// GetOverviewByCategory groups the period's expenses shared with the group
+// by category name, currency and day, attributing split expenses line by
+// line.
+func (r *groupRepository) GetOverviewByCategory(ctx context.Context, groupID int64, period string, customDate *time.Time) ([]GroupCategoryOverview, error) {
+	dialect := dialectOf(r.db.DriverName())
+	condition, err := dialect.periodCondition("e.occurred_at", period, 2)
+	if err != nil {
+		return nil, errors.New("invalid period. Must be 'today', 'month', or 'year'")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			MIN(c.name) as category_name,
+			e.currency,
+			%s as day,
+			SUM(e.amount) as total_amount,
+			COUNT(DISTINCT e.id) as count,
+			SUM(e.first_line) as expense_count
+		FROM (%s
+		) e
+		JOIN categories c ON c.id = e.category_id
+		WHERE e.group_id = $1
+			AND e.deleted_at IS NULL
+			AND %s
+		GROUP BY LOWER(c.name), e.currency, day
+		ORDER BY total_amount DESC
+	`, dialect.dayOf("e.occurred_at"), expenseLines, condition)
+
+	overviews := []GroupCategoryOverview{}
+	if err := r.db.SelectContext(ctx, &overviews, query, groupID, customDate); err != nil {
+		return nil, fmt.Errorf("Failed to get group overview by category: %w", err)
+	}
+
+	return overviews, nil
+}
+
+// GroupMemberOverview totals the expenses a member shared with a group in
+// one currency on one day.
+type GroupMemberOverview struct {
+	UserID      int64  `db:"user_id"`
+	Email       string `db:"email"`
+	Currency    string `db:"currency"`
+	Day         string `db:"day"`
+	TotalAmount int64  `db:"total_amount"`
+	Count       int64  `db:"count"`
+}
+
+// GetOverviewByMember groups the period's expenses shared with the group by
+// the member who paid them, currency and day.
+func (r *groupRepository) GetOverviewByMember(ctx context.Context, groupID int64, period string, customDate *time.Time) ([]GroupMemberOverview, error) {
+	dialect := dialectOf(r.db.DriverName())
+	condition, err := dialect.periodCondition("e.occurred_at", period, 2)
+	if err != nil {
+		return nil, errors.New("invalid period. Must be 'today', 'month', or 'year'")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			users.id as user_id,
+			users.email,
+			e.currency,
+			%s as day,
+			SUM(e.amount) as total_amount,
+			COUNT(e.id) as count
+		FROM expenses e
+		JOIN users ON users.id = e.user_id
+		WHERE e.group_id = $1
+			AND e.deleted_at IS NULL
+			AND %s
+		GROUP BY users.id, users.email, e.currency, day
+		ORDER BY total_amount DESC
+	`, dialect.dayOf("e.occurred_at"), condition)
+
+	overviews := []GroupMemberOverview{}
+	if err := r.db.SelectContext(ctx, &overviews, query, groupID, customDate); err != nil {
+		return nil, fmt.Errorf("Failed to get group overview by member: %w", err)
+	}
+
+	return overviews, nil
+}
+
+// GroupBalance is where a user stands in one currency of a group's shared
+// bills and settlements. Amounts are in the currency's minor unit.
+type GroupBalance struct {
+	UserID   int64  `db:"user_id"`
+	Email    string `db:"email"`
+	Currency string `db:"currency"`
+	// Paid is the total of bills the user paid and Owed their shares of
+	// bills, their own included
+	Paid int64 `db:"paid"`
+	Owed int64 `db:"owed"`
+	// Sent and Received are settlements paid to and by others
+	Sent     int64 `db:"sent"`
+	Received int64 `db:"received"`
+}
+
+// Net is what the user is owed, negative when they owe others.
+func (b GroupBalance) Net() int64 {
+	return b.Paid - b.Owed + b.Sent - b.Received
+}
+
+// GetBalances adds up, per user and currency, the group's bills that are
+// not in the trash and its settlements. Expenses without shares are not
+// bills and are left out.
+func (r *groupRepository) GetBalances(ctx context.Context, groupID int64) ([]GroupBalance, error) {
+	query := `
+		SELECT
+			users.id as user_id,
+			users.email,
+			b.currency,
+			SUM(b.paid) as paid,
+			SUM(b.owed) as owed,
+			SUM(b.sent) as sent,
+			SUM(b.received) as received
+		FROM (
+			SELECT expenses.user_id, expenses.currency, expenses.amount as paid, 0 as owed, 0 as sent, 0 as received
+			FROM expenses
+			WHERE expenses.group_id = $1
+			AND expenses.deleted_at IS NULL
+			AND EXISTS (SELECT 1 FROM expense_shares WHERE expense_shares.expense_id = expenses.id)
+			UNION ALL
+			SELECT expense_shares.user_id, expenses.currency, 0, expense_shares.amount, 0, 0
+			FROM expense_shares
+			JOIN expenses ON expenses.id = expense_shares.expense_id
+			WHERE expenses.group_id = $1
+			AND expenses.deleted_at IS NULL
+			UNION ALL
+			SELECT from_user_id, currency, 0, 0, amount, 0
+			FROM group_settlements
+			WHERE group_id = $1
+			UNION ALL
+			SELECT to_user_id, currency, 0, 0, 0, amount
+			FROM group_settlements
+			WHERE group_id = $1
+		) b
+		JOIN users ON users.id = b.user_id
+		GROUP BY users.id, users.email, b.currency
+		ORDER BY b.currency, users.email
+	`
+
+	balances := []GroupBalance{}
+	if err := r.db.SelectContext(ctx, &balances, query, groupID); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// GroupSettlement is a payment between two members that settles what one
+// owes the other in a group.
+type GroupSettlement struct {
+	ID         int64     `db:"id"`
+	GroupID    int64     `db:"group_id"`
+	FromUserID int64     `db:"from_user_id"`
+	FromEmail  string    `db:"from_email"`
+	ToUserID   int64     `db:"to_user_id"`
+	ToEmail    string    `db:"to_email"`
+	Amount     int64     `db:"amount"`
+	Currency   string    `db:"currency"`
+	Note       string    `db:"note"`
+	OccurredAt time.Time `db:"occurred_at"`
+	CreatedBy  int64     `db:"created_by"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+const groupSettlementColumns = `
+			group_settlements.id,
+			group_settlements.group_id,
+			group_settlements.from_user_id,
+			from_users.email as from_email,
+			group_settlements.to_user_id,
+			to_users.email as to_email,
+			group_settlements.amount,
+			group_settlements.currency,
+			group_settlements.note,
+			group_settlements.occurred_at,
+			group_settlements.created_by,
+			group_settlements.created_at`
+
+const groupSettlementJoins = `
+		JOIN users from_users ON from_users.id = group_settlements.from_user_id
+		JOIN users to_users ON to_users.id = group_settlements.to_user_id`
+
+type NewGroupSettlementInput struct {
+	GroupID    int64
+	FromUserID int64
+	ToUserID   int64
+	// Amount is in the minor unit of Currency
+	Amount     int64
+	Currency   string
+	Note       string
+	OccurredAt *time.Time
+	CreatedBy  int64
+}
+
+func (r *groupRepository) CreateSettlement(ctx context.Context, input NewGroupSettlementInput) (*GroupSettlement, error) {
+	query := `
+		INSERT INTO group_settlements (group_id, from_user_id, to_user_id, amount, currency, note, occurred_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+
+	now := time.Now()
+	occurredAt := now
+	if input.OccurredAt != nil {
+		occurredAt = *input.OccurredAt
+	}
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query,
+		input.GroupID, input.FromUserID, input.ToUserID, input.Amount, input.Currency, input.Note, occurredAt.UTC(), input.CreatedBy, now,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetSettlement(ctx, id)
+}
+
+func (r *groupRepository) GetSettlement(ctx context.Context, id int64) (*GroupSettlement, error) {
+	var settlement GroupSettlement
+	query := `SELECT` + groupSettlementColumns + ` FROM group_settlements` + groupSettlementJoins + ` WHERE group_settlements.id = $1`
+	err := r.db.GetContext(ctx, &settlement, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("group settlement not found")
+		}
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+// ListSettlements returns the settlements of a group, most recent first.
+func (r *groupRepository) ListSettlements(ctx context.Context, groupID int64) ([]GroupSettlement, error) {
+	settlements := []GroupSettlement{}
+	query := `
+		SELECT` + groupSettlementColumns + `
+		FROM group_settlements` + groupSettlementJoins + `
+		WHERE group_settlements.group_id = $1
+		ORDER BY group_settlements.occurred_at DESC, group_settlements.id DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &settlements, query, groupID); err != nil {
+		return nil, err
+	}
+
+	return settlements, nil
+}
+
+func (r *groupRepository) DeleteSettlement(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM group_settlements WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ImportProfile is a saved layout of a bank or e-wallet CSV statement. See
+// statement.Mapping for the meaning of its columns.
+type ImportProfile struct {
+	ID                int64  `db:"id"`
+	UserID            int64  `db:"user_id"`
+	Name              string `db:"name"`
+	Delimiter         string `db:"delimiter"`
+	HasHeader         bool   `db:"has_header"`
+	DateColumn        string `db:"date_column"`
+	AmountColumn      string `db:"amount_column"`
+	DescriptionColumn string `db:"description_column"`
+	DateFormat        string `db:"date_format"`
+	ExpenseSign       string `db:"expense_sign"`
+	DecimalSeparator  string `db:"decimal_separator"`
+	// Currency of the statement's amounts. Rows are imported as expenses in
+	// it, paid from WalletID when set.
+	Currency  string        `db:"currency"`
+	WalletID  sql.NullInt64 `db:"wallet_id"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
+}
+
+type ImportProfileRepository interface {
+	Create(ctx context.Context, input ImportProfileInput) (*ImportProfile, error)
+	GetByID(ctx context.Context, id int64) (*ImportProfile, error)
+	Update(ctx context.Context, id int64, input ImportProfileInput) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, userID int64) ([]ImportProfile, error)
+	ExistWithUserID(ctx context.Context, input ExistImportProfileWithUserIDInput) (bool, error)
+}
+
+type importProfileRepository struct {
+	db DBTX
+}
+
+func NewImportProfileRepository(db DBTX) ImportProfileRepository {
+	return &importProfileRepository{db: db}
+}
+
+const importProfileColumns = `
+			id,
+			user_id,
+			name,
+			delimiter,
+			has_header,
+			date_column,
+			amount_column,
+			description_column,
+			date_format,
+			expense_sign,
+			decimal_separator,
+			currency,
+			wallet_id,
+			created_at,
+			updated_at`
+
+// ImportProfileInput describes a profile to create, or what to set on an
+// existing one. UserID is ignored on update.
+type ImportProfileInput struct {
+	UserID            int64
+	Name              string
+	Delimiter         string
+	HasHeader         bool
+	DateColumn        string
+	AmountColumn      string
+	DescriptionColumn string
+	DateFormat        string
+	ExpenseSign       string
+	DecimalSeparator  string
+	Currency          string
+	WalletID          sql.NullInt64
+}
+
+func (r *importProfileRepository) Create(ctx context.Context, input ImportProfileInput) (*ImportProfile, error) {
+	query := `
+		INSERT INTO import_profiles (user_id, name, delimiter, has_header, date_column, amount_column, description_column, date_format, expense_sign, decimal_separator, currency, wallet_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id`
+
+	now := time.Now()
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query,
+		input.UserID, input.Name, input.Delimiter, input.HasHeader, input.DateColumn, input.AmountColumn, input.DescriptionColumn,
+		input.DateFormat, input.ExpenseSign, input.DecimalSeparator, input.Currency, input.WalletID, now, now,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *importProfileRepository) GetByID(ctx context.Context, id int64) (*ImportProfile, error) {
+	var profile ImportProfile
+	query := `SELECT` + importProfileColumns + ` FROM import_profiles WHERE id = $1`
+	err := r.db.GetContext(ctx, &profile, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("import profile not found")
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *importProfileRepository) Update(ctx context.Context, id int64, input ImportProfileInput) error {
+	query := `
+		UPDATE import_profiles
+		SET name = $1,
+			delimiter = $2,
+			has_header = $3,
+			date_column = $4,
+			amount_column = $5,
+			description_column = $6,
+			date_format = $7,
+			expense_sign = $8,
+			decimal_separator = $9,
+			currency = $10,
+			wallet_id = $11,
+			updated_at = $12
+		WHERE id = $13`
+
+	_, err := r.db.ExecContext(ctx, query,
+		input.Name, input.Delimiter, input.HasHeader, input.DateColumn, input.AmountColumn, input.DescriptionColumn,
+		input.DateFormat, input.ExpenseSign, input.DecimalSeparator, input.Currency, input.WalletID, time.Now(), id,
+	)
+	return err
+}
+
+func (r *importProfileRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM import_profiles WHERE id = $1`, id)
+	return err
+}
+
+func (r *importProfileRepository) List(ctx context.Context, userID int64) ([]ImportProfile, error) {
+	profiles := []ImportProfile{}
+	query := `
+		SELECT` + importProfileColumns + `
+		FROM import_profiles
+		WHERE user_id = $1
+		ORDER BY name, id
+	`
+
+	if err := r.db.SelectContext(ctx, &profiles, query, userID); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+type ExistImportProfileWithUserIDInput struct {
+	UserID    int64 `doc:"User ID"`
+	ProfileID int64 `doc:"Import profile ID"`
+}
+
+func (r *importProfileRepository) ExistWithUserID(ctx context.Context, input ExistImportProfileWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM import_profiles
+		WHERE id = $1
+		AND user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.ProfileID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,506 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guregu/null/v6"
+)
+
+// Income is money coming in, filed under an income category.
+type Income struct {
+	ID         int64 `db:"id"`
+	UserID     int64 `db:"user_id"`
+	CategoryID int64 `db:"category_id"`
+	// Amount is in the minor unit of Currency
+	Amount      int64     `db:"amount"`
+	Currency    string    `db:"currency"`
+	Description string    `db:"description"`
+	OccurredAt  time.Time `db:"occurred_at"`
+	// WalletID is the wallet the income was paid into, if any
+	WalletID  sql.NullInt64 `db:"wallet_id"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
+	DeletedAt null.Time     `db:"deleted_at"`
+
+	CategoryName        string    `db:"category_name"`
+	CategoryDescription string    `db:"category_description"`
+	CategoryCreatedAt   time.Time `db:"category_created_at"`
+	CategoryUpdatedAt   time.Time `db:"category_updated_at"`
+}
+
+type IncomeRepository interface {
+	Create(ctx context.Context, input NewIncomeInput) (*Income, error)
+	GetByID(ctx context.Context, id int64) (*Income, error)
+	Update(ctx context.Context, input UpdateIncomeInput) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, input ListIncomeInput) (*IncomePage, error)
+	ExistWithUserID(ctx context.Context, input ExistIncomeWithUserIDInput) (bool, error)
+	ListDeleted(ctx context.Context, userID int64) ([]Income, error)
+	ExistDeletedWithUserID(ctx context.Context, input ExistIncomeWithUserIDInput) (bool, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	CountByCategory(ctx context.Context, categoryID int64) (int64, error)
+	ReassignCategory(ctx context.Context, fromCategoryID, toCategoryID int64) (int64, error)
+	DeleteByCategory(ctx context.Context, categoryID int64) (int64, error)
+	RestoreByCategory(ctx context.Context, categoryID int64) error
+	GetDailyTotals(ctx context.Context, userID int64, period string, customDate *time.Time, walletID int64) ([]DailyTotal, error)
+}
+
+type incomeRepository struct {
+	db DBTX
+}
+
+func NewIncomeRepository(db DBTX) IncomeRepository {
+	return &incomeRepository{db: db}
+}
+
+// incomeColumns selects an income together with its category.
+const incomeColumns = `
+			incomes.id,
+			incomes.user_id,
+			incomes.category_id,
+			incomes.amount,
+			incomes.currency,
+			incomes.description,
+			incomes.occurred_at,
+			incomes.wallet_id,
+			incomes.created_at,
+			incomes.updated_at,
+			incomes.deleted_at,
+			categories.name as category_name,
+			COALESCE(categories.description, '') as category_description,
+			categories.created_at as category_created_at,
+			categories.updated_at as category_updated_at`
+
+type NewIncomeInput struct {
+	UserID     int64
+	CategoryID int64
+	// Amount is in the minor unit of Currency
+	Amount      int64
+	Currency    string
+	Description string
+	// OccurredAt defaults to the time of creation
+	OccurredAt *time.Time
+	WalletID   sql.NullInt64
+}
+
+func (r *incomeRepository) Create(ctx context.Context, input NewIncomeInput) (*Income, error) {
+	query := `
+		INSERT INTO incomes (user_id, category_id, amount, currency, description, occurred_at, wallet_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+
+	now := time.Now()
+	occurredAt := now
+	if input.OccurredAt != nil {
+		occurredAt = *input.OccurredAt
+	}
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query,
+		input.UserID, input.CategoryID, input.Amount, input.Currency, input.Description, occurredAt.UTC(), input.WalletID, now, now,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *incomeRepository) GetByID(ctx context.Context, id int64) (*Income, error) {
+	var income Income
+	query := `
+		SELECT` + incomeColumns + `
+		FROM incomes
+		JOIN categories ON categories.id = incomes.category_id
+		WHERE incomes.id = $1`
+
+	err := r.db.GetContext(ctx, &income, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("income not found")
+		}
+		return nil, err
+	}
+	return &income, nil
+}
+
+type UpdateIncomeInput struct {
+	IncomeID   int64
+	UserID     int64
+	CategoryID int64
+	// Amount is in the minor unit of Currency
+	Amount      int64
+	Currency    string
+	Description string
+	// OccurredAt is left unchanged when nil
+	OccurredAt *time.Time
+	WalletID   sql.NullInt64
+}
+
+func (r *incomeRepository) Update(ctx context.Context, input UpdateIncomeInput) error {
+	query := `
+		UPDATE incomes
+		SET category_id = $1,
+			amount = $2,
+			currency = $3,
+			description = $4,
+			occurred_at = COALESCE($5, occurred_at),
+			wallet_id = $6,
+			updated_at = $7
+		WHERE id = $8 AND user_id = $9`
+
+	_, err := r.db.ExecContext(ctx, query,
+		input.CategoryID, input.Amount, input.Currency, input.Description, utcOrNil(input.OccurredAt), input.WalletID, time.Now(),
+		input.IncomeID, input.UserID,
+	)
+	return err
+}
+
+func (r *incomeRepository) Delete(ctx context.Context, id int64) error {
+	query := `
+		UPDATE incomes
+		SET deleted_at = $1
+		WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
+
+type ListIncomeInput struct {
+	UserID   int64
+	Page     int
+	Limit    int
+	Date     *time.Time
+	Category []int64
+	Cursor   string
+	// WalletID lists only incomes paid into that wallet when set
+	WalletID int64
+}
+
+// IncomePage is one page of a list. The totals cover every income matching
+// the filters, not just this page, with one sum per currency.
+type IncomePage struct {
+	Incomes    []Income
+	NextCursor string
+	HasMore    bool
+	TotalCount int64
+	Totals     []CurrencyTotal
+}
+
+// List returns incomes by when they occurred, most recent first. Pages
+// continue from an opaque cursor keyed on (occurred_at, id), like expenses.
+func (r *incomeRepository) List(ctx context.Context, input ListIncomeInput) (*IncomePage, error) {
+	defaultLimit := 10
+	if input.Limit == 0 {
+		input.Limit = defaultLimit
+	}
+
+	defaultPage := 1
+	if input.Page == 0 {
+		input.Page = defaultPage
+	}
+
+	after, err := decodeCursor(input.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{input.UserID}
+	conditions := []string{"incomes.deleted_at IS NULL", "incomes.user_id = $1"}
+
+	if input.Date != nil {
+		startOfDay := input.Date.In(time.UTC).Truncate(24 * time.Hour)
+		endOfDay := startOfDay.Add(24 * time.Hour)
+
+		condition := fmt.Sprintf("incomes.occurred_at >= $%d AND incomes.occurred_at < $%d", len(args)+1, len(args)+2)
+		conditions = append(conditions, condition)
+		args = append(args, startOfDay, endOfDay)
+	}
+
+	if len(input.Category) > 0 {
+		placeholders := make([]string, len(input.Category))
+		for i, categoryID := range input.Category {
+			placeholders[i] = fmt.Sprintf("$%d", len(args)+1)
+			args = append(args, categoryID)
+		}
+		conditions = append(conditions, fmt.Sprintf("incomes.category_id IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	if input.WalletID != 0 {
+		conditions = append(conditions, fmt.Sprintf("incomes.wallet_id = $%d", len(args)+1))
+		args = append(args, input.WalletID)
+	}
+
+	page := &IncomePage{Incomes: []Income{}}
+
+	totalsQuery := `
+		SELECT
+			incomes.currency,
+			COUNT(*) as count,
+			SUM(incomes.amount) as amount
+		FROM incomes
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY incomes.currency
+		ORDER BY incomes.currency`
+
+	var totals []struct {
+		CurrencyTotal
+		Count int64 `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &totals, totalsQuery, args...); err != nil {
+		return nil, err
+	}
+	page.Totals = make([]CurrencyTotal, len(totals))
+	for i, total := range totals {
+		page.Totals[i] = total.CurrencyTotal
+		page.TotalCount += total.Count
+	}
+
+	offset := (input.Page - 1) * input.Limit
+	if after.Key != "" {
+		occurredAt, err := time.Parse(time.RFC3339Nano, after.Key)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		offset = 0
+		condition := fmt.Sprintf("(incomes.occurred_at < $%d OR (incomes.occurred_at = $%d AND incomes.id < $%d))", len(args)+1, len(args)+1, len(args)+2)
+		conditions = append(conditions, condition)
+		args = append(args, occurredAt.UTC(), after.ID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT%s
+		FROM incomes
+		JOIN categories ON categories.id = incomes.category_id
+		WHERE %s
+		ORDER BY incomes.occurred_at DESC, incomes.id DESC
+		LIMIT $%d OFFSET $%d
+	`, incomeColumns, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+
+	// Fetch one extra row to learn whether another page follows
+	args = append(args, input.Limit+1, offset)
+
+	if err := r.db.SelectContext(ctx, &page.Incomes, query, args...); err != nil {
+		return nil, err
+	}
+
+	if len(page.Incomes) > input.Limit {
+		page.Incomes = page.Incomes[:input.Limit]
+		page.HasMore = true
+
+		last := page.Incomes[len(page.Incomes)-1]
+		page.NextCursor = encodeCursor(cursor{Key: last.OccurredAt.Format(time.RFC3339Nano), ID: last.ID})
+	}
+
+	return page, nil
+}
+
+type ExistIncomeWithUserIDInput struct {
+	UserID   int64 `doc:"User ID"`
+	IncomeID int64 `doc:"Income ID"`
+}
+
+func (r *incomeRepository) ExistWithUserID(ctx context.Context, input ExistIncomeWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM incomes
+		WHERE id = $1
+		AND user_id = $2
+		AND deleted_at IS NULL
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.IncomeID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *incomeRepository) ListDeleted(ctx context.Context, userID int64) ([]Income, error) {
+	incomes := []Income{}
+	query := `
+		SELECT` + incomeColumns + `
+		FROM incomes
+		JOIN categories ON categories.id = incomes.category_id
+		WHERE incomes.user_id = $1
+		AND incomes.deleted_at IS NOT NULL
+		ORDER BY incomes.deleted_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &incomes, query, userID); err != nil {
+		return nil, err
+	}
+
+	return incomes, nil
+}
+
+func (r *incomeRepository) ExistDeletedWithUserID(ctx context.Context, input ExistIncomeWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM incomes
+		WHERE id = $1
+		AND user_id = $2
+		AND deleted_at IS NOT NULL
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.IncomeID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *incomeRepository) Restore(ctx context.Context, id int64) error {
+	query := `
+		UPDATE incomes
+		SET deleted_at = NULL,
+			updated_at = $1
+		WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
+
+// Purge permanently removes incomes that were soft-deleted before
+// deletedBefore and returns how many rows were removed.
+func (r *incomeRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	query := `
+		DELETE FROM incomes
+		WHERE deleted_at IS NOT NULL
+		AND deleted_at < $1`
+
+	result, err := r.db.ExecContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *incomeRepository) CountByCategory(ctx context.Context, categoryID int64) (int64, error) {
+	var count int64
+	query := `
+		SELECT
+			COUNT(*)
+		FROM incomes
+		WHERE category_id = $1
+		AND deleted_at IS NULL
+	`
+
+	if err := r.db.GetContext(ctx, &count, query, categoryID); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ReassignCategory moves every active income from one category to another
+// and returns how many incomes were moved.
+func (r *incomeRepository) ReassignCategory(ctx context.Context, fromCategoryID, toCategoryID int64) (int64, error) {
+	query := `
+		UPDATE incomes
+		SET category_id = $1,
+			updated_at = $2
+		WHERE category_id = $3
+		AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, toCategoryID, time.Now(), fromCategoryID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteByCategory soft-deletes every active income in a category and
+// returns how many incomes were deleted. RestoreByCategory undoes it.
+func (r *incomeRepository) DeleteByCategory(ctx context.Context, categoryID int64) (int64, error) {
+	query := `
+		UPDATE incomes
+		SET deleted_at = ` + categoryDeletedAt + `
+		WHERE category_id = $1
+		AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, categoryID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// RestoreByCategory brings back the incomes deleted with a category, before
+// the category itself is restored.
+func (r *incomeRepository) RestoreByCategory(ctx context.Context, categoryID int64) error {
+	query := `
+		UPDATE incomes
+		SET deleted_at = NULL,
+			updated_at = $1
+		WHERE category_id = $2
+		AND deleted_at = (SELECT deleted_at FROM categories WHERE id = $2)`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), categoryID)
+	return err
+}
+
+// DailyTotal sums entries in one currency on one day, so each row can be
+// converted at that day's exchange rate.
+type DailyTotal struct {
+	Currency    string `db:"currency"`
+	Day         string `db:"day"`
+	TotalAmount int64  `db:"total_amount"`
+	Count       int64  `db:"count"`
+}
+
+// GetDailyTotals totals the user's active incomes in the period, with the
+// same period semantics as the expense overview. A walletID other than 0
+// only counts incomes paid into it.
+func (r *incomeRepository) GetDailyTotals(ctx context.Context, userID int64, period string, customDate *time.Time, walletID int64) ([]DailyTotal, error) {
+	return dailyTotals(ctx, r.db, "incomes", userID, period, customDate, walletID)
+}
+
+// dailyTotals groups the active entries of table, expenses or incomes, by
+// currency and day, including ones kept under a deleted category.
+func dailyTotals(ctx context.Context, db DBTX, table string, userID int64, period string, customDate *time.Time, walletID int64) ([]DailyTotal, error) {
+	dialect := dialectOf(db.DriverName())
+	periodCondition, err := dialect.periodCondition("t.occurred_at", period, 2)
+	if err != nil {
+		return nil, errors.New("invalid period. Must be 'today', 'month', or 'year'")
+	}
+	periodCondition, args := withWallet(periodCondition, "t.wallet_id", walletID, userID, customDate)
+
+	query := fmt.Sprintf(`
+		SELECT
+			t.currency,
+			%s as day,
+			SUM(t.amount) as total_amount,
+			COUNT(t.id) as count
+		FROM %s t
+		INNER JOIN categories c ON c.id = t.category_id
+		WHERE t.user_id = $1
+			AND t.deleted_at IS NULL
+			AND %s
+		GROUP BY t.currency, day
+		ORDER BY day
+	`, dialect.dayOf("t.occurred_at"), table, periodCondition)
+
+	totals := []DailyTotal{}
+	if err := db.SelectContext(ctx, &totals, query, args...); err != nil {
+		return nil, fmt.Errorf("Failed to get daily totals of %s: %w", table, err)
+	}
+
+	return totals, nil
+}
@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is a numbered schema change with its up and down scripts.
+// The checksum is taken from the up script so edits to an already applied
+// migration are detected instead of silently ignored.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the embedded migrations written for the dialect of db.
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+	dialect := dialectOf(db.DriverName())
+	if dialect == DialectSQLite {
+		if err := checkFTS5(db); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := path.Join("migrations", string(dialect))
+	return newMigrator(db, migrationFiles, dir)
+}
+
+// checkFTS5 fails when SQLite was compiled without the FTS5 module, which
+// the expense search migration needs, so a build missing the sqlite_fts5
+// tag stops at startup instead of part way through the migrations.
+func checkFTS5(db *sqlx.DB) error {
+	var enabled bool
+	if err := db.Get(&enabled, `SELECT sqlite_compileoption_used('ENABLE_FTS5')`); err != nil {
+		return fmt.Errorf("failed to check for FTS5: %w", err)
+	}
+	if !enabled {
+		return errors.New("SQLite was built without FTS5; build with -tags sqlite_fts5")
+	}
+	return nil
+}
+
+func newMigrator(db *sqlx.DB, fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations reads files named <version>_<name>.<up|down>.sql and pairs
+// them by version. Every version must provide both directions.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		versionPart, name, ok := strings.Cut(base, "_")
+		if !ok || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, name)
+		}
+
+		if direction == ".up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d (%s) must have both up and down scripts", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`
+
+	_, err := m.db.ExecContext(ctx, query)
+	return err
+}
+
+// applied returns the recorded migrations after checking that each of them
+// is still known to this binary and unchanged since it was applied.
+func (m *Migrator) applied(ctx context.Context) (map[int]appliedMigration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows := []appliedMigration{}
+	query := `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`
+	if err := m.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		migration, ok := known[row.Version]
+		if !ok {
+			return nil, fmt.Errorf("database has migration %d (%s) which is unknown to this build", row.Version, row.Name)
+		}
+		if migration.Checksum != row.Checksum {
+			return nil, fmt.Errorf("checksum mismatch for migration %d (%s): applied file was modified", row.Version, row.Name)
+		}
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+// Up applies every pending migration in version order. Each migration runs
+// in its own transaction together with its schema_migrations record.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ran := []Migration{}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.run(ctx, migration.Up, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+				migration.Version, migration.Name, migration.Checksum, time.Now().UTC(),
+			)
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("failed to apply migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+// Down reverts the most recently applied migration. It returns nil when
+// there is nothing left to revert.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.run(ctx, migration.Down, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to revert migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		return &migration, nil
+	}
+
+	return nil, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = MigrationStatus{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) run(ctx context.Context, script string, record func(tx *sqlx.Tx) error) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := record(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
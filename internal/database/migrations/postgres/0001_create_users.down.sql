@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_last_login;
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_users_role;
+DROP INDEX IF EXISTS idx_users_email;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_expenses_deleted_at;
+DROP INDEX IF EXISTS idx_expenses_created_at;
+DROP INDEX IF EXISTS idx_expenses_category_id;
+DROP INDEX IF EXISTS idx_expenses_user_id;
+DROP TABLE IF EXISTS expenses;
@@ -0,0 +1,18 @@
+-- Expenses table for user-specific expense records
+CREATE TABLE IF NOT EXISTS expenses (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	category_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL,
+	description TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMPTZ,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_user_id ON expenses (user_id);
+CREATE INDEX IF NOT EXISTS idx_expenses_category_id ON expenses (category_id);
+CREATE INDEX IF NOT EXISTS idx_expenses_created_at ON expenses (created_at);
+CREATE INDEX IF NOT EXISTS idx_expenses_deleted_at ON expenses (deleted_at);
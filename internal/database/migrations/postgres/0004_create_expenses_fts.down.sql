@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_expenses_description_tsv;
+ALTER TABLE expenses DROP COLUMN IF EXISTS description_tsv;
@@ -0,0 +1,5 @@
+-- Full-text index over expense descriptions, kept in sync as a generated column
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS description_tsv tsvector
+	GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(description, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_description_tsv ON expenses USING GIN (description_tsv);
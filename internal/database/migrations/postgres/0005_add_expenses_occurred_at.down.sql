@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_expenses_occurred_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS occurred_at;
@@ -0,0 +1,10 @@
+-- When the expense happened, as opposed to when it was recorded. Existing
+-- rows are backfilled from created_at.
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS occurred_at TIMESTAMPTZ;
+
+UPDATE expenses SET occurred_at = created_at WHERE occurred_at IS NULL;
+
+ALTER TABLE expenses ALTER COLUMN occurred_at SET NOT NULL;
+ALTER TABLE expenses ALTER COLUMN occurred_at SET DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_occurred_at ON expenses (occurred_at);
@@ -0,0 +1,14 @@
+-- Amounts are stored in the minor unit of their currency. Rows recorded
+-- before currencies were tracked default to PHP.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS base_currency TEXT NOT NULL DEFAULT 'PHP';
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'PHP';
+
+-- Latest known rate per currency pair: one unit of base_currency costs
+-- rate units of quote_currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+	base_currency TEXT NOT NULL,
+	quote_currency TEXT NOT NULL,
+	rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (base_currency, quote_currency)
+);
@@ -0,0 +1,12 @@
+-- Keep only the most recent rate of each pair
+DROP INDEX IF EXISTS idx_exchange_rates_rate_date;
+
+DELETE FROM exchange_rates r
+USING exchange_rates newer
+WHERE newer.base_currency = r.base_currency
+AND newer.quote_currency = r.quote_currency
+AND newer.rate_date > r.rate_date;
+
+ALTER TABLE exchange_rates DROP CONSTRAINT IF EXISTS exchange_rates_pkey;
+ALTER TABLE exchange_rates ADD PRIMARY KEY (base_currency, quote_currency);
+ALTER TABLE exchange_rates DROP COLUMN IF EXISTS rate_date;
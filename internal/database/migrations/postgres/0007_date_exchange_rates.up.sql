@@ -0,0 +1,12 @@
+-- Keep a rate per day rather than only the latest, so conversions can use
+-- the rate in effect when an expense occurred. Existing rates are dated by
+-- when they were last updated.
+ALTER TABLE exchange_rates ADD COLUMN IF NOT EXISTS rate_date DATE;
+
+UPDATE exchange_rates SET rate_date = CAST(updated_at AS DATE) WHERE rate_date IS NULL;
+
+ALTER TABLE exchange_rates ALTER COLUMN rate_date SET NOT NULL;
+ALTER TABLE exchange_rates DROP CONSTRAINT IF EXISTS exchange_rates_pkey;
+ALTER TABLE exchange_rates ADD PRIMARY KEY (base_currency, quote_currency, rate_date);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_rate_date ON exchange_rates (rate_date);
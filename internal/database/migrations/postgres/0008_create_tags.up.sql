@@ -0,0 +1,20 @@
+-- User-scoped tags, attached to any number of expenses
+CREATE TABLE IF NOT EXISTS tags (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	name TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT unique_user_tag UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS expense_tags (
+	expense_id BIGINT NOT NULL,
+	tag_id BIGINT NOT NULL,
+	PRIMARY KEY (expense_id, tag_id),
+	CONSTRAINT fk_expense FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+	CONSTRAINT fk_tag FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_tags_tag_id ON expense_tags (tag_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_budgets_user_overall;
+DROP INDEX IF EXISTS idx_budgets_user_category;
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_expenses_recurrence;
+ALTER TABLE expenses DROP COLUMN IF EXISTS recurring_expense_id;
+
+DROP INDEX IF EXISTS idx_recurring_expenses_next_occurrence_at;
+DROP INDEX IF EXISTS idx_recurring_expenses_user_id;
+DROP TABLE IF EXISTS recurring_expenses;
@@ -0,0 +1,28 @@
+-- Rules that generate expenses on a schedule. next_occurrence_at is the
+-- next one still to be generated, NULL once the schedule has ended.
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	category_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL,
+	currency TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	rrule TEXT NOT NULL,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ,
+	last_occurrence_at TIMESTAMPTZ,
+	next_occurrence_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_user_id ON recurring_expenses (user_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_next_occurrence_at ON recurring_expenses (next_occurrence_at);
+
+-- Generated expenses point back at their rule. The unique index makes
+-- generating the same occurrence twice a no-op.
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_expense_id BIGINT REFERENCES recurring_expenses(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expenses_recurrence ON expenses (recurring_expense_id, occurred_at);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_incomes_wallet_id;
+DROP INDEX IF EXISTS idx_expenses_wallet_id;
+
+ALTER TABLE recurring_expenses DROP COLUMN IF EXISTS wallet_id;
+ALTER TABLE incomes DROP COLUMN IF EXISTS wallet_id;
+ALTER TABLE expenses DROP COLUMN IF EXISTS wallet_id;
+
+DROP INDEX IF EXISTS idx_wallets_user_name;
+DROP TABLE IF EXISTS wallets;
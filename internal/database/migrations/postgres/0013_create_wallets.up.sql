@@ -0,0 +1,24 @@
+-- Where money is kept or paid from, e.g. Cash, GCash or a credit card.
+-- opening_balance is in the minor unit of currency; the current balance is
+-- derived from it and the entries recorded against the wallet.
+CREATE TABLE IF NOT EXISTS wallets (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	name TEXT NOT NULL,
+	kind TEXT NOT NULL CHECK (kind IN ('cash', 'bank', 'ewallet', 'credit_card', 'other')),
+	currency TEXT NOT NULL,
+	opening_balance BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallets_user_name ON wallets (user_id, name);
+
+-- Optional wallet an entry was paid from or into
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS wallet_id BIGINT REFERENCES wallets(id);
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS wallet_id BIGINT REFERENCES wallets(id);
+ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS wallet_id BIGINT REFERENCES wallets(id);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_wallet_id ON expenses (wallet_id);
+CREATE INDEX IF NOT EXISTS idx_incomes_wallet_id ON incomes (wallet_id);
@@ -0,0 +1,26 @@
+-- Money moved between two wallets of a user. amount and fee leave the source
+-- wallet in its currency, to_amount reaches the destination in its own, so
+-- transfers between currencies record the rate actually applied. Transfers
+-- are neither expenses nor incomes.
+CREATE TABLE IF NOT EXISTS transfers (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	from_wallet_id BIGINT NOT NULL,
+	to_wallet_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL,
+	to_amount BIGINT NOT NULL,
+	fee BIGINT NOT NULL DEFAULT 0,
+	description TEXT NOT NULL DEFAULT '',
+	occurred_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_from_wallet FOREIGN KEY (from_wallet_id) REFERENCES wallets(id),
+	CONSTRAINT fk_to_wallet FOREIGN KEY (to_wallet_id) REFERENCES wallets(id),
+	CHECK (from_wallet_id <> to_wallet_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_user_id ON transfers (user_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_from_wallet_id ON transfers (from_wallet_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_to_wallet_id ON transfers (to_wallet_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_occurred_at ON transfers (occurred_at);
@@ -0,0 +1,16 @@
+-- Lines of an expense split across categories, e.g. a grocery receipt with
+-- food and household items. Amounts are in the minor unit of the expense's
+-- currency and add up to its amount. An expense without lines is attributed
+-- to its own category.
+CREATE TABLE IF NOT EXISTS expense_splits (
+	id BIGSERIAL PRIMARY KEY,
+	expense_id BIGINT NOT NULL,
+	category_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	CONSTRAINT fk_expense FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+	CONSTRAINT fk_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_splits_expense_id ON expense_splits (expense_id);
+CREATE INDEX IF NOT EXISTS idx_expense_splits_category_id ON expense_splits (category_id);
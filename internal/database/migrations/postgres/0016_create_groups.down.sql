@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_expenses_group_id;
+ALTER TABLE expenses DROP COLUMN IF EXISTS group_id;
+
+DROP TABLE IF EXISTS group_invitations;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
@@ -0,0 +1,43 @@
+-- Households and other groups of users who look at their spending together.
+-- Owners manage the group, members share their own expenses with it and
+-- viewers only see what was shared.
+CREATE TABLE IF NOT EXISTS groups (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+	group_id BIGINT NOT NULL,
+	user_id BIGINT NOT NULL,
+	role TEXT NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (group_id, user_id),
+	CONSTRAINT fk_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members (user_id);
+
+-- Pending invitations, addressed by email so people can be invited before
+-- they sign up. The email is stored in lower case.
+CREATE TABLE IF NOT EXISTS group_invitations (
+	id BIGSERIAL PRIMARY KEY,
+	group_id BIGINT NOT NULL,
+	email TEXT NOT NULL,
+	role TEXT NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+	invited_by BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+	CONSTRAINT fk_invited_by FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_invitations_group_email ON group_invitations (group_id, email);
+CREATE INDEX IF NOT EXISTS idx_group_invitations_email ON group_invitations (email);
+
+-- Group an expense is shared with, if any
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS group_id BIGINT REFERENCES groups(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_group_id ON expenses (group_id);
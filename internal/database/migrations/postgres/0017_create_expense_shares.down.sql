@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS group_settlements;
+DROP TABLE IF EXISTS expense_shares;
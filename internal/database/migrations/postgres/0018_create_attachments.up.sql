@@ -0,0 +1,18 @@
+-- Receipts and other files attached to expenses. The file itself lives in
+-- the attachment store under the SHA-256 of its content, so the same file
+-- attached twice is stored once and removed with its last attachment.
+CREATE TABLE IF NOT EXISTS attachments (
+	id BIGSERIAL PRIMARY KEY,
+	expense_id BIGINT NOT NULL,
+	user_id BIGINT NOT NULL,
+	sha256 TEXT NOT NULL,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_expense FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_expense_id ON attachments (expense_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256 ON attachments (sha256);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_categories_deleted_at;
+DROP INDEX IF EXISTS idx_categories_created_at;
+DROP INDEX IF EXISTS idx_categories_name;
+DROP INDEX IF EXISTS idx_categories_user_id;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,17 @@
+-- Categories table for user-specific expense categories
+CREATE TABLE IF NOT EXISTS categories (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at DATETIME,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT unique_user_category UNIQUE (user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_user_id ON categories (user_id);
+CREATE INDEX IF NOT EXISTS idx_categories_name ON categories (name);
+CREATE INDEX IF NOT EXISTS idx_categories_created_at ON categories (created_at);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
@@ -0,0 +1,23 @@
+-- Full-text index over expense descriptions, kept in sync by triggers
+CREATE VIRTUAL TABLE IF NOT EXISTS expenses_fts USING fts5(
+	description,
+	content = 'expenses',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS expenses_fts_insert AFTER INSERT ON expenses BEGIN
+	INSERT INTO expenses_fts (rowid, description) VALUES (new.id, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS expenses_fts_delete AFTER DELETE ON expenses BEGIN
+	INSERT INTO expenses_fts (expenses_fts, rowid, description) VALUES ('delete', old.id, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS expenses_fts_update AFTER UPDATE OF description ON expenses BEGIN
+	INSERT INTO expenses_fts (expenses_fts, rowid, description) VALUES ('delete', old.id, old.description);
+	INSERT INTO expenses_fts (rowid, description) VALUES (new.id, new.description);
+END;
+
+-- Index expenses that existed before this migration
+INSERT INTO expenses_fts (expenses_fts) VALUES ('rebuild');
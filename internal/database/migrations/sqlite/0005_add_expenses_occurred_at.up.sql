@@ -0,0 +1,13 @@
+-- When the expense happened, as opposed to when it was recorded. SQLite
+-- cannot add a NOT NULL column without a constant default, so the
+-- repository always writes it. Existing rows are backfilled from
+-- created_at in UTC, formatted the way the Go driver writes timestamps
+-- (fraction without trailing zeros) so values compare correctly as text.
+ALTER TABLE expenses ADD COLUMN occurred_at DATETIME;
+
+UPDATE expenses SET occurred_at =
+	STRFTIME('%Y-%m-%d %H:%M:%S', created_at)
+	|| COALESCE('.' || NULLIF(RTRIM(SUBSTR(STRFTIME('%f', created_at), 4), '0'), ''), '')
+	|| '+00:00';
+
+CREATE INDEX IF NOT EXISTS idx_expenses_occurred_at ON expenses (occurred_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS exchange_rates;
+ALTER TABLE expenses DROP COLUMN currency;
+ALTER TABLE users DROP COLUMN base_currency;
@@ -0,0 +1,21 @@
+-- Keep only the most recent rate of each pair
+CREATE TABLE exchange_rates_latest (
+	base_currency TEXT NOT NULL,
+	quote_currency TEXT NOT NULL,
+	rate REAL NOT NULL CHECK (rate > 0),
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (base_currency, quote_currency)
+);
+
+INSERT INTO exchange_rates_latest (base_currency, quote_currency, rate, updated_at)
+SELECT r.base_currency, r.quote_currency, r.rate, r.updated_at
+FROM exchange_rates r
+WHERE r.rate_date = (
+	SELECT MAX(latest.rate_date)
+	FROM exchange_rates latest
+	WHERE latest.base_currency = r.base_currency
+	AND latest.quote_currency = r.quote_currency
+);
+
+DROP TABLE exchange_rates;
+ALTER TABLE exchange_rates_latest RENAME TO exchange_rates;
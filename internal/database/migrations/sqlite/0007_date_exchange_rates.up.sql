@@ -0,0 +1,20 @@
+-- Keep a rate per day rather than only the latest, so conversions can use
+-- the rate in effect when an expense occurred. Existing rates are dated by
+-- when they were last updated.
+CREATE TABLE exchange_rates_dated (
+	rate_date DATE NOT NULL,
+	base_currency TEXT NOT NULL,
+	quote_currency TEXT NOT NULL,
+	rate REAL NOT NULL CHECK (rate > 0),
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (base_currency, quote_currency, rate_date)
+);
+
+INSERT INTO exchange_rates_dated (rate_date, base_currency, quote_currency, rate, updated_at)
+SELECT DATE(updated_at), base_currency, quote_currency, rate, updated_at
+FROM exchange_rates;
+
+DROP TABLE exchange_rates;
+ALTER TABLE exchange_rates_dated RENAME TO exchange_rates;
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_rate_date ON exchange_rates (rate_date);
@@ -0,0 +1,5 @@
+-- Optional parent for nesting categories, e.g. Transport > Fuel. The API
+-- checks the parent exists and guards against cycles.
+ALTER TABLE categories ADD COLUMN parent_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
@@ -0,0 +1,18 @@
+-- Spending limits per category, or for all spending when category_id is
+-- NULL. Amount is in the minor unit of currency and resets every period.
+CREATE TABLE IF NOT EXISTS budgets (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	category_id INTEGER,
+	period TEXT NOT NULL CHECK (period IN ('week', 'month', 'year')),
+	amount INTEGER NOT NULL,
+	currency TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+-- One budget per category, and one overall budget, per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_category ON budgets (user_id, category_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_overall ON budgets (user_id) WHERE category_id IS NULL;
@@ -0,0 +1,28 @@
+-- Rules that generate expenses on a schedule. next_occurrence_at is the
+-- next one still to be generated, NULL once the schedule has ended.
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	category_id INTEGER NOT NULL,
+	amount INTEGER NOT NULL,
+	currency TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	rrule TEXT NOT NULL,
+	starts_at DATETIME NOT NULL,
+	ends_at DATETIME,
+	last_occurrence_at DATETIME,
+	next_occurrence_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_user_id ON recurring_expenses (user_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_next_occurrence_at ON recurring_expenses (next_occurrence_at);
+
+-- Generated expenses point back at their rule. The unique index makes
+-- generating the same occurrence twice a no-op.
+ALTER TABLE expenses ADD COLUMN recurring_expense_id INTEGER;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expenses_recurrence ON expenses (recurring_expense_id, occurred_at);
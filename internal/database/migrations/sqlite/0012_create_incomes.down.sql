@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS incomes;
+
+DROP INDEX IF EXISTS idx_categories_kind;
+ALTER TABLE categories DROP COLUMN kind;
@@ -0,0 +1,26 @@
+-- Categories are either for expenses or for incomes. Existing ones keep
+-- categorizing expenses.
+ALTER TABLE categories ADD COLUMN kind TEXT NOT NULL DEFAULT 'expense' CHECK (kind IN ('expense', 'income'));
+
+CREATE INDEX IF NOT EXISTS idx_categories_kind ON categories (kind);
+
+-- Money coming in, recorded like expenses in the minor unit of currency
+CREATE TABLE IF NOT EXISTS incomes (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	category_id INTEGER NOT NULL,
+	amount INTEGER NOT NULL,
+	currency TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	occurred_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at DATETIME,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_incomes_user_id ON incomes (user_id);
+CREATE INDEX IF NOT EXISTS idx_incomes_category_id ON incomes (category_id);
+CREATE INDEX IF NOT EXISTS idx_incomes_occurred_at ON incomes (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_incomes_deleted_at ON incomes (deleted_at);
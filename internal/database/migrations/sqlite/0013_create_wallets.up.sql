@@ -0,0 +1,25 @@
+-- Where money is kept or paid from, e.g. Cash, GCash or a credit card.
+-- opening_balance is in the minor unit of currency; the current balance is
+-- derived from it and the entries recorded against the wallet.
+CREATE TABLE IF NOT EXISTS wallets (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	kind TEXT NOT NULL CHECK (kind IN ('cash', 'bank', 'ewallet', 'credit_card', 'other')),
+	currency TEXT NOT NULL,
+	opening_balance INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallets_user_name ON wallets (user_id, name);
+
+-- Optional wallet an entry was paid from or into. A wallet is only deleted
+-- once no entry names it.
+ALTER TABLE expenses ADD COLUMN wallet_id INTEGER;
+ALTER TABLE incomes ADD COLUMN wallet_id INTEGER;
+ALTER TABLE recurring_expenses ADD COLUMN wallet_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_wallet_id ON expenses (wallet_id);
+CREATE INDEX IF NOT EXISTS idx_incomes_wallet_id ON incomes (wallet_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transfers_occurred_at;
+DROP INDEX IF EXISTS idx_transfers_to_wallet_id;
+DROP INDEX IF EXISTS idx_transfers_from_wallet_id;
+DROP INDEX IF EXISTS idx_transfers_user_id;
+DROP TABLE IF EXISTS transfers;
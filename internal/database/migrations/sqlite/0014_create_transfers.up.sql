@@ -0,0 +1,26 @@
+-- Money moved between two wallets of a user. amount and fee leave the source
+-- wallet in its currency, to_amount reaches the destination in its own, so
+-- transfers between currencies record the rate actually applied. Transfers
+-- are neither expenses nor incomes.
+CREATE TABLE IF NOT EXISTS transfers (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	from_wallet_id INTEGER NOT NULL,
+	to_wallet_id INTEGER NOT NULL,
+	amount INTEGER NOT NULL,
+	to_amount INTEGER NOT NULL,
+	fee INTEGER NOT NULL DEFAULT 0,
+	description TEXT NOT NULL DEFAULT '',
+	occurred_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_from_wallet FOREIGN KEY (from_wallet_id) REFERENCES wallets(id),
+	CONSTRAINT fk_to_wallet FOREIGN KEY (to_wallet_id) REFERENCES wallets(id),
+	CHECK (from_wallet_id <> to_wallet_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_user_id ON transfers (user_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_from_wallet_id ON transfers (from_wallet_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_to_wallet_id ON transfers (to_wallet_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_occurred_at ON transfers (occurred_at);
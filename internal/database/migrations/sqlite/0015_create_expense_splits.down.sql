@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_expense_splits_category_id;
+DROP INDEX IF EXISTS idx_expense_splits_expense_id;
+DROP TABLE IF EXISTS expense_splits;
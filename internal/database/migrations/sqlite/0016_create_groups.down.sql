@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_expenses_group_id;
+ALTER TABLE expenses DROP COLUMN group_id;
+
+DROP INDEX IF EXISTS idx_group_invitations_email;
+DROP INDEX IF EXISTS idx_group_invitations_group_email;
+DROP TABLE IF EXISTS group_invitations;
+
+DROP INDEX IF EXISTS idx_group_members_user_id;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
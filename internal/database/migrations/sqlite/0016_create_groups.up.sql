@@ -0,0 +1,44 @@
+-- Households and other groups of users who look at their spending together.
+-- Owners manage the group, members share their own expenses with it and
+-- viewers only see what was shared.
+CREATE TABLE IF NOT EXISTS groups (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+	group_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	role TEXT NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (group_id, user_id),
+	CONSTRAINT fk_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members (user_id);
+
+-- Pending invitations, addressed by email so people can be invited before
+-- they sign up. The email is stored in lower case.
+CREATE TABLE IF NOT EXISTS group_invitations (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	group_id INTEGER NOT NULL,
+	email TEXT NOT NULL,
+	role TEXT NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+	invited_by INTEGER NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+	CONSTRAINT fk_invited_by FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_invitations_group_email ON group_invitations (group_id, email);
+CREATE INDEX IF NOT EXISTS idx_group_invitations_email ON group_invitations (email);
+
+-- Group an expense is shared with, if any. Deleting the group makes its
+-- expenses private again.
+ALTER TABLE expenses ADD COLUMN group_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_group_id ON expenses (group_id);
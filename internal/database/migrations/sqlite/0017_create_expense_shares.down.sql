@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_group_settlements_group_id;
+DROP TABLE IF EXISTS group_settlements;
+
+DROP INDEX IF EXISTS idx_expense_shares_user_id;
+DROP INDEX IF EXISTS idx_expense_shares_expense_user;
+DROP TABLE IF EXISTS expense_shares;
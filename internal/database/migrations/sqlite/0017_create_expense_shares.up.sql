@@ -0,0 +1,39 @@
+-- Shares of a bill paid by one member of a group and owed by several. The
+-- payer is the user who recorded the expense, who usually has a share too.
+-- Amounts are in the minor unit of the expense's currency and add up to its
+-- amount. mode records how the bill was divided and percent the percentage
+-- a share was given as.
+CREATE TABLE IF NOT EXISTS expense_shares (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	expense_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	mode TEXT NOT NULL CHECK (mode IN ('equal', 'exact', 'percent')),
+	percent REAL,
+	CONSTRAINT fk_expense FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expense_shares_expense_user ON expense_shares (expense_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_expense_shares_user_id ON expense_shares (user_id);
+
+-- Payments between members that settle what they owe each other in a group
+CREATE TABLE IF NOT EXISTS group_settlements (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	group_id INTEGER NOT NULL,
+	from_user_id INTEGER NOT NULL,
+	to_user_id INTEGER NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount > 0),
+	currency TEXT NOT NULL,
+	note TEXT NOT NULL DEFAULT '',
+	occurred_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_by INTEGER NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CHECK (from_user_id <> to_user_id),
+	CONSTRAINT fk_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+	CONSTRAINT fk_from_user FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_to_user FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_created_by FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_settlements_group_id ON group_settlements (group_id, occurred_at);
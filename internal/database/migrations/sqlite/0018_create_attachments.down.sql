@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_attachments_sha256;
+DROP INDEX IF EXISTS idx_attachments_expense_id;
+DROP TABLE IF EXISTS attachments;
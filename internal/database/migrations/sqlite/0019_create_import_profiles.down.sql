@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_import_profiles_user_name;
+DROP TABLE IF EXISTS import_profiles;
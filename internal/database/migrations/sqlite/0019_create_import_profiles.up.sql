@@ -0,0 +1,24 @@
+-- Saved layouts of bank and e-wallet CSV statements: which columns hold the
+-- date, amount and description and how to read them. Imported rows become
+-- expenses in currency, paid from wallet_id when set.
+CREATE TABLE IF NOT EXISTS import_profiles (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	delimiter TEXT NOT NULL DEFAULT ',',
+	has_header BOOLEAN NOT NULL DEFAULT TRUE,
+	date_column TEXT NOT NULL,
+	amount_column TEXT NOT NULL,
+	description_column TEXT NOT NULL DEFAULT '',
+	date_format TEXT NOT NULL DEFAULT 'YYYY-MM-DD',
+	expense_sign TEXT NOT NULL DEFAULT 'negative' CHECK (expense_sign IN ('negative', 'positive')),
+	decimal_separator TEXT NOT NULL DEFAULT '.',
+	currency TEXT NOT NULL,
+	wallet_id INTEGER,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_import_profiles_user_name ON import_profiles (user_id, name);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_imported_transactions_expense_id;
+DROP INDEX IF EXISTS idx_imported_transactions_fitid;
+DROP TABLE IF EXISTS imported_transactions;
@@ -0,0 +1,17 @@
+-- Expenses imported from OFX statements, by the FITID the bank gave the
+-- transaction, so importing the same statement again skips them. account
+-- is a hash of the statement's bank and account numbers, as FITIDs are only
+-- unique within an account.
+CREATE TABLE IF NOT EXISTS imported_transactions (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	expense_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	account TEXT NOT NULL,
+	fitid TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_expense FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_imported_transactions_fitid ON imported_transactions (user_id, account, fitid);
+CREATE INDEX IF NOT EXISTS idx_imported_transactions_expense_id ON imported_transactions (expense_id);
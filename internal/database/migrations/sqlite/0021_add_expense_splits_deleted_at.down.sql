@@ -0,0 +1 @@
+ALTER TABLE expense_splits DROP COLUMN deleted_at;
@@ -0,0 +1,4 @@
+-- Set on the lines a category's deletion took off a split expense, so
+-- restoring the category brings them back. The expense keeps its amount and
+-- shares meanwhile.
+ALTER TABLE expense_splits ADD COLUMN deleted_at DATETIME;
@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RecurringExpense generates an expense on every occurrence of its RRULE,
+// starting at StartsAt and stopping after EndsAt when set.
+type RecurringExpense struct {
+	ID         int64 `db:"id"`
+	UserID     int64 `db:"user_id"`
+	CategoryID int64 `db:"category_id"`
+	// Amount is in the minor unit of Currency
+	Amount      int64  `db:"amount"`
+	Currency    string `db:"currency"`
+	Description string `db:"description"`
+	RRule       string `db:"rrule"`
+	// WalletID is the wallet generated expenses are paid from, if any
+	WalletID sql.NullInt64 `db:"wallet_id"`
+	StartsAt time.Time     `db:"starts_at"`
+	EndsAt   *time.Time    `db:"ends_at"`
+	// LastOccurrenceAt is the latest occurrence generated so far
+	LastOccurrenceAt *time.Time `db:"last_occurrence_at"`
+	// NextOccurrenceAt is the next occurrence to generate, nil once the
+	// schedule has ended
+	NextOccurrenceAt *time.Time `db:"next_occurrence_at"`
+	CreatedAt        time.Time  `db:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at"`
+}
+
+type RecurringExpenseRepository interface {
+	Create(ctx context.Context, input NewRecurringExpenseInput) (*RecurringExpense, error)
+	GetByID(ctx context.Context, id int64) (*RecurringExpense, error)
+	Update(ctx context.Context, input UpdateRecurringExpenseInput) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, userID int64) ([]RecurringExpense, error)
+	ExistWithUserID(ctx context.Context, input ExistRecurringExpenseWithUserIDInput) (bool, error)
+	ListDue(ctx context.Context, now time.Time, limit int) ([]RecurringExpense, error)
+	Advance(ctx context.Context, id int64, lastOccurrenceAt, nextOccurrenceAt *time.Time) error
+}
+
+type recurringExpenseRepository struct {
+	db DBTX
+}
+
+func NewRecurringExpenseRepository(db DBTX) RecurringExpenseRepository {
+	return &recurringExpenseRepository{db: db}
+}
+
+// utcOrNil keeps nullable timestamps in UTC, like occurred_at, so SQLite
+// compares them correctly as text.
+func utcOrNil(at *time.Time) *time.Time {
+	if at == nil {
+		return nil
+	}
+	utc := at.UTC()
+	return &utc
+}
+
+type NewRecurringExpenseInput struct {
+	UserID     int64
+	CategoryID int64
+	// Amount is in the minor unit of Currency
+	Amount           int64
+	Currency         string
+	Description      string
+	RRule            string
+	StartsAt         time.Time
+	EndsAt           *time.Time
+	NextOccurrenceAt *time.Time
+	WalletID         sql.NullInt64
+}
+
+func (r *recurringExpenseRepository) Create(ctx context.Context, input NewRecurringExpenseInput) (*RecurringExpense, error) {
+	query := `
+		INSERT INTO recurring_expenses (user_id, category_id, amount, currency, description, rrule, starts_at, ends_at, next_occurrence_at, wallet_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`
+
+	now := time.Now()
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query,
+		input.UserID, input.CategoryID, input.Amount, input.Currency, input.Description, input.RRule,
+		input.StartsAt.UTC(), utcOrNil(input.EndsAt), utcOrNil(input.NextOccurrenceAt), input.WalletID, now, now,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *recurringExpenseRepository) GetByID(ctx context.Context, id int64) (*RecurringExpense, error) {
+	var recurringExpense RecurringExpense
+	query := `SELECT * FROM recurring_expenses WHERE id = $1`
+	err := r.db.GetContext(ctx, &recurringExpense, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("recurring expense not found")
+		}
+		return nil, err
+	}
+	return &recurringExpense, nil
+}
+
+type UpdateRecurringExpenseInput struct {
+	RecurringExpenseID int64
+	UserID             int64
+	CategoryID         int64
+	// Amount is in the minor unit of Currency
+	Amount           int64
+	Currency         string
+	Description      string
+	RRule            string
+	StartsAt         time.Time
+	EndsAt           *time.Time
+	NextOccurrenceAt *time.Time
+	WalletID         sql.NullInt64
+}
+
+func (r *recurringExpenseRepository) Update(ctx context.Context, input UpdateRecurringExpenseInput) error {
+	query := `
+		UPDATE recurring_expenses
+		SET category_id = $1,
+			amount = $2,
+			currency = $3,
+			description = $4,
+			rrule = $5,
+			starts_at = $6,
+			ends_at = $7,
+			next_occurrence_at = $8,
+			wallet_id = $9,
+			updated_at = $10
+		WHERE id = $11 AND user_id = $12`
+
+	_, err := r.db.ExecContext(ctx, query,
+		input.CategoryID, input.Amount, input.Currency, input.Description, input.RRule,
+		input.StartsAt.UTC(), utcOrNil(input.EndsAt), utcOrNil(input.NextOccurrenceAt), input.WalletID, time.Now(),
+		input.RecurringExpenseID, input.UserID,
+	)
+	return err
+}
+
+// Delete removes the rule. Expenses it already generated are kept and
+// detached from it.
+func (r *recurringExpenseRepository) Delete(ctx context.Context, id int64) error {
+	// SQLite does not enforce the foreign key, so detach explicitly
+	if _, err := r.db.ExecContext(ctx, `UPDATE expenses SET recurring_expense_id = NULL WHERE recurring_expense_id = $1`, id); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM recurring_expenses WHERE id = $1`, id)
+	return err
+}
+
+func (r *recurringExpenseRepository) List(ctx context.Context, userID int64) ([]RecurringExpense, error) {
+	recurringExpenses := []RecurringExpense{}
+	query := `
+		SELECT *
+		FROM recurring_expenses
+		WHERE user_id = $1
+		ORDER BY next_occurrence_at IS NULL, next_occurrence_at, id
+	`
+
+	if err := r.db.SelectContext(ctx, &recurringExpenses, query, userID); err != nil {
+		return nil, err
+	}
+
+	return recurringExpenses, nil
+}
+
+type ExistRecurringExpenseWithUserIDInput struct {
+	UserID             int64 `doc:"User ID"`
+	RecurringExpenseID int64 `doc:"Recurring expense ID"`
+}
+
+func (r *recurringExpenseRepository) ExistWithUserID(ctx context.Context, input ExistRecurringExpenseWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM recurring_expenses
+		WHERE id = $1
+		AND user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.RecurringExpenseID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// ListDue returns up to limit rules with an occurrence at or before now,
+// most overdue first. Rules on deleted categories wait until the category
+// is restored.
+func (r *recurringExpenseRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]RecurringExpense, error) {
+	recurringExpenses := []RecurringExpense{}
+	query := `
+		SELECT recurring_expenses.*
+		FROM recurring_expenses
+		INNER JOIN categories ON categories.id = recurring_expenses.category_id
+			AND categories.deleted_at IS NULL
+		WHERE recurring_expenses.next_occurrence_at <= $1
+		ORDER BY recurring_expenses.next_occurrence_at, recurring_expenses.id
+		LIMIT $2
+	`
+
+	if err := r.db.SelectContext(ctx, &recurringExpenses, query, now.UTC(), limit); err != nil {
+		return nil, err
+	}
+
+	return recurringExpenses, nil
+}
+
+// Advance records that every occurrence up to lastOccurrenceAt has been
+// generated and moves the rule on to nextOccurrenceAt. A nil
+// lastOccurrenceAt keeps the current one.
+func (r *recurringExpenseRepository) Advance(ctx context.Context, id int64, lastOccurrenceAt, nextOccurrenceAt *time.Time) error {
+	query := `
+		UPDATE recurring_expenses
+		SET last_occurrence_at = COALESCE($1, last_occurrence_at),
+			next_occurrence_at = $2,
+			updated_at = $3
+		WHERE id = $4`
+
+	_, err := r.db.ExecContext(ctx, query, utcOrNil(lastOccurrenceAt), utcOrNil(nextOccurrenceAt), time.Now(), id)
+	return err
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	snippetStart = "<mark>"
+	snippetEnd   = "</mark>"
+)
+
+// searchTerms splits free text into words, dropping punctuation so user
+// input can never be interpreted as full-text query syntax.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// fullTextQuery turns search terms into a prefix query where every term
+// must match, e.g. "grab ri" matches "Grab ride to the airport".
+func (d Dialect) fullTextQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		if d == DialectPostgres {
+			parts[i] = term + ":*"
+		} else {
+			parts[i] = `"` + term + `"*`
+		}
+	}
+
+	if d == DialectPostgres {
+		return strings.Join(parts, " & ")
+	}
+	return strings.Join(parts, " ")
+}
+
+type expenseSearch struct {
+	join      string
+	condition string
+	snippet   string
+	rank      string
+}
+
+// expenseSearch returns the SQL fragments that match expenses against the
+// full-text query bound to placeholder argIndex. rank sorts best matches
+// first when used in ascending order.
+func (d Dialect) expenseSearch(argIndex int) expenseSearch {
+	if d == DialectPostgres {
+		query := fmt.Sprintf("to_tsquery('simple', $%d)", argIndex)
+		return expenseSearch{
+			condition: fmt.Sprintf("expenses.description_tsv @@ %s", query),
+			snippet: fmt.Sprintf("ts_headline('simple', COALESCE(expenses.description, ''), %s, 'StartSel=%s, StopSel=%s, MaxFragments=1, MaxWords=12, MinWords=3')",
+				query, snippetStart, snippetEnd),
+			rank: fmt.Sprintf("-ts_rank(expenses.description_tsv, %s)", query),
+		}
+	}
+
+	return expenseSearch{
+		join:      "JOIN expenses_fts ON expenses_fts.rowid = expenses.id",
+		condition: fmt.Sprintf("expenses_fts MATCH $%d", argIndex),
+		snippet:   fmt.Sprintf("snippet(expenses_fts, 0, '%s', '%s', '…', 12)", snippetStart, snippetEnd),
+		rank:      "bm25(expenses_fts)",
+	}
+}
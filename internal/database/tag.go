@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var ErrInvalidTag = errors.New("tag names may only contain letters, digits, '-' and '_'")
+
+const maxTagLength = 50
+
+type Tag struct {
+	ID        int64     `db:"id"`
+	UserID    int64     `db:"user_id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// NormalizeTagName lowercases a tag and drops a leading '#', so "#Work-Trip"
+// and "work-trip" are the same tag.
+func NormalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" || len(name) > maxTagLength {
+		return "", ErrInvalidTag
+	}
+
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", ErrInvalidTag
+		}
+	}
+
+	return name, nil
+}
+
+// NormalizeTagNames normalizes every name and drops duplicates, keeping the
+// order they were given in.
+func NormalizeTagNames(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		tag, err := NormalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+type TagRepository interface {
+	List(ctx context.Context, userID int64) ([]Tag, error)
+	Ensure(ctx context.Context, userID int64, names []string) ([]Tag, error)
+	SetExpenseTags(ctx context.Context, expenseID int64, tagIDs []int64) error
+}
+
+type tagRepository struct {
+	db DBTX
+}
+
+func NewTagRepository(db DBTX) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) List(ctx context.Context, userID int64) ([]Tag, error) {
+	tags := []Tag{}
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			created_at,
+			updated_at
+		FROM tags
+		WHERE user_id = $1
+		ORDER BY name ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &tags, query, userID); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Ensure returns the user's tags with the given normalized names, creating
+// the ones that do not exist yet.
+func (r *tagRepository) Ensure(ctx context.Context, userID int64, names []string) ([]Tag, error) {
+	tags := []Tag{}
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	now := time.Now()
+	insertQuery := `
+		INSERT INTO tags (user_id, name, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id, name) DO NOTHING`
+
+	for _, name := range names {
+		if _, err := r.db.ExecContext(ctx, insertQuery, userID, name, now); err != nil {
+			return nil, err
+		}
+	}
+
+	args := []interface{}{userID}
+	placeholders := make([]string, len(names))
+	for i, name := range names {
+		placeholders[i] = fmt.Sprintf("$%d", len(args)+1)
+		args = append(args, name)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id,
+			user_id,
+			name,
+			created_at,
+			updated_at
+		FROM tags
+		WHERE user_id = $1
+		AND name IN (%s)
+		ORDER BY name ASC
+	`, strings.Join(placeholders, ","))
+
+	if err := r.db.SelectContext(ctx, &tags, query, args...); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// SetExpenseTags replaces the tags attached to an expense.
+func (r *tagRepository) SetExpenseTags(ctx context.Context, expenseID int64, tagIDs []int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM expense_tags WHERE expense_id = $1`, expenseID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO expense_tags (expense_id, tag_id) VALUES ($1, $2)`
+	for _, tagID := range tagIDs {
+		if _, err := r.db.ExecContext(ctx, query, expenseID, tagID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Transfer moves money between two wallets of a user. It is neither an
+// expense nor an income, so it never shows up in spending or cash flow.
+type Transfer struct {
+	ID           int64 `db:"id"`
+	UserID       int64 `db:"user_id"`
+	FromWalletID int64 `db:"from_wallet_id"`
+	ToWalletID   int64 `db:"to_wallet_id"`
+	// Amount and Fee leave the source wallet in its currency. ToAmount
+	// reaches the destination wallet in its own currency.
+	Amount      int64     `db:"amount"`
+	ToAmount    int64     `db:"to_amount"`
+	Fee         int64     `db:"fee"`
+	Description string    `db:"description"`
+	OccurredAt  time.Time `db:"occurred_at"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+
+	FromWalletName     string `db:"from_wallet_name"`
+	FromWalletCurrency string `db:"from_wallet_currency"`
+	ToWalletName       string `db:"to_wallet_name"`
+	ToWalletCurrency   string `db:"to_wallet_currency"`
+}
+
+type TransferRepository interface {
+	Create(ctx context.Context, input NewTransferInput) (*Transfer, error)
+	GetByID(ctx context.Context, id int64) (*Transfer, error)
+	Update(ctx context.Context, input UpdateTransferInput) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, input ListTransferInput) (*TransferPage, error)
+	ExistWithUserID(ctx context.Context, input ExistTransferWithUserIDInput) (bool, error)
+}
+
+type transferRepository struct {
+	db DBTX
+}
+
+func NewTransferRepository(db DBTX) TransferRepository {
+	return &transferRepository{db: db}
+}
+
+// transferColumns selects a transfer together with both of its wallets.
+const transferColumns = `
+			transfers.id,
+			transfers.user_id,
+			transfers.from_wallet_id,
+			transfers.to_wallet_id,
+			transfers.amount,
+			transfers.to_amount,
+			transfers.fee,
+			transfers.description,
+			transfers.occurred_at,
+			transfers.created_at,
+			transfers.updated_at,
+			from_wallets.name as from_wallet_name,
+			from_wallets.currency as from_wallet_currency,
+			to_wallets.name as to_wallet_name,
+			to_wallets.currency as to_wallet_currency`
+
+const transferJoins = `
+		JOIN wallets from_wallets ON from_wallets.id = transfers.from_wallet_id
+		JOIN wallets to_wallets ON to_wallets.id = transfers.to_wallet_id`
+
+type NewTransferInput struct {
+	UserID       int64
+	FromWalletID int64
+	ToWalletID   int64
+	Amount       int64
+	ToAmount     int64
+	Fee          int64
+	Description  string
+	OccurredAt   *time.Time
+}
+
+func (r *transferRepository) Create(ctx context.Context, input NewTransferInput) (*Transfer, error) {
+	query := `
+		INSERT INTO transfers (user_id, from_wallet_id, to_wallet_id, amount, to_amount, fee, description, occurred_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`
+
+	now := time.Now()
+	occurredAt := now
+	if input.OccurredAt != nil {
+		occurredAt = *input.OccurredAt
+	}
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query,
+		input.UserID, input.FromWalletID, input.ToWalletID, input.Amount, input.ToAmount, input.Fee, input.Description, occurredAt.UTC(), now, now,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *transferRepository) GetByID(ctx context.Context, id int64) (*Transfer, error) {
+	var transfer Transfer
+	query := `SELECT` + transferColumns + ` FROM transfers` + transferJoins + ` WHERE transfers.id = $1`
+	err := r.db.GetContext(ctx, &transfer, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("transfer not found")
+		}
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+type UpdateTransferInput struct {
+	TransferID   int64
+	UserID       int64
+	FromWalletID int64
+	ToWalletID   int64
+	Amount       int64
+	ToAmount     int64
+	Fee          int64
+	Description  string
+	// OccurredAt is left unchanged when nil
+	OccurredAt *time.Time
+}
+
+func (r *transferRepository) Update(ctx context.Context, input UpdateTransferInput) error {
+	query := `
+		UPDATE transfers
+		SET from_wallet_id = $1,
+			to_wallet_id = $2,
+			amount = $3,
+			to_amount = $4,
+			fee = $5,
+			description = $6,
+			occurred_at = COALESCE($7, occurred_at),
+			updated_at = $8
+		WHERE id = $9 AND user_id = $10`
+
+	_, err := r.db.ExecContext(ctx, query,
+		input.FromWalletID, input.ToWalletID, input.Amount, input.ToAmount, input.Fee, input.Description, utcOrNil(input.OccurredAt), time.Now(),
+		input.TransferID, input.UserID,
+	)
+	return err
+}
+
+func (r *transferRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM transfers WHERE id = $1`, id)
+	return err
+}
+
+type ListTransferInput struct {
+	UserID int64
+	Page   int
+	Limit  int
+	// WalletID lists only transfers from or to that wallet when set
+	WalletID int64
+}
+
+type TransferPage struct {
+	Transfers  []Transfer
+	HasMore    bool
+	TotalCount int64
+}
+
+func (r *transferRepository) List(ctx context.Context, input ListTransferInput) (*TransferPage, error) {
+	defaultLimit := 10
+	if input.Limit == 0 {
+		input.Limit = defaultLimit
+	}
+
+	defaultPage := 1
+	if input.Page == 0 {
+		input.Page = defaultPage
+	}
+
+	args := []interface{}{input.UserID}
+	conditions := []string{"transfers.user_id = $1"}
+
+	if input.WalletID != 0 {
+		conditions = append(conditions, fmt.Sprintf("(transfers.from_wallet_id = $%d OR transfers.to_wallet_id = $%d)", len(args)+1, len(args)+1))
+		args = append(args, input.WalletID)
+	}
+
+	page := &TransferPage{Transfers: []Transfer{}}
+
+	countQuery := `SELECT COUNT(*) FROM transfers WHERE ` + strings.Join(conditions, " AND ")
+	if err := r.db.GetContext(ctx, &page.TotalCount, countQuery, args...); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT%s
+		FROM transfers%s
+		WHERE %s
+		ORDER BY transfers.occurred_at DESC, transfers.id DESC
+		LIMIT $%d OFFSET $%d
+	`, transferColumns, transferJoins, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+
+	// Fetch one extra row to learn whether another page follows
+	offset := (input.Page - 1) * input.Limit
+	args = append(args, input.Limit+1, offset)
+
+	if err := r.db.SelectContext(ctx, &page.Transfers, query, args...); err != nil {
+		return nil, err
+	}
+
+	if len(page.Transfers) > input.Limit {
+		page.Transfers = page.Transfers[:input.Limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+type ExistTransferWithUserIDInput struct {
+	UserID     int64 `doc:"User ID"`
+	TransferID int64 `doc:"Transfer ID"`
+}
+
+func (r *transferRepository) ExistWithUserID(ctx context.Context, input ExistTransferWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM transfers
+		WHERE id = $1
+		AND user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.TransferID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
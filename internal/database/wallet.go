@@ -0,0 +1,384 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Wallet kinds
+const (
+	WalletKindCash       = "cash"
+	WalletKindBank       = "bank"
+	WalletKindEWallet    = "ewallet"
+	WalletKindCreditCard = "credit_card"
+	WalletKindOther      = "other"
+)
+
+// Wallet holds money in one currency. Expenses paid from it and transfers
+// out of it lower its balance, incomes and transfers into it raise it.
+type Wallet struct {
+	ID       int64  `db:"id"`
+	UserID   int64  `db:"user_id"`
+	Name     string `db:"name"`
+	Kind     string `db:"kind"`
+	Currency string `db:"currency"`
+	// OpeningBalance and Balance are in the minor unit of Currency. A credit
+	// card owing money has a negative balance.
+	OpeningBalance int64     `db:"opening_balance"`
+	Balance        int64     `db:"balance"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+type WalletRepository interface {
+	Create(ctx context.Context, input NewWalletInput) (*Wallet, error)
+	GetByID(ctx context.Context, id int64) (*Wallet, error)
+	Update(ctx context.Context, input UpdateWalletInput) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, userID int64) ([]Wallet, error)
+	ExistWithUserID(ctx context.Context, input ExistWalletWithUserIDInput) (bool, error)
+	CountEntries(ctx context.Context, id int64) (int64, error)
+	Ledger(ctx context.Context, input LedgerInput) (*LedgerPage, error)
+}
+
+type walletRepository struct {
+	db DBTX
+}
+
+func NewWalletRepository(db DBTX) WalletRepository {
+	return &walletRepository{db: db}
+}
+
+// walletColumns selects a wallet with its balance after every active entry.
+const walletColumns = `
+			wallets.id,
+			wallets.user_id,
+			wallets.name,
+			wallets.kind,
+			wallets.currency,
+			wallets.opening_balance,
+			wallets.opening_balance
+				+ COALESCE((
+					SELECT SUM(incomes.amount) FROM incomes
+					WHERE incomes.wallet_id = wallets.id AND incomes.deleted_at IS NULL
+				), 0)
+				- COALESCE((
+					SELECT SUM(expenses.amount) FROM expenses
+					WHERE expenses.wallet_id = wallets.id AND expenses.deleted_at IS NULL
+				), 0)
+				+ COALESCE((
+					SELECT SUM(transfers.to_amount) FROM transfers
+					WHERE transfers.to_wallet_id = wallets.id
+				), 0)
+				- COALESCE((
+					SELECT SUM(transfers.amount + transfers.fee) FROM transfers
+					WHERE transfers.from_wallet_id = wallets.id
+				), 0) as balance,
+			wallets.created_at,
+			wallets.updated_at`
+
+// withWallet narrows condition to entries whose column is walletID, unless
+// walletID is 0, and returns the arguments with walletID bound last.
+func withWallet(condition string, column string, walletID int64, args ...interface{}) (string, []interface{}) {
+	if walletID == 0 {
+		return condition, args
+	}
+	return fmt.Sprintf("%s AND %s = $%d", condition, column, len(args)+1), append(args, walletID)
+}
+
+type NewWalletInput struct {
+	UserID   int64
+	Name     string
+	Kind     string
+	Currency string
+	// OpeningBalance is in the minor unit of Currency
+	OpeningBalance int64
+}
+
+func (r *walletRepository) Create(ctx context.Context, input NewWalletInput) (*Wallet, error) {
+	query := `
+		INSERT INTO wallets (user_id, name, kind, currency, opening_balance, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+
+	now := time.Now()
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query,
+		input.UserID, input.Name, input.Kind, input.Currency, input.OpeningBalance, now, now,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *walletRepository) GetByID(ctx context.Context, id int64) (*Wallet, error) {
+	var wallet Wallet
+	query := `SELECT` + walletColumns + ` FROM wallets WHERE wallets.id = $1`
+	err := r.db.GetContext(ctx, &wallet, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("wallet not found")
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// UpdateWalletInput changes how a wallet is shown. Its currency is fixed
+// once created because its entries are recorded in it.
+type UpdateWalletInput struct {
+	WalletID int64
+	UserID   int64
+	Name     string
+	Kind     string
+	// OpeningBalance is in the minor unit of the wallet's currency
+	OpeningBalance int64
+}
+
+func (r *walletRepository) Update(ctx context.Context, input UpdateWalletInput) error {
+	query := `
+		UPDATE wallets
+		SET name = $1,
+			kind = $2,
+			opening_balance = $3,
+			updated_at = $4
+		WHERE id = $5 AND user_id = $6`
+
+	_, err := r.db.ExecContext(ctx, query,
+		input.Name, input.Kind, input.OpeningBalance, time.Now(), input.WalletID, input.UserID,
+	)
+	return err
+}
+
+// Delete removes the wallet. Import profiles that named it keep their
+// currency but no longer name a wallet.
+func (r *walletRepository) Delete(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE import_profiles SET wallet_id = NULL WHERE wallet_id = $1`, id); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM wallets WHERE id = $1`, id)
+	return err
+}
+
+func (r *walletRepository) List(ctx context.Context, userID int64) ([]Wallet, error) {
+	wallets := []Wallet{}
+	query := `
+		SELECT` + walletColumns + `
+		FROM wallets
+		WHERE wallets.user_id = $1
+		ORDER BY wallets.name, wallets.id
+	`
+
+	if err := r.db.SelectContext(ctx, &wallets, query, userID); err != nil {
+		return nil, err
+	}
+
+	return wallets, nil
+}
+
+type ExistWalletWithUserIDInput struct {
+	UserID   int64 `doc:"User ID"`
+	WalletID int64 `doc:"Wallet ID"`
+}
+
+func (r *walletRepository) ExistWithUserID(ctx context.Context, input ExistWalletWithUserIDInput) (bool, error) {
+	var count int
+	query := `
+		SELECT
+			COUNT(*)
+		FROM wallets
+		WHERE id = $1
+		AND user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &count, query, input.WalletID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// CountEntries counts everything that refers to the wallet, including
+// entries in the trash, recurring expense rules and transfers.
+func (r *walletRepository) CountEntries(ctx context.Context, id int64) (int64, error) {
+	var count int64
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM expenses WHERE wallet_id = $1)
+			+ (SELECT COUNT(*) FROM incomes WHERE wallet_id = $1)
+			+ (SELECT COUNT(*) FROM recurring_expenses WHERE wallet_id = $1)
+			+ (SELECT COUNT(*) FROM transfers WHERE from_wallet_id = $1 OR to_wallet_id = $1)
+	`
+
+	if err := r.db.GetContext(ctx, &count, query, id); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Ledger entry types
+const (
+	LedgerEntryExpense     = "expense"
+	LedgerEntryIncome      = "income"
+	LedgerEntryTransferIn  = "transfer_in"
+	LedgerEntryTransferOut = "transfer_out"
+)
+
+// LedgerEntry is an expense, income or one side of a transfer recorded
+// against a wallet. Amount is negative for money going out, including any
+// transfer fee, and Balance is the wallet's balance right after the entry.
+type LedgerEntry struct {
+	Type        string    `db:"entry_type"`
+	ID          int64     `db:"id"`
+	Description string    `db:"description"`
+	OccurredAt  time.Time `db:"occurred_at"`
+	Amount      int64     `db:"amount"`
+	Balance     int64     `db:"balance"`
+
+	// Category is set for expenses and incomes
+	CategoryID   sql.NullInt64  `db:"category_id"`
+	CategoryName sql.NullString `db:"category_name"`
+	// Counterpart is the other wallet of a transfer
+	CounterpartWalletID   sql.NullInt64  `db:"counterpart_wallet_id"`
+	CounterpartWalletName sql.NullString `db:"counterpart_wallet_name"`
+}
+
+type LedgerInput struct {
+	WalletID int64
+	// OpeningBalance is where the running balance starts
+	OpeningBalance int64
+	Page           int
+	Limit          int
+}
+
+type LedgerPage struct {
+	Entries    []LedgerEntry
+	HasMore    bool
+	TotalCount int64
+}
+
+// Ledger returns the wallet's active entries and transfers, most recent first, each with
+// the running balance after it. Entries at the same time are applied in a
+// stable order so balances do not shift between pages.
+func (r *walletRepository) Ledger(ctx context.Context, input LedgerInput) (*LedgerPage, error) {
+	defaultLimit := 10
+	if input.Limit == 0 {
+		input.Limit = defaultLimit
+	}
+
+	defaultPage := 1
+	if input.Page == 0 {
+		input.Page = defaultPage
+	}
+
+	entries := `
+		SELECT
+			'expense' as entry_type,
+			expenses.id,
+			COALESCE(expenses.description, '') as description,
+			expenses.category_id,
+			categories.name as category_name,
+			CAST(NULL AS BIGINT) as counterpart_wallet_id,
+			CAST(NULL AS TEXT) as counterpart_wallet_name,
+			expenses.occurred_at,
+			-expenses.amount as amount
+		FROM expenses
+		JOIN categories ON categories.id = expenses.category_id
+		WHERE expenses.wallet_id = $1
+		AND expenses.deleted_at IS NULL
+		UNION ALL
+		SELECT
+			'income' as entry_type,
+			incomes.id,
+			incomes.description,
+			incomes.category_id,
+			categories.name as category_name,
+			CAST(NULL AS BIGINT) as counterpart_wallet_id,
+			CAST(NULL AS TEXT) as counterpart_wallet_name,
+			incomes.occurred_at,
+			incomes.amount
+		FROM incomes
+		JOIN categories ON categories.id = incomes.category_id
+		WHERE incomes.wallet_id = $1
+		AND incomes.deleted_at IS NULL
+		UNION ALL
+		SELECT
+			'transfer_in' as entry_type,
+			transfers.id,
+			transfers.description,
+			NULL as category_id,
+			NULL as category_name,
+			transfers.from_wallet_id as counterpart_wallet_id,
+			wallets.name as counterpart_wallet_name,
+			transfers.occurred_at,
+			transfers.to_amount
+		FROM transfers
+		JOIN wallets ON wallets.id = transfers.from_wallet_id
+		WHERE transfers.to_wallet_id = $1
+		UNION ALL
+		SELECT
+			'transfer_out' as entry_type,
+			transfers.id,
+			transfers.description,
+			NULL as category_id,
+			NULL as category_name,
+			transfers.to_wallet_id as counterpart_wallet_id,
+			wallets.name as counterpart_wallet_name,
+			transfers.occurred_at,
+			-(transfers.amount + transfers.fee)
+		FROM transfers
+		JOIN wallets ON wallets.id = transfers.to_wallet_id
+		WHERE transfers.from_wallet_id = $1`
+
+	page := &LedgerPage{Entries: []LedgerEntry{}}
+
+	countQuery := `SELECT COUNT(*) FROM (` + entries + `) ledger_entries`
+	if err := r.db.GetContext(ctx, &page.TotalCount, countQuery, input.WalletID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH ledger_entries AS (` + entries + `
+		)
+		SELECT
+			entry_type,
+			id,
+			description,
+			category_id,
+			category_name,
+			counterpart_wallet_id,
+			counterpart_wallet_name,
+			occurred_at,
+			amount,
+			CAST($2 + SUM(amount) OVER (
+				ORDER BY occurred_at, entry_type, id
+				ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW
+			) AS BIGINT) as balance
+		FROM ledger_entries
+		ORDER BY occurred_at DESC, entry_type DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	// Fetch one extra row to learn whether another page follows
+	offset := (input.Page - 1) * input.Limit
+	err := r.db.SelectContext(ctx, &page.Entries, query, input.WalletID, input.OpeningBalance, input.Limit+1, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Entries) > input.Limit {
+		page.Entries = page.Entries[:input.Limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
@@ -0,0 +1,69 @@
+package exchangerate
+
+import (
+	"context"
+	"gastoslog/internal/currency"
+	"gastoslog/internal/database"
+	"io"
+)
+
+// ImportResult counts the rates stored by an import. Rates for currencies
+// outside the supported ISO 4217 list, such as the pre-euro currencies in the
+// ECB history, or with a non-positive rate are skipped.
+type ImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// ParseError is returned by Import when r does not hold valid rates, as
+// opposed to failing to store them.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Import parses r and stores its rates in a single transaction, replacing
+// rates already stored for the same pair and day.
+func Import(ctx context.Context, db database.Service, r io.Reader, format Format) (ImportResult, error) {
+	var result ImportResult
+
+	rates, err := Parse(r, format)
+	if err != nil {
+		return result, &ParseError{Err: err}
+	}
+
+	err = db.WithTx(ctx, func(tx database.Repositories) error {
+		for _, rate := range rates {
+			base, baseErr := currency.Lookup(rate.Base)
+			quote, quoteErr := currency.Lookup(rate.Quote)
+			if baseErr != nil || quoteErr != nil || base == quote || rate.Rate <= 0 {
+				result.Skipped++
+				continue
+			}
+
+			err := tx.ExchangeRateRepository().Upsert(ctx, database.ExchangeRate{
+				RateDate:      rate.Date,
+				BaseCurrency:  base.Code,
+				QuoteCurrency: quote.Code,
+				Rate:          rate.Rate,
+			})
+			if err != nil {
+				return err
+			}
+			result.Imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,172 @@
+package exchangerate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format names a rate file layout.
+type Format string
+
+const (
+	// FormatECB is the euro foreign exchange reference rates XML published
+	// by the European Central Bank, either the daily or the historical file.
+	FormatECB Format = "ecb"
+	// FormatCSV has a date,base,quote,rate header followed by one rate per
+	// line, e.g. 2024-01-05,USD,PHP,55.72.
+	FormatCSV Format = "csv"
+)
+
+const dateLayout = "2006-01-02"
+
+var ErrUnknownFormat = errors.New("unknown exchange rate format")
+
+// Rate prices one unit of Base in Quote on Date.
+type Rate struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Rate  float64
+}
+
+// ParseFormat accepts "ecb", "xml" or "csv", ignoring case.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "ecb", "xml":
+		return FormatECB, nil
+	case "csv":
+		return FormatCSV, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// Parse reads rates in the given format. An empty format is detected from
+// the content: XML starts with '<', anything else is read as CSV.
+func Parse(r io.Reader, format Format) ([]Rate, error) {
+	br := bufio.NewReader(r)
+
+	// Spreadsheet exports often start with a byte order mark
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		_, _ = br.Discard(3)
+	}
+
+	if format == "" {
+		format = FormatCSV
+		peek, _ := br.Peek(512)
+		if bytes.HasPrefix(bytes.TrimSpace(peek), []byte("<")) {
+			format = FormatECB
+		}
+	}
+
+	switch format {
+	case FormatECB:
+		return ParseECB(br)
+	case FormatCSV:
+		return ParseCSV(br)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECB reads the ECB reference rates XML. Every rate is quoted against
+// EUR.
+func ParseECB(r io.Reader) ([]Rate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("parse ECB rates: %w", err)
+	}
+
+	rates := []Rate{}
+	for _, day := range envelope.Days {
+		date, err := time.Parse(dateLayout, day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("parse ECB rates: invalid date %q", day.Time)
+		}
+
+		for _, quote := range day.Rates {
+			value, err := strconv.ParseFloat(quote.Rate, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse ECB rates: invalid %s rate %q on %s", quote.Currency, quote.Rate, day.Time)
+			}
+			rates = append(rates, Rate{Date: date, Base: "EUR", Quote: quote.Currency, Rate: value})
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.New("parse ECB rates: no rates found")
+	}
+
+	return rates, nil
+}
+
+// ParseCSV reads rates with a date,base,quote,rate header. Columns may come
+// in any order and blank lines are ignored.
+func ParseCSV(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("parse CSV rates: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("parse CSV rates: missing %q column", name)
+		}
+	}
+
+	rates := []Rate{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse CSV rates: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		date, err := time.Parse(dateLayout, strings.TrimSpace(record[columns["date"]]))
+		if err != nil {
+			return nil, fmt.Errorf("parse CSV rates: line %d: invalid date %q", line, record[columns["date"]])
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[columns["rate"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse CSV rates: line %d: invalid rate %q", line, record[columns["rate"]])
+		}
+
+		rates = append(rates, Rate{
+			Date:  date,
+			Base:  strings.ToUpper(strings.TrimSpace(record[columns["base"]])),
+			Quote: strings.ToUpper(strings.TrimSpace(record[columns["quote"]])),
+			Rate:  value,
+		})
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,69 @@
+package exchangerate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const ecbSample = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time="2024-01-05">
+			<Cube currency="USD" rate="1.0921"/>
+			<Cube currency="JPY" rate="158.08"/>
+		</Cube>
+		<Cube time="2024-01-04">
+			<Cube currency="USD" rate="1.0953"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestParseDetectsFormat(t *testing.T) {
+	rates, err := Parse(strings.NewReader(ecbSample), "")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(rates) != 3 {
+		t.Fatalf("expected 3 ECB rates; got %+v", rates)
+	}
+	if rates[1].Base != "EUR" || rates[1].Quote != "JPY" || rates[1].Rate != 158.08 || rates[1].Date.Format(dateLayout) != "2024-01-05" {
+		t.Errorf("unexpected ECB rate %+v", rates[1])
+	}
+
+	csv := "\xef\xbb\xbfDate,Base,Quote,Rate\n2024-01-05,usd,PHP,55.72\n\n2024-01-04,USD,PHP,55.60\n"
+	rates, err = Parse(strings.NewReader(csv), "")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(rates) != 2 || rates[0].Base != "USD" || rates[0].Rate != 55.72 {
+		t.Errorf("unexpected CSV rates %+v", rates)
+	}
+}
+
+func TestParseCSVRejectsInvalidRows(t *testing.T) {
+	tests := map[string]string{
+		"missing column": "date,base,rate\n2024-01-05,USD,55.72\n",
+		"invalid date":   "date,base,quote,rate\n05/01/2024,USD,PHP,55.72\n",
+		"invalid rate":   "date,base,quote,rate\n2024-01-05,USD,PHP,abc\n",
+	}
+
+	for name, input := range tests {
+		if _, err := ParseCSV(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestImportReportsParseErrors(t *testing.T) {
+	_, err := Import(context.Background(), nil, strings.NewReader("date,base,quote,rate\n2024-01-05,USD,PHP,abc\n"), FormatCSV)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("expected a ParseError; got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"gastoslog/internal/database"
+	"gastoslog/internal/storage"
+	"log"
+	"time"
+)
+
+// TrashPurger hard-deletes expenses, incomes and categories that have stayed
+// in the trash longer than the retention window, along with the attached
+// files no other expense refers to.
+type TrashPurger struct {
+	db          database.Service
+	attachments *storage.Local
+	retention   time.Duration
+	interval    time.Duration
+}
+
+func NewTrashPurger(db database.Service, attachments *storage.Local, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{db: db, attachments: attachments, retention: retention, interval: interval}
+}
+
+// Run purges once immediately and then on every interval until ctx is done.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		expenses, incomes, categories, err := p.Purge(ctx)
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if expenses > 0 || incomes > 0 || categories > 0 {
+			log.Printf("Purged %d expenses, %d incomes and %d categories from trash", expenses, incomes, categories)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes expired expenses and incomes before categories so that
+// categories whose last entry has just been purged can go in the same run.
+func (p *TrashPurger) Purge(ctx context.Context) (expenses int64, incomes int64, categories int64, err error) {
+	deletedBefore := time.Now().Add(-p.retention)
+
+	var keys []string
+	err = p.db.WithTx(ctx, func(tx database.Repositories) error {
+		keys, err = tx.AttachmentRepository().PurgeByExpenses(ctx, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		expenses, err = tx.ExpenseRepository().Purge(ctx, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		incomes, err = tx.IncomeRepository().Purge(ctx, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		categories, err = tx.CategoryRepository().Purge(ctx, deletedBefore)
+		return err
+	})
+	if err != nil {
+		return expenses, incomes, categories, err
+	}
+
+	// Files go only once the records are gone for good, and only when no
+	// other expense has the same file attached
+	for _, key := range keys {
+		if err := deleteUnreferenced(ctx, p.db, p.attachments, key); err != nil {
+			log.Printf("Failed to delete attachment %s: %v", key, err)
+		}
+	}
+
+	return expenses, incomes, categories, nil
+}
+
+// deleteUnreferenced removes a stored file once no attachment refers to it.
+// It races uploads of the same content just as v1's DeleteAttachment does,
+// and relies on the upload storing the file again.
+func deleteUnreferenced(ctx context.Context, db database.Repositories, attachments *storage.Local, key string) error {
+	count, err := db.AttachmentRepository().CountBySHA256(ctx, key)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return attachments.Delete(key)
+}
@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"context"
+	"gastoslog/internal/database"
+	"gastoslog/internal/recurrence"
+	"log"
+	"time"
+)
+
+const (
+	// dueBatchSize caps the rules handled per run; the rest follow next run
+	dueBatchSize = 500
+	// maxOccurrencesPerRule caps the expenses one rule generates per run, so
+	// catching up after a long downtime is spread over several runs
+	maxOccurrencesPerRule = 1000
+)
+
+// RecurringMaterializer generates the expenses of recurring rules as they
+// fall due. Each rule remembers its next occurrence, so missed occurrences
+// are caught up after downtime, and generated expenses are unique per rule
+// and occurrence, so running it twice, or in two processes, is harmless.
+type RecurringMaterializer struct {
+	db       database.Service
+	interval time.Duration
+}
+
+func NewRecurringMaterializer(db database.Service, interval time.Duration) *RecurringMaterializer {
+	return &RecurringMaterializer{db: db, interval: interval}
+}
+
+// Run materializes once immediately and then on every interval until ctx is
+// done.
+func (m *RecurringMaterializer) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := m.Materialize(ctx, time.Now())
+		if err != nil {
+			log.Printf("Failed to materialize recurring expenses: %v", err)
+		} else if created > 0 {
+			log.Printf("Created %d expenses from recurring rules", created)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Materialize generates every occurrence due at or before now.
+func (m *RecurringMaterializer) Materialize(ctx context.Context, now time.Time) (int64, error) {
+	due, err := m.db.RecurringExpenseRepository().ListDue(ctx, now, dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var created int64
+	for _, recurringExpense := range due {
+		count, err := m.materializeRule(ctx, recurringExpense, now)
+		if err != nil {
+			// One broken rule should not hold back the others
+			log.Printf("Failed to materialize recurring expense %d: %v", recurringExpense.ID, err)
+			continue
+		}
+		created += count
+	}
+
+	return created, nil
+}
+
+// materializeRule creates the rule's due expenses and advances it in one
+// transaction.
+func (m *RecurringMaterializer) materializeRule(ctx context.Context, recurringExpense database.RecurringExpense, now time.Time) (int64, error) {
+	rule, err := recurrence.Parse(recurringExpense.RRule)
+	if err != nil {
+		return 0, err
+	}
+
+	schedule := recurrence.Schedule{Rule: rule, Start: recurringExpense.StartsAt.UTC(), End: recurringExpense.EndsAt}
+	// Include the next occurrence itself
+	after := recurringExpense.NextOccurrenceAt.Add(-time.Nanosecond)
+	occurrences := schedule.Between(after, now, maxOccurrencesPerRule)
+
+	var created int64
+	err = m.db.WithTx(ctx, func(tx database.Repositories) error {
+		for _, occurredAt := range occurrences {
+			occurredAt := occurredAt
+			inserted, err := tx.ExpenseRepository().CreateRecurring(ctx, database.NewExpenseInput{
+				UserID:      recurringExpense.UserID,
+				CategoryID:  recurringExpense.CategoryID,
+				Amount:      recurringExpense.Amount,
+				Currency:    recurringExpense.Currency,
+				Description: recurringExpense.Description,
+				OccurredAt:  &occurredAt,
+				WalletID:    recurringExpense.WalletID,
+			}, recurringExpense.ID)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				created++
+			}
+		}
+
+		var last *time.Time
+		if len(occurrences) > 0 {
+			last = &occurrences[len(occurrences)-1]
+			after = *last
+		}
+
+		var next *time.Time
+		if nextAt, ok := schedule.Next(after); ok {
+			next = &nextAt
+		}
+
+		return tx.RecurringExpenseRepository().Advance(ctx, recurringExpense.ID, last, next)
+	})
+
+	return created, err
+}
@@ -0,0 +1,186 @@
+package journal
+
+import (
+	"context"
+	"gastoslog/internal/currency"
+	"gastoslog/internal/database"
+	"io"
+	"slices"
+)
+
+// DefaultAccount is where expenses paid from no wallet come from.
+const DefaultAccount = "Assets:Cash"
+
+// pageSize is how many expenses Export reads from the database at a time.
+const pageSize = 500
+
+// Options select the expenses Export writes and how.
+type Options struct {
+	Format Format
+	// Filters selects the expenses, as for the expense list. Paging is
+	// ignored
+	Filters database.ListExpenseInput
+	// DefaultAccount is the other posting of expenses paid from no wallet,
+	// DefaultAccount when empty
+	DefaultAccount string
+}
+
+// Export writes the user's expenses as a journal, oldest first, reading them
+// page by page. Categories become Expenses accounts, nested under their
+// parents, and the money comes from the wallet's Assets account, or
+// Liabilities for credit cards. Only the matching lines of split expenses
+// are written when the filters name categories.
+func Export(ctx context.Context, db database.Repositories, w io.Writer, options Options) error {
+	format, err := ParseFormat(string(options.Format))
+	if err != nil {
+		return err
+	}
+	if options.DefaultAccount == "" {
+		options.DefaultAccount = DefaultAccount
+	}
+	defaultAccount, err := format.ParseAccount(options.DefaultAccount)
+	if err != nil {
+		return err
+	}
+
+	userID := options.Filters.UserID
+	categories, err := db.CategoryRepository().List(ctx, database.ListCategoryInput{UserID: userID, Kind: database.CategoryKindExpense, All: true})
+	if err != nil {
+		return err
+	}
+	wallets, err := db.WalletRepository().List(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	accounts := []string{defaultAccount}
+	categoryAccounts := categoryAccounts(format, categories.Categories)
+	for _, account := range categoryAccounts {
+		accounts = append(accounts, account)
+	}
+	walletAccounts := make(map[int64]string, len(wallets))
+	for _, wallet := range wallets {
+		root := "Assets"
+		if wallet.Kind == database.WalletKindCreditCard {
+			root = "Liabilities"
+		}
+		walletAccounts[wallet.ID] = format.Account(root, wallet.Name)
+		accounts = append(accounts, walletAccounts[wallet.ID])
+	}
+	slices.Sort(accounts)
+	accounts = slices.Compact(accounts)
+
+	filters := options.Filters
+	filters.Page = 1
+	filters.Limit = pageSize
+	filters.Cursor = ""
+	filters.OldestFirst = true
+
+	page, err := db.ExpenseRepository().List(ctx, filters)
+	if err != nil {
+		return err
+	}
+
+	out := NewWriter(w, format)
+	if len(page.Expenses) > 0 {
+		if err := out.Open(page.Expenses[0].OccurredAt, accounts); err != nil {
+			return err
+		}
+	}
+
+	for {
+		for _, expense := range page.Expenses {
+			cur, err := currency.Lookup(expense.Currency)
+			if err != nil {
+				return err
+			}
+
+			source, ok := walletAccounts[expense.WalletID.Int64]
+			if !ok || !expense.WalletID.Valid {
+				source = defaultAccount
+			}
+
+			tx := expenseTransaction(format, expense, cur, filters.Category, categoryAccounts, source)
+			if len(tx.Postings) == 0 {
+				continue
+			}
+			if err := out.WriteTransaction(tx); err != nil {
+				return err
+			}
+		}
+		if !page.HasMore {
+			break
+		}
+
+		filters.Cursor = page.NextCursor
+		if page, err = db.ExpenseRepository().List(ctx, filters); err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// expenseTransaction posts an expense, or the lines of a split expense that
+// are in categories when there are any, against source.
+func expenseTransaction(format Format, expense database.RawExpense, cur currency.Currency, categories []int64, categoryAccounts map[int64]string, source string) Transaction {
+	account := func(categoryID int64, name string) string {
+		if account, ok := categoryAccounts[categoryID]; ok {
+			return account
+		}
+		return format.Account("Expenses", name)
+	}
+
+	tx := Transaction{
+		Date:        expense.OccurredAt,
+		Description: expense.Description.String,
+		ID:          expense.ID,
+		Tags:        expense.Tags,
+	}
+	if tx.Description == "" {
+		tx.Description = expense.CategoryName
+	}
+
+	var total int64
+	if len(expense.Splits) == 0 {
+		tx.Postings = append(tx.Postings, Posting{Account: account(expense.CategoryID, expense.CategoryName), Amount: expense.Amount, Currency: cur})
+		total = expense.Amount
+	}
+	for _, split := range expense.Splits {
+		if len(categories) > 0 && !slices.Contains(categories, split.CategoryID) {
+			continue
+		}
+		tx.Postings = append(tx.Postings, Posting{Account: account(split.CategoryID, split.CategoryName), Amount: split.Amount, Currency: cur})
+		total += split.Amount
+	}
+
+	if len(tx.Postings) > 0 {
+		tx.Postings = append(tx.Postings, Posting{Account: source, Amount: -total, Currency: cur})
+	}
+	return tx
+}
+
+// categoryAccounts maps each category to its Expenses account, with the
+// names of its parents in between.
+func categoryAccounts(format Format, categories []database.Category) map[int64]string {
+	byID := make(map[int64]database.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	accounts := make(map[int64]string, len(categories))
+	for _, category := range categories {
+		names := []string{category.Name}
+		// The depth limit guards against a cycle
+		for parent := category.ParentID; parent.Valid && len(names) < 10; {
+			parentCategory, ok := byID[parent.Int64]
+			if !ok {
+				break
+			}
+			names = append([]string{parentCategory.Name}, names...)
+			parent = parentCategory.ParentID
+		}
+		accounts[category.ID] = format.Account(append([]string{"Expenses"}, names...)...)
+	}
+	return accounts
+}
@@ -0,0 +1,239 @@
+// Package journal writes expenses as plain-text accounting journals for
+// ledger-cli, hledger and beancount.
+package journal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gastoslog/internal/currency"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Format names a journal dialect.
+type Format string
+
+const (
+	FormatLedger    Format = "ledger"
+	FormatHledger   Format = "hledger"
+	FormatBeancount Format = "beancount"
+)
+
+const dateLayout = "2006-01-02"
+
+var ErrUnknownFormat = errors.New("unknown journal format")
+
+var ErrInvalidAccount = errors.New("accounts must look like Assets:Cash, starting with Assets, Liabilities, Equity, Income or Expenses")
+
+// ParseFormat accepts "ledger", "hledger" or "beancount", ignoring case.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatLedger, FormatHledger, FormatBeancount:
+		return format, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// Account joins names into an account of the format, e.g. "Expenses" and
+// "Eating out" into Expenses:Eating out, or Expenses:Eating-out for
+// beancount, whose account names only hold letters, digits and '-'.
+func (f Format) Account(names ...string) string {
+	components := make([]string, len(names))
+	for i, name := range names {
+		if f == FormatBeancount {
+			components[i] = beancountComponent(name)
+		} else {
+			components[i] = ledgerComponent(name)
+		}
+	}
+	return strings.Join(components, ":")
+}
+
+// ParseAccount reads a user-given account such as Assets:Cash in the format.
+func (f Format) ParseAccount(account string) (string, error) {
+	names := strings.Split(account, ":")
+	if len(names) < 2 {
+		return "", ErrInvalidAccount
+	}
+	switch names[0] {
+	case "Assets", "Liabilities", "Equity", "Income", "Expenses":
+	default:
+		return "", ErrInvalidAccount
+	}
+	for _, name := range names[1:] {
+		if strings.TrimSpace(name) == "" {
+			return "", ErrInvalidAccount
+		}
+	}
+	return f.Account(names...), nil
+}
+
+// ledgerComponent keeps a name on one line and out of the ':' separators.
+// ledger and hledger end an account name at two spaces or a tab, so
+// whitespace collapses to single spaces.
+func ledgerComponent(name string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(name, ":", "-")), " ")
+}
+
+// beancountComponent turns runs of anything but letters and digits into a
+// '-' and capitalizes the name, which must start with an uppercase letter or
+// a digit.
+func beancountComponent(name string) string {
+	var component strings.Builder
+	dash := false
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = component.Len() > 0
+			continue
+		}
+		if dash {
+			component.WriteByte('-')
+			dash = false
+		}
+		if component.Len() == 0 {
+			r = unicode.ToUpper(r)
+		}
+		component.WriteRune(r)
+	}
+
+	result := component.String()
+	if result == "" {
+		return "Other"
+	}
+	if first := []rune(result)[0]; !unicode.IsUpper(first) && !unicode.IsDigit(first) {
+		// Letters without case, such as CJK, cannot start a component
+		result = "X-" + result
+	}
+	return result
+}
+
+// Posting moves Amount, in minor units of Currency, to or from Account.
+type Posting struct {
+	Account  string
+	Amount   int64
+	Currency currency.Currency
+}
+
+// Transaction is one dated entry whose postings balance.
+type Transaction struct {
+	Date        time.Time
+	Description string
+	// ID is kept as metadata so entries can be traced back to their expense
+	ID       int64
+	Tags     []string
+	Postings []Posting
+}
+
+// Writer writes journal entries in one format.
+type Writer struct {
+	w      *bufio.Writer
+	format Format
+}
+
+func NewWriter(w io.Writer, format Format) *Writer {
+	return &Writer{w: bufio.NewWriter(w), format: format}
+}
+
+// Open declares accounts, which beancount requires before their first use
+// on date and hledger's strict mode checks.
+func (w *Writer) Open(date time.Time, accounts []string) error {
+	for _, account := range accounts {
+		if w.format == FormatBeancount {
+			fmt.Fprintf(w.w, "%s open %s\n", date.Format(dateLayout), account)
+		} else {
+			fmt.Fprintf(w.w, "account %s\n", account)
+		}
+	}
+	_, err := w.w.WriteString("\n")
+	return err
+}
+
+// WriteTransaction writes one entry followed by a blank line.
+func (w *Writer) WriteTransaction(tx Transaction) error {
+	description := strings.Join(strings.Fields(tx.Description), " ")
+	date := tx.Date.Format(dateLayout)
+	indent := "    "
+
+	switch w.format {
+	case FormatBeancount:
+		indent = "  "
+		fmt.Fprintf(w.w, "%s * %s", date, strconv.Quote(description))
+		for _, tag := range tx.Tags {
+			fmt.Fprintf(w.w, " #%s", beancountTag(tag))
+		}
+		fmt.Fprintf(w.w, "\n%sid: \"%d\"\n", indent, tx.ID)
+	case FormatHledger:
+		// hledger reads tags from the comment as name:value pairs
+		fields := []string{fmt.Sprintf("id:%d", tx.ID)}
+		for _, tag := range tx.Tags {
+			fields = append(fields, tag+":")
+		}
+		fmt.Fprintf(w.w, "%s * %s  ; %s\n", date, ledgerDescription(description), strings.Join(fields, ", "))
+	default:
+		fmt.Fprintf(w.w, "%s * %s\n", date, ledgerDescription(description))
+		if len(tx.Tags) > 0 {
+			fmt.Fprintf(w.w, "%s; :%s:\n", indent, strings.Join(tx.Tags, ":"))
+		}
+		fmt.Fprintf(w.w, "%s; ID: %d\n", indent, tx.ID)
+	}
+
+	accountWidth, amountWidth := 0, 0
+	amounts := make([]string, len(tx.Postings))
+	for i, posting := range tx.Postings {
+		amounts[i] = formatAmount(posting.Amount, posting.Currency)
+		accountWidth = max(accountWidth, len([]rune(posting.Account)))
+		amountWidth = max(amountWidth, len(amounts[i]))
+	}
+	for i, posting := range tx.Postings {
+		padding := accountWidth - len([]rune(posting.Account)) + amountWidth - len(amounts[i]) + 2
+		fmt.Fprintf(w.w, "%s%s%s%s %s\n", indent, posting.Account, strings.Repeat(" ", padding), amounts[i], posting.Currency.Code)
+	}
+
+	_, err := w.w.WriteString("\n")
+	return err
+}
+
+// Flush writes any buffered entries to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// ledgerDescription keeps a description from starting a comment, which a
+// ';' does in ledger and hledger.
+func ledgerDescription(description string) string {
+	return strings.ReplaceAll(description, ";", ",")
+}
+
+// beancountTag replaces the characters beancount tags cannot hold with '-'.
+func beancountTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return r
+		}
+		return '-'
+	}, tag)
+}
+
+// formatAmount writes amount, in minor units of cur, with exactly the
+// currency's decimals and no float rounding.
+func formatAmount(amount int64, cur currency.Currency) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if cur.MinorUnits == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	scale := int64(1)
+	for range cur.MinorUnits {
+		scale *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/scale, cur.MinorUnits, amount%scale)
+}
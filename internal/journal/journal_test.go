@@ -0,0 +1,144 @@
+package journal
+
+import (
+	"bytes"
+	"gastoslog/internal/currency"
+	"testing"
+	"time"
+)
+
+func testTransaction(t *testing.T) Transaction {
+	php, err := currency.Lookup("PHP")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	return Transaction{
+		Date:        time.Date(2024, 3, 2, 15, 4, 0, 0, time.UTC),
+		Description: "Groceries; \"weekly\"\n run",
+		ID:          12,
+		Tags:        []string{"work", "trip"},
+		Postings: []Posting{
+			{Account: "Expenses:Food", Amount: 125050, Currency: php},
+			{Account: "Expenses:Household", Amount: 5, Currency: php},
+			{Account: "Assets:Cash", Amount: -125055, Currency: php},
+		},
+	}
+}
+
+func TestWriteTransaction(t *testing.T) {
+	tests := map[Format]string{
+		FormatLedger: `2024-03-02 * Groceries, "weekly" run
+    ; :work:trip:
+    ; ID: 12
+    Expenses:Food        1250.50 PHP
+    Expenses:Household      0.05 PHP
+    Assets:Cash         -1250.55 PHP
+
+`,
+		FormatHledger: `2024-03-02 * Groceries, "weekly" run  ; id:12, work:, trip:
+    Expenses:Food        1250.50 PHP
+    Expenses:Household      0.05 PHP
+    Assets:Cash         -1250.55 PHP
+
+`,
+		FormatBeancount: `2024-03-02 * "Groceries; \"weekly\" run" #work #trip
+  id: "12"
+  Expenses:Food        1250.50 PHP
+  Expenses:Household      0.05 PHP
+  Assets:Cash         -1250.55 PHP
+
+`,
+	}
+
+	for format, want := range tests {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, format)
+		if err := w.WriteTransaction(testTransaction(t)); err != nil {
+			t.Fatalf("WriteTransaction failed: %v", err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: unexpected entry:\n%s\nwant:\n%s", format, buf.String(), want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, FormatBeancount)
+	_ = w.Open(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), []string{"Assets:Cash", "Expenses:Food"})
+	_ = w.Flush()
+	if want := "2024-01-05 open Assets:Cash\n2024-01-05 open Expenses:Food\n\n"; buf.String() != want {
+		t.Errorf("unexpected open directives %q", buf.String())
+	}
+
+	buf.Reset()
+	w = NewWriter(&buf, FormatHledger)
+	_ = w.Open(time.Now(), []string{"Assets:Cash"})
+	_ = w.Flush()
+	if want := "account Assets:Cash\n\n"; buf.String() != want {
+		t.Errorf("unexpected account directives %q", buf.String())
+	}
+}
+
+func TestAccount(t *testing.T) {
+	tests := []struct {
+		format Format
+		names  []string
+		want   string
+	}{
+		{FormatLedger, []string{"Expenses", "Eating  out", "Dates: 1st"}, "Expenses:Eating out:Dates- 1st"},
+		{FormatHledger, []string{"Assets", "GCash"}, "Assets:GCash"},
+		{FormatBeancount, []string{"Expenses", "eating out & bars"}, "Expenses:Eating-out-bars"},
+		{FormatBeancount, []string{"Expenses", "Café"}, "Expenses:Café"},
+		{FormatBeancount, []string{"Expenses", "食物"}, "Expenses:X-食物"},
+		{FormatBeancount, []string{"Expenses", "!!"}, "Expenses:Other"},
+	}
+	for _, test := range tests {
+		if got := test.format.Account(test.names...); got != test.want {
+			t.Errorf("%s Account(%q) = %q; want %q", test.format, test.names, got, test.want)
+		}
+	}
+}
+
+func TestParseAccount(t *testing.T) {
+	if got, err := FormatBeancount.ParseAccount("Liabilities:credit card"); err != nil || got != "Liabilities:Credit-card" {
+		t.Errorf("ParseAccount = %q, %v", got, err)
+	}
+	for _, account := range []string{"", "Assets", "Cash:Wallet", "Assets::Cash", "Assets: "} {
+		if _, err := FormatLedger.ParseAccount(account); err != ErrInvalidAccount {
+			t.Errorf("expected ErrInvalidAccount for %q; got %v", account, err)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat(" HLedger "); err != nil || format != FormatHledger {
+		t.Errorf("ParseFormat = %q, %v", format, err)
+	}
+	if _, err := ParseFormat("gnucash"); err != ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat; got %v", err)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	jpy, _ := currency.Lookup("JPY")
+	bhd, _ := currency.Lookup("BHD")
+	php, _ := currency.Lookup("PHP")
+	tests := []struct {
+		amount int64
+		cur    currency.Currency
+		want   string
+	}{
+		{1500, jpy, "1500"},
+		{-1, php, "-0.01"},
+		{123456, bhd, "123.456"},
+	}
+	for _, test := range tests {
+		if got := formatAmount(test.amount, test.cur); got != test.want {
+			t.Errorf("formatAmount(%d, %s) = %q; want %q", test.amount, test.cur.Code, got, test.want)
+		}
+	}
+}
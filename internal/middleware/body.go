@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+)
+
+// NewBodyLimitMiddleware caps multipart bodies at the operation's
+// MaxBodyBytes, which huma only enforces on other bodies. It must run before
+// any middleware that wraps the context, as it needs the raw request.
+func NewBodyLimitMiddleware(api huma.API) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		limit := ctx.Operation().MaxBodyBytes
+		if limit <= 0 || !strings.HasPrefix(ctx.Header("Content-Type"), "multipart/form-data") {
+			next(ctx)
+			return
+		}
+
+		r, w := humachi.Unwrap(ctx)
+		if r.ContentLength > limit {
+			huma.WriteErr(api, ctx, http.StatusRequestEntityTooLarge, tooLargeMessage(limit))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(ctx)
+	}
+}
+
+// NewMultipartFormMiddleware reads the multipart form ahead of huma so a
+// body cut off by NewBodyLimitMiddleware fails with 413 rather than as an
+// unreadable form. Use it on the operation, after authentication.
+func NewMultipartFormMiddleware(api huma.API) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		var tooLarge *http.MaxBytesError
+		if _, err := ctx.GetMultipartForm(); errors.As(err, &tooLarge) {
+			huma.WriteErr(api, ctx, http.StatusRequestEntityTooLarge, tooLargeMessage(tooLarge.Limit))
+			return
+		}
+		// Other errors are left for huma to report
+		next(ctx)
+	}
+}
+
+func tooLargeMessage(limit int64) string {
+	return fmt.Sprintf("Request body is limited to %d bytes", limit)
+}
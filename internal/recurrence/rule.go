@@ -0,0 +1,257 @@
+// Package recurrence parses and expands the subset of RFC 5545 recurrence
+// rules (RRULE) used by recurring expenses, e.g. "FREQ=MONTHLY;BYMONTHDAY=1".
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is a parsed RRULE. Supported parts are FREQ, INTERVAL, BYDAY (plain
+// weekdays, no ordinals), BYMONTHDAY (negative counts from the month's end),
+// COUNT and UNTIL.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR". A leading
+// "RRULE:" is ignored.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	rule := &Rule{Interval: 1}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+
+		key, val, found := strings.Cut(part, "=")
+		if !found || val == "" {
+			return nil, invalid("%q is not KEY=VALUE", part)
+		}
+		if seen[key] {
+			return nil, invalid("%s given twice", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			switch Frequency(val) {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = Frequency(val)
+			default:
+				return nil, invalid("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, invalid("INTERVAL must be a positive number")
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, invalid("COUNT must be a positive number")
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, name := range strings.Split(val, ",") {
+				weekday, ok := weekdays[name]
+				if !ok {
+					return nil, invalid("BYDAY takes MO, TU, WE, TH, FR, SA or SU, got %q", name)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, name := range strings.Split(val, ",") {
+				day, err := strconv.Atoi(name)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, invalid("BYMONTHDAY takes 1 to 31 or -1 to -31, got %q", name)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		default:
+			return nil, invalid("%s is not supported", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, invalid("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, invalid("COUNT and UNTIL cannot be combined")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return nil, invalid("BYMONTHDAY needs FREQ=MONTHLY")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == Yearly {
+		return nil, invalid("BYDAY is not supported with FREQ=YEARLY")
+	}
+
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A plain date includes the whole day
+				until = until.Add(24*time.Hour - time.Nanosecond)
+			}
+			return until, nil
+		}
+	}
+	return time.Time{}, invalid("UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ")
+}
+
+// String formats the rule in a canonical order, suitable for storing.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		names := make([]string, len(r.ByDay))
+		for i, weekday := range r.ByDay {
+			names[i] = strings.ToUpper(weekday.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (r *Rule) matchesDay(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, weekday := range r.ByDay {
+		if day.Weekday() == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the occurrences of the period that lies period
+// intervals after the one containing start, in order. They keep start's
+// time of day and may fall before start.
+func (r *Rule) candidates(start time.Time, period int) []time.Time {
+	step := period * r.Interval
+	hour, minute, second := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, start.Nanosecond(), start.Location())
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := start.AddDate(0, 0, step)
+		if r.matchesDay(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		// Weeks start on Monday
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*step)
+		if len(r.ByDay) == 0 {
+			return []time.Time{monday.AddDate(0, 0, (int(start.Weekday())+6)%7)}
+		}
+		for _, weekday := range r.ByDay {
+			days = append(days, monday.AddDate(0, 0, (int(weekday)+6)%7))
+		}
+	case Monthly:
+		first := at(start.Year(), start.Month()+time.Month(step), 1)
+		daysInMonth := first.AddDate(0, 1, -1).Day()
+
+		monthDays := r.ByMonthDay
+		if len(monthDays) == 0 && len(r.ByDay) > 0 {
+			for day := 1; day <= daysInMonth; day++ {
+				monthDays = append(monthDays, day)
+			}
+		} else if len(monthDays) == 0 {
+			monthDays = []int{start.Day()}
+		}
+
+		for _, monthDay := range monthDays {
+			if monthDay < 0 {
+				monthDay = daysInMonth + monthDay + 1
+			}
+			if monthDay < 1 || monthDay > daysInMonth {
+				continue
+			}
+			day := at(first.Year(), first.Month(), monthDay)
+			if r.matchesDay(day) {
+				days = append(days, day)
+			}
+		}
+	case Yearly:
+		day := at(start.Year()+step, start.Month(), start.Day())
+		// Skip years without the date, e.g. February 29
+		if day.Month() == start.Month() {
+			days = append(days, day)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	unique := days[:0]
+	for i, day := range days {
+		if i == 0 || !day.Equal(days[i-1]) {
+			unique = append(unique, day)
+		}
+	}
+	return unique
+}
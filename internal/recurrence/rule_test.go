@@ -0,0 +1,103 @@
+package recurrence
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dates(times []time.Time) string {
+	formatted := make([]string, len(times))
+	for i, at := range times {
+		formatted[i] = at.Format("2006-01-02")
+	}
+	return strings.Join(formatted, " ")
+}
+
+func mustParse(t *testing.T, value string) *Rule {
+	t.Helper()
+	rule, err := Parse(value)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", value, err)
+	}
+	return rule
+}
+
+func TestParse(t *testing.T) {
+	rule := mustParse(t, "rrule:freq=weekly;interval=2;byday=MO,FR;count=4")
+	if rule.String() != "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=4" {
+		t.Errorf("unexpected canonical form %q", rule.String())
+	}
+
+	invalidRules := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYSETPOS=1",
+	}
+	for _, value := range invalidRules {
+		if _, err := Parse(value); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q): expected ErrInvalidRule; got %v", value, err)
+		}
+	}
+}
+
+func TestScheduleOccurrences(t *testing.T) {
+	start := time.Date(2025, time.January, 31, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rule     string
+		expected string
+	}{
+		// Months without a 31st are skipped, as in RFC 5545
+		{"FREQ=MONTHLY", "2025-01-31 2025-03-31 2025-05-31 2025-07-31"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2025-01-31 2025-02-28 2025-03-31 2025-04-30"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,15", "2025-02-01 2025-02-15 2025-03-01 2025-03-15"},
+		{"FREQ=WEEKLY;BYDAY=MO,FR", "2025-01-31 2025-02-03 2025-02-07 2025-02-10"},
+		{"FREQ=WEEKLY;INTERVAL=2", "2025-01-31 2025-02-14 2025-02-28 2025-03-14"},
+		{"FREQ=DAILY;BYDAY=SA,SU", "2025-02-01 2025-02-02 2025-02-08 2025-02-09"},
+		{"FREQ=YEARLY;COUNT=2", "2025-01-31 2026-01-31"},
+		{"FREQ=DAILY;UNTIL=20250202", "2025-01-31 2025-02-01 2025-02-02"},
+	}
+
+	for _, test := range tests {
+		schedule := Schedule{Rule: mustParse(t, test.rule), Start: start}
+		got := dates(schedule.Upcoming(start.Add(-time.Nanosecond), 4))
+		if got != test.expected {
+			t.Errorf("%s: expected %s; got %s", test.rule, test.expected, got)
+		}
+	}
+}
+
+func TestScheduleLeapDay(t *testing.T) {
+	start := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+	schedule := Schedule{Rule: mustParse(t, "FREQ=YEARLY"), Start: start}
+	if got := dates(schedule.Upcoming(start, 2)); got != "2028-02-29 2032-02-29" {
+		t.Errorf("expected only leap years; got %s", got)
+	}
+}
+
+func TestScheduleBetweenAndEnd(t *testing.T) {
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	schedule := Schedule{Rule: mustParse(t, "FREQ=MONTHLY"), Start: start, End: &end}
+
+	// Catching up after downtime returns every missed occurrence once
+	got := schedule.Between(start, time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC), 100)
+	if dates(got) != "2025-02-01 2025-03-01 2025-04-01" {
+		t.Errorf("unexpected occurrences %s", dates(got))
+	}
+
+	if _, ok := schedule.Next(end); ok {
+		t.Errorf("expected the schedule to have ended")
+	}
+	if next, ok := schedule.Next(start.Add(-time.Second)); !ok || !next.Equal(start) {
+		t.Errorf("expected the start to be the first occurrence; got %s", next)
+	}
+}
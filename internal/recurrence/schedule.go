@@ -0,0 +1,88 @@
+package recurrence
+
+import "time"
+
+// maxPeriods bounds how far a schedule is expanded, so a rule that rarely
+// matches, e.g. every February 29, cannot loop forever.
+const maxPeriods = 100000
+
+// Schedule anchors a rule at its first occurrence. End optionally stops it
+// in addition to the rule's own COUNT or UNTIL.
+type Schedule struct {
+	Rule  *Rule
+	Start time.Time
+	End   *time.Time
+}
+
+// each calls fn with every occurrence in order until fn returns false or the
+// schedule runs out.
+func (s Schedule) each(fn func(at time.Time) bool) {
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, at := range s.Rule.candidates(s.Start, period) {
+			if at.Before(s.Start) {
+				continue
+			}
+			if s.Rule.Until != nil && at.After(*s.Rule.Until) {
+				return
+			}
+			if s.End != nil && at.After(*s.End) {
+				return
+			}
+
+			count++
+			if s.Rule.Count > 0 && count > s.Rule.Count {
+				return
+			}
+
+			if !fn(at) {
+				return
+			}
+		}
+	}
+}
+
+// Next returns the first occurrence strictly after after. It reports false
+// once the schedule has ended.
+func (s Schedule) Next(after time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	s.each(func(at time.Time) bool {
+		if at.After(after) {
+			next, found = at, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// Between returns up to limit occurrences in (after, until].
+func (s Schedule) Between(after, until time.Time, limit int) []time.Time {
+	occurrences := []time.Time{}
+	s.each(func(at time.Time) bool {
+		if at.After(until) || len(occurrences) >= limit {
+			return false
+		}
+		if at.After(after) {
+			occurrences = append(occurrences, at)
+		}
+		return true
+	})
+	return occurrences
+}
+
+// Upcoming returns the next n occurrences strictly after after.
+func (s Schedule) Upcoming(after time.Time, n int) []time.Time {
+	occurrences := []time.Time{}
+	s.each(func(at time.Time) bool {
+		if len(occurrences) >= n {
+			return false
+		}
+		if at.After(after) {
+			occurrences = append(occurrences, at)
+		}
+		return true
+	})
+	return occurrences
+}
@@ -45,6 +45,7 @@ func (s *Server) RegisterRoutes() http.Handler {
 	api := huma.NewGroup(humaApi, "/api")
 	apiV1 := huma.NewGroup(api, "/v1")
 
+	apiV1.UseMiddleware(gastoslogMiddleware.NewBodyLimitMiddleware(apiV1))
 	apiV1.UseMiddleware(gastoslogMiddleware.NewBasicAuthMiddleware(apiV1))
 
 	bearerSecurity := []map[string][]string{{"bearer": {}}}
@@ -76,6 +77,15 @@ func (s *Server) RegisterRoutes() http.Handler {
 		Security:    bearerSecurity,
 	}, userHandler.Me)
 
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "auth-me-update",
+		Method:      http.MethodPatch,
+		Path:        "/auth/me",
+		Summary:     "Update session user settings",
+		Tags:        []string{"Auth"},
+		Security:    bearerSecurity,
+	}, userHandler.UpdateMe)
+
 	huma.Register(apiV1, huma.Operation{
 		OperationID: "auth-refresh-token",
 		Method:      http.MethodPost,
@@ -84,7 +94,7 @@ func (s *Server) RegisterRoutes() http.Handler {
 		Tags:        []string{"Auth"},
 	}, userHandler.RefreshToken)
 
-	categoryHandler := v1.NewCategoryHandler(s.db.CategoryRepository())
+	categoryHandler := v1.NewCategoryHandler(s.db)
 
 	huma.Register(apiV1, huma.Operation{
 		OperationID: "category-list",
@@ -131,7 +141,16 @@ func (s *Server) RegisterRoutes() http.Handler {
 		Security:    bearerSecurity,
 	}, categoryHandler.DetailCategory)
 
-	expenseHandler := v1.NewExpenseHandler(s.db.ExpenseRepository(), s.db.CategoryRepository())
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "category-restore",
+		Method:      http.MethodPost,
+		Path:        "/categories/{categoryId}/restore",
+		Summary:     "Restore deleted category",
+		Tags:        []string{"Category"},
+		Security:    bearerSecurity,
+	}, categoryHandler.RestoreCategory)
+
+	expenseHandler := v1.NewExpenseHandler(s.db)
 
 	huma.Register(apiV1, huma.Operation{
 		OperationID: "expense-list",
@@ -187,6 +206,645 @@ func (s *Server) RegisterRoutes() http.Handler {
 		Security:    bearerSecurity,
 	}, expenseHandler.GetExpenseOverview)
 
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "expense-overview-tags",
+		Method:      http.MethodGet,
+		Path:        "/expenses/overview/tags",
+		Summary:     "Expense overview by tag",
+		Tags:        []string{"Expense"},
+		Security:    bearerSecurity,
+	}, expenseHandler.GetTagOverview)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "expense-restore",
+		Method:      http.MethodPost,
+		Path:        "/expenses/{expenseId}/restore",
+		Summary:     "Restore deleted expense",
+		Tags:        []string{"Expense"},
+		Security:    bearerSecurity,
+	}, expenseHandler.RestoreExpense)
+
+	tagHandler := v1.NewTagHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "tag-list",
+		Method:      http.MethodGet,
+		Path:        "/tags",
+		Summary:     "List tags",
+		Tags:        []string{"Tag"},
+		Security:    bearerSecurity,
+	}, tagHandler.ListTag)
+
+	budgetHandler := v1.NewBudgetHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "budget-list",
+		Method:      http.MethodGet,
+		Path:        "/budgets",
+		Summary:     "List budgets",
+		Tags:        []string{"Budget"},
+		Security:    bearerSecurity,
+	}, budgetHandler.ListBudget)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "budget-create",
+		Method:      http.MethodPost,
+		Path:        "/budgets",
+		Summary:     "Create budget",
+		Tags:        []string{"Budget"},
+		Security:    bearerSecurity,
+	}, budgetHandler.CreateBudget)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "budget-detail",
+		Method:      http.MethodGet,
+		Path:        "/budgets/{budgetId}",
+		Summary:     "Detail budget",
+		Tags:        []string{"Budget"},
+		Security:    bearerSecurity,
+	}, budgetHandler.DetailBudget)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "budget-update",
+		Method:      http.MethodPost,
+		Path:        "/budgets/{budgetId}",
+		Summary:     "Update budget",
+		Tags:        []string{"Budget"},
+		Security:    bearerSecurity,
+	}, budgetHandler.UpdateBudget)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "budget-delete",
+		Method:      http.MethodDelete,
+		Path:        "/budgets/{budgetId}",
+		Summary:     "Delete budget",
+		Tags:        []string{"Budget"},
+		Security:    bearerSecurity,
+	}, budgetHandler.DeleteBudget)
+
+	recurringExpenseHandler := v1.NewRecurringExpenseHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "recurring-expense-list",
+		Method:      http.MethodGet,
+		Path:        "/recurring-expenses",
+		Summary:     "List recurring expenses",
+		Tags:        []string{"Recurring Expense"},
+		Security:    bearerSecurity,
+	}, recurringExpenseHandler.ListRecurringExpense)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "recurring-expense-create",
+		Method:      http.MethodPost,
+		Path:        "/recurring-expenses",
+		Summary:     "Create recurring expense",
+		Tags:        []string{"Recurring Expense"},
+		Security:    bearerSecurity,
+	}, recurringExpenseHandler.CreateRecurringExpense)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "recurring-expense-detail",
+		Method:      http.MethodGet,
+		Path:        "/recurring-expenses/{recurringExpenseId}",
+		Summary:     "Detail recurring expense",
+		Tags:        []string{"Recurring Expense"},
+		Security:    bearerSecurity,
+	}, recurringExpenseHandler.DetailRecurringExpense)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "recurring-expense-update",
+		Method:      http.MethodPost,
+		Path:        "/recurring-expenses/{recurringExpenseId}",
+		Summary:     "Update recurring expense",
+		Tags:        []string{"Recurring Expense"},
+		Security:    bearerSecurity,
+	}, recurringExpenseHandler.UpdateRecurringExpense)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "recurring-expense-delete",
+		Method:      http.MethodDelete,
+		Path:        "/recurring-expenses/{recurringExpenseId}",
+		Summary:     "Delete recurring expense",
+		Tags:        []string{"Recurring Expense"},
+		Security:    bearerSecurity,
+	}, recurringExpenseHandler.DeleteRecurringExpense)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "recurring-expense-preview",
+		Method:      http.MethodGet,
+		Path:        "/recurring-expenses/{recurringExpenseId}/preview",
+		Summary:     "Preview next occurrences",
+		Tags:        []string{"Recurring Expense"},
+		Security:    bearerSecurity,
+	}, recurringExpenseHandler.PreviewRecurringExpense)
+
+	incomeHandler := v1.NewIncomeHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "income-list",
+		Method:      http.MethodGet,
+		Path:        "/incomes",
+		Summary:     "List incomes",
+		Tags:        []string{"Income"},
+		Security:    bearerSecurity,
+	}, incomeHandler.ListIncome)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "income-create",
+		Method:      http.MethodPost,
+		Path:        "/incomes",
+		Summary:     "Create income",
+		Tags:        []string{"Income"},
+		Security:    bearerSecurity,
+	}, incomeHandler.CreateIncome)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "income-detail",
+		Method:      http.MethodGet,
+		Path:        "/incomes/{incomeId}",
+		Summary:     "Detail income",
+		Tags:        []string{"Income"},
+		Security:    bearerSecurity,
+	}, incomeHandler.DetailIncome)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "income-update",
+		Method:      http.MethodPost,
+		Path:        "/incomes/{incomeId}",
+		Summary:     "Update income",
+		Tags:        []string{"Income"},
+		Security:    bearerSecurity,
+	}, incomeHandler.UpdateIncome)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "income-delete",
+		Method:      http.MethodDelete,
+		Path:        "/incomes/{incomeId}",
+		Summary:     "Delete income",
+		Tags:        []string{"Income"},
+		Security:    bearerSecurity,
+	}, incomeHandler.DeleteIncome)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "income-restore",
+		Method:      http.MethodPost,
+		Path:        "/incomes/{incomeId}/restore",
+		Summary:     "Restore deleted income",
+		Tags:        []string{"Income"},
+		Security:    bearerSecurity,
+	}, incomeHandler.RestoreIncome)
+
+	cashFlowHandler := v1.NewCashFlowHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "cash-flow",
+		Method:      http.MethodGet,
+		Path:        "/cash-flow",
+		Summary:     "Income, expenses and net cash flow",
+		Tags:        []string{"Cash Flow"},
+		Security:    bearerSecurity,
+	}, cashFlowHandler.GetCashFlow)
+
+	walletHandler := v1.NewWalletHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "wallet-list",
+		Method:      http.MethodGet,
+		Path:        "/wallets",
+		Summary:     "List wallets with their balances",
+		Tags:        []string{"Wallet"},
+		Security:    bearerSecurity,
+	}, walletHandler.ListWallet)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "wallet-create",
+		Method:      http.MethodPost,
+		Path:        "/wallets",
+		Summary:     "Create wallet",
+		Tags:        []string{"Wallet"},
+		Security:    bearerSecurity,
+	}, walletHandler.CreateWallet)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "wallet-detail",
+		Method:      http.MethodGet,
+		Path:        "/wallets/{walletId}",
+		Summary:     "Detail wallet",
+		Tags:        []string{"Wallet"},
+		Security:    bearerSecurity,
+	}, walletHandler.DetailWallet)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "wallet-update",
+		Method:      http.MethodPost,
+		Path:        "/wallets/{walletId}",
+		Summary:     "Update wallet",
+		Tags:        []string{"Wallet"},
+		Security:    bearerSecurity,
+	}, walletHandler.UpdateWallet)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "wallet-delete",
+		Method:      http.MethodDelete,
+		Path:        "/wallets/{walletId}",
+		Summary:     "Delete wallet",
+		Tags:        []string{"Wallet"},
+		Security:    bearerSecurity,
+	}, walletHandler.DeleteWallet)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "wallet-ledger",
+		Method:      http.MethodGet,
+		Path:        "/wallets/{walletId}/ledger",
+		Summary:     "Wallet entries with running balances",
+		Tags:        []string{"Wallet"},
+		Security:    bearerSecurity,
+	}, walletHandler.GetWalletLedger)
+
+	transferHandler := v1.NewTransferHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "transfer-list",
+		Method:      http.MethodGet,
+		Path:        "/transfers",
+		Summary:     "List transfers",
+		Tags:        []string{"Transfer"},
+		Security:    bearerSecurity,
+	}, transferHandler.ListTransfer)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "transfer-create",
+		Method:      http.MethodPost,
+		Path:        "/transfers",
+		Summary:     "Create transfer",
+		Tags:        []string{"Transfer"},
+		Security:    bearerSecurity,
+	}, transferHandler.CreateTransfer)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "transfer-detail",
+		Method:      http.MethodGet,
+		Path:        "/transfers/{transferId}",
+		Summary:     "Detail transfer",
+		Tags:        []string{"Transfer"},
+		Security:    bearerSecurity,
+	}, transferHandler.DetailTransfer)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "transfer-update",
+		Method:      http.MethodPost,
+		Path:        "/transfers/{transferId}",
+		Summary:     "Update transfer",
+		Tags:        []string{"Transfer"},
+		Security:    bearerSecurity,
+	}, transferHandler.UpdateTransfer)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "transfer-delete",
+		Method:      http.MethodDelete,
+		Path:        "/transfers/{transferId}",
+		Summary:     "Delete transfer",
+		Tags:        []string{"Transfer"},
+		Security:    bearerSecurity,
+	}, transferHandler.DeleteTransfer)
+
+	groupHandler := v1.NewGroupHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-list",
+		Method:      http.MethodGet,
+		Path:        "/groups",
+		Summary:     "List groups",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.ListGroup)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-create",
+		Method:      http.MethodPost,
+		Path:        "/groups",
+		Summary:     "Create group",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.CreateGroup)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-detail",
+		Method:      http.MethodGet,
+		Path:        "/groups/{groupId}",
+		Summary:     "Detail group with its members",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.DetailGroup)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-update",
+		Method:      http.MethodPost,
+		Path:        "/groups/{groupId}",
+		Summary:     "Rename group",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.UpdateGroup)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-delete",
+		Method:      http.MethodDelete,
+		Path:        "/groups/{groupId}",
+		Summary:     "Delete group",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.DeleteGroup)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-expense-list",
+		Method:      http.MethodGet,
+		Path:        "/groups/{groupId}/expenses",
+		Summary:     "List expenses shared with the group",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.ListGroupExpense)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-overview",
+		Method:      http.MethodGet,
+		Path:        "/groups/{groupId}/overview",
+		Summary:     "Group expense overview by category and member",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.GetGroupOverview)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-invitation-create",
+		Method:      http.MethodPost,
+		Path:        "/groups/{groupId}/invitations",
+		Summary:     "Invite to group",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.InviteToGroup)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-invitation-list",
+		Method:      http.MethodGet,
+		Path:        "/groups/{groupId}/invitations",
+		Summary:     "List pending invitations of a group",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.ListGroupInvitation)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-member-update",
+		Method:      http.MethodPost,
+		Path:        "/groups/{groupId}/members/{userId}",
+		Summary:     "Change a member's role",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.UpdateGroupMember)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-member-remove",
+		Method:      http.MethodDelete,
+		Path:        "/groups/{groupId}/members/{userId}",
+		Summary:     "Remove a member or leave the group",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.RemoveGroupMember)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "my-group-invitation-list",
+		Method:      http.MethodGet,
+		Path:        "/group-invitations",
+		Summary:     "List invitations to the current user",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.ListMyGroupInvitation)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-invitation-accept",
+		Method:      http.MethodPost,
+		Path:        "/group-invitations/{invitationId}/accept",
+		Summary:     "Accept invitation",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.AcceptGroupInvitation)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-invitation-delete",
+		Method:      http.MethodDelete,
+		Path:        "/group-invitations/{invitationId}",
+		Summary:     "Decline or withdraw invitation",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, groupHandler.DeleteGroupInvitation)
+
+	settlementHandler := v1.NewSettlementHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-balance",
+		Method:      http.MethodGet,
+		Path:        "/groups/{groupId}/balances",
+		Summary:     "Who owes whom in the group, with settle-up transfers",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, settlementHandler.GetGroupBalance)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-settlement-list",
+		Method:      http.MethodGet,
+		Path:        "/groups/{groupId}/settlements",
+		Summary:     "List settlements of the group",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, settlementHandler.ListGroupSettlement)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-settlement-create",
+		Method:      http.MethodPost,
+		Path:        "/groups/{groupId}/settlements",
+		Summary:     "Record a settlement between members",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, settlementHandler.CreateGroupSettlement)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "group-settlement-delete",
+		Method:      http.MethodDelete,
+		Path:        "/groups/{groupId}/settlements/{settlementId}",
+		Summary:     "Delete settlement",
+		Tags:        []string{"Group"},
+		Security:    bearerSecurity,
+	}, settlementHandler.DeleteGroupSettlement)
+
+	attachmentHandler := v1.NewAttachmentHandler(s.db, s.attachments)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "attachment-list",
+		Method:      http.MethodGet,
+		Path:        "/expenses/{expenseId}/attachments",
+		Summary:     "List attachments of an expense",
+		Tags:        []string{"Attachment"},
+		Security:    bearerSecurity,
+	}, attachmentHandler.ListAttachment)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID:   "attachment-upload",
+		Method:        http.MethodPost,
+		Path:          "/expenses/{expenseId}/attachments",
+		Summary:       "Attach a receipt to an expense",
+		Tags:          []string{"Attachment"},
+		Security:      bearerSecurity,
+		DefaultStatus: http.StatusCreated,
+		MaxBodyBytes:  v1.MaxAttachmentRequestBytes,
+		Middlewares:   huma.Middlewares{gastoslogMiddleware.NewMultipartFormMiddleware(apiV1)},
+	}, attachmentHandler.UploadAttachment)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "attachment-download",
+		Method:      http.MethodGet,
+		Path:        "/expenses/{expenseId}/attachments/{attachmentId}",
+		Summary:     "Download attachment",
+		Tags:        []string{"Attachment"},
+		Security:    bearerSecurity,
+	}, attachmentHandler.DownloadAttachment)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "attachment-delete",
+		Method:      http.MethodDelete,
+		Path:        "/expenses/{expenseId}/attachments/{attachmentId}",
+		Summary:     "Delete attachment",
+		Tags:        []string{"Attachment"},
+		Security:    bearerSecurity,
+	}, attachmentHandler.DeleteAttachment)
+
+	importProfileHandler := v1.NewImportProfileHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "import-profile-list",
+		Method:      http.MethodGet,
+		Path:        "/import-profiles",
+		Summary:     "List CSV import profiles",
+		Tags:        []string{"Import"},
+		Security:    bearerSecurity,
+	}, importProfileHandler.ListImportProfile)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID:   "import-profile-create",
+		Method:        http.MethodPost,
+		Path:          "/import-profiles",
+		Summary:       "Create CSV import profile",
+		Tags:          []string{"Import"},
+		Security:      bearerSecurity,
+		DefaultStatus: http.StatusCreated,
+	}, importProfileHandler.CreateImportProfile)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "import-profile-detail",
+		Method:      http.MethodGet,
+		Path:        "/import-profiles/{profileId}",
+		Summary:     "Get CSV import profile",
+		Tags:        []string{"Import"},
+		Security:    bearerSecurity,
+	}, importProfileHandler.DetailImportProfile)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "import-profile-update",
+		Method:      http.MethodPost,
+		Path:        "/import-profiles/{profileId}",
+		Summary:     "Update CSV import profile",
+		Tags:        []string{"Import"},
+		Security:    bearerSecurity,
+	}, importProfileHandler.UpdateImportProfile)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "import-profile-delete",
+		Method:      http.MethodDelete,
+		Path:        "/import-profiles/{profileId}",
+		Summary:     "Delete CSV import profile",
+		Tags:        []string{"Import"},
+		Security:    bearerSecurity,
+	}, importProfileHandler.DeleteImportProfile)
+
+	importHandler := v1.NewImportHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "import-csv-preview",
+		Method:      http.MethodPost,
+		Path:        "/imports/csv",
+		Summary:     "Preview the rows of a CSV statement",
+		Tags:        []string{"Import"},
+		Security:    bearerSecurity,
+	}, importHandler.PreviewCSVImport)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "import-ofx-preview",
+		Method:      http.MethodPost,
+		Path:        "/imports/ofx",
+		Summary:     "Preview the transactions of an OFX or QFX statement",
+		Tags:        []string{"Import"},
+		Security:    bearerSecurity,
+	}, importHandler.PreviewOFXImport)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID:   "import-commit",
+		Method:        http.MethodPost,
+		Path:          "/imports/commit",
+		Summary:       "Import previewed statement rows as expenses",
+		Tags:          []string{"Import"},
+		Security:      bearerSecurity,
+		DefaultStatus: http.StatusCreated,
+	}, importHandler.CommitImport)
+
+	exportHandler := v1.NewExportHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "expense-export",
+		Method:      http.MethodGet,
+		Path:        "/expenses/export",
+		Summary:     "Export expenses",
+		Tags:        []string{"Expense"},
+		Security:    bearerSecurity,
+	}, exportHandler.ExportExpense)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "expense-journal",
+		Method:      http.MethodGet,
+		Path:        "/expenses/journal",
+		Summary:     "Export expenses as a ledger, hledger or beancount journal",
+		Tags:        []string{"Expense"},
+		Security:    bearerSecurity,
+	}, exportHandler.ExportJournal)
+
+	trashHandler := v1.NewTrashHandler(s.db, s.trashRetention)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "trash-list",
+		Method:      http.MethodGet,
+		Path:        "/trash",
+		Summary:     "List deleted expenses and categories",
+		Tags:        []string{"Trash"},
+		Security:    bearerSecurity,
+	}, trashHandler.ListTrash)
+
+	exchangeRateHandler := v1.NewExchangeRateHandler(s.db)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID: "exchange-rate-list",
+		Method:      http.MethodGet,
+		Path:        "/exchange-rates",
+		Summary:     "List exchange rates in effect on a date",
+		Tags:        []string{"Exchange Rate"},
+		Security:    bearerSecurity,
+	}, exchangeRateHandler.ListExchangeRates)
+
+	huma.Register(apiV1, huma.Operation{
+		OperationID:  "exchange-rate-import",
+		Method:       http.MethodPost,
+		Path:         "/admin/exchange-rates/import",
+		Summary:      "Import exchange rates from an ECB XML or CSV file",
+		Tags:         []string{"Exchange Rate"},
+		Security:     bearerSecurity,
+		MaxBodyBytes: 32 << 20,
+	}, exchangeRateHandler.ImportExchangeRates)
+
 	return r
 }
 
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"gastoslog/internal/database"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -29,3 +30,26 @@ func TestHandler(t *testing.T) {
 		t.Errorf("expected response body to be %v; got %v", expected, string(body))
 	}
 }
+
+func TestRegisterRoutes(t *testing.T) {
+	db, err := database.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	// huma panics while registering an operation whose schema names clash
+	defer func() {
+		if p := recover(); p != nil {
+			t.Fatalf("RegisterRoutes panicked: %v", p)
+		}
+	}()
+	s := &Server{db: database.NewService(db)}
+	handler := s.RegisterRoutes()
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if resp.Code != http.StatusOK {
+		t.Errorf("expected the OpenAPI document; got %d", resp.Code)
+	}
+}
@@ -1,26 +1,57 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"gastoslog/internal/config"
 	"gastoslog/internal/database"
+	"gastoslog/internal/jobs"
+	"gastoslog/internal/storage"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 )
 
+const defaultTrashRetentionDays = 30
+
+const defaultAttachmentsDir = "./db/attachments"
+
 type Server struct {
 	port int
 
 	db database.Service
+
+	trashRetention time.Duration
+
+	attachments *storage.Local
 }
 
 func NewServer() *http.Server {
 	port, _ := strconv.Atoi(config.PORT)
+
+	trashRetentionDays, err := strconv.Atoi(config.TRASH_RETENTION_DAYS)
+	if err != nil || trashRetentionDays <= 0 {
+		trashRetentionDays = defaultTrashRetentionDays
+	}
+
+	attachmentsDir := config.ATTACHMENTS_DIR
+	if attachmentsDir == "" {
+		attachmentsDir = defaultAttachmentsDir
+	}
+	attachments, err := storage.NewLocal(attachmentsDir)
+	if err != nil {
+		log.Fatalf("Failed to open attachment store %s: %v", attachmentsDir, err)
+	}
+
 	NewServer := &Server{
 		port: port,
 
 		db: database.New(),
+
+		trashRetention: time.Duration(trashRetentionDays) * 24 * time.Hour,
+
+		attachments: attachments,
 	}
 
 	// Declare Server config
@@ -32,5 +63,12 @@ func NewServer() *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	server.RegisterOnShutdown(stopJobs)
+
+	go jobs.NewTrashPurger(NewServer.db, NewServer.attachments, NewServer.trashRetention, time.Hour).Run(jobsCtx)
+	go jobs.NewRecurringMaterializer(NewServer.db, 5*time.Minute).Run(jobsCtx)
+
 	return server
 }
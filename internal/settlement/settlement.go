@@ -0,0 +1,176 @@
+// Package settlement divides shared bills between people and works out who
+// should pay whom to settle up. Amounts are integers in a currency's minor
+// unit.
+package settlement
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrNoParticipants = errors.New("a bill needs at least one participant")
+	ErrPercentTotal   = errors.New("percentages must add up to 100")
+	ErrExactTotal     = errors.New("exact shares must add up to the amount")
+	ErrNegativeShare  = errors.New("shares cannot be negative")
+)
+
+// Equal divides amount into n shares that differ by at most one minor unit.
+// The first shares take the remainder, so the shares always add up.
+func Equal(amount int64, n int) ([]int64, error) {
+	if n <= 0 {
+		return nil, ErrNoParticipants
+	}
+
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return byWeight(amount, weights), nil
+}
+
+// Percent divides amount by percentages that add up to 100. Rounding is
+// settled with the largest remainder method, so the shares always add up.
+func Percent(amount int64, percents []float64) ([]int64, error) {
+	if len(percents) == 0 {
+		return nil, ErrNoParticipants
+	}
+
+	var total float64
+	for _, percent := range percents {
+		if percent < 0 {
+			return nil, ErrNegativeShare
+		}
+		total += percent
+	}
+	// Allow for percentages such as 33.33 given to two decimal places
+	if total < 99.99 || total > 100.01 {
+		return nil, ErrPercentTotal
+	}
+
+	return byWeight(amount, percents), nil
+}
+
+// Exact checks shares given as amounts, which must add up to amount.
+func Exact(amount int64, shares []int64) ([]int64, error) {
+	if len(shares) == 0 {
+		return nil, ErrNoParticipants
+	}
+
+	var total int64
+	for _, share := range shares {
+		if share < 0 {
+			return nil, ErrNegativeShare
+		}
+		total += share
+	}
+	if total != amount {
+		return nil, ErrExactTotal
+	}
+
+	return shares, nil
+}
+
+// byWeight divides amount in proportion to weights, handing the minor units
+// lost to rounding down to the shares with the largest remainders, ties
+// going to the earlier share.
+func byWeight(amount int64, weights []float64) []int64 {
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+
+	shares := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	var allocated int64
+	for i, weight := range weights {
+		exact := float64(amount) * weight / total
+		shares[i] = int64(exact)
+		remainders[i] = exact - float64(shares[i])
+		allocated += shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+	for i := 0; allocated < amount; i++ {
+		shares[order[i%len(order)]]++
+		allocated++
+	}
+
+	return shares
+}
+
+// Transfer is a payment that settles part of the balances.
+type Transfer struct {
+	From   int64
+	To     int64
+	Amount int64
+}
+
+// SettleUp suggests transfers that bring every balance to zero. Balances
+// map a person to what they are owed, negative when they owe, and must add
+// up to zero. The largest debt is repeatedly paid to the largest credit, so
+// n people never need more than n-1 transfers and anyone owed exactly what
+// another owes is paid in one go.
+func SettleUp(balances map[int64]int64) []Transfer {
+	type balance struct {
+		id     int64
+		amount int64
+	}
+
+	var creditors, debtors []balance
+	for id, amount := range balances {
+		switch {
+		case amount > 0:
+			creditors = append(creditors, balance{id, amount})
+		case amount < 0:
+			debtors = append(debtors, balance{id, -amount})
+		}
+	}
+
+	// Largest first, by ID on ties, so the suggestion is stable
+	byAmount := func(list []balance) {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].amount != list[j].amount {
+				return list[i].amount > list[j].amount
+			}
+			return list[i].id < list[j].id
+		})
+	}
+
+	transfers := []Transfer{}
+	for len(creditors) > 0 && len(debtors) > 0 {
+		byAmount(creditors)
+		byAmount(debtors)
+
+		// Prefer a creditor owed exactly the largest debt
+		debtor := debtors[0]
+		match := 0
+		for i, creditor := range creditors {
+			if creditor.amount == debtor.amount {
+				match = i
+				break
+			}
+		}
+		creditor := creditors[match]
+
+		amount := min(debtor.amount, creditor.amount)
+		transfers = append(transfers, Transfer{From: debtor.id, To: creditor.id, Amount: amount})
+
+		debtors[0].amount -= amount
+		creditors[match].amount -= amount
+		if debtors[0].amount == 0 {
+			debtors = debtors[1:]
+		}
+		if creditors[match].amount == 0 {
+			creditors = append(creditors[:match], creditors[match+1:]...)
+		}
+	}
+
+	return transfers
+}
@@ -0,0 +1,93 @@
+package settlement
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	shares, err := Equal(1000, 3)
+	if err != nil {
+		t.Fatalf("Equal failed: %v", err)
+	}
+	if want := []int64{334, 333, 333}; !reflect.DeepEqual(shares, want) {
+		t.Errorf("Equal = %v; want %v", shares, want)
+	}
+
+	if _, err := Equal(1000, 0); err != ErrNoParticipants {
+		t.Errorf("expected ErrNoParticipants; got %v", err)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	shares, err := Percent(10001, []float64{50, 25, 25})
+	if err != nil {
+		t.Fatalf("Percent failed: %v", err)
+	}
+	if want := []int64{5001, 2500, 2500}; !reflect.DeepEqual(shares, want) {
+		t.Errorf("Percent = %v; want %v", shares, want)
+	}
+
+	// Thirds to two decimal places still add up to the amount
+	shares, err = Percent(100, []float64{33.33, 33.33, 33.34})
+	if err != nil || shares[0]+shares[1]+shares[2] != 100 {
+		t.Errorf("expected shares adding up to 100; got %v, %v", shares, err)
+	}
+
+	if _, err := Percent(100, []float64{50, 40}); err != ErrPercentTotal {
+		t.Errorf("expected ErrPercentTotal; got %v", err)
+	}
+}
+
+func TestExact(t *testing.T) {
+	if _, err := Exact(1000, []int64{600, 400}); err != nil {
+		t.Errorf("Exact failed: %v", err)
+	}
+	if _, err := Exact(1000, []int64{600, 300}); err != ErrExactTotal {
+		t.Errorf("expected ErrExactTotal; got %v", err)
+	}
+	if _, err := Exact(1000, []int64{1100, -100}); err != ErrNegativeShare {
+		t.Errorf("expected ErrNegativeShare; got %v", err)
+	}
+}
+
+func TestSettleUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances map[int64]int64
+		want     []Transfer
+	}{
+		{
+			name:     "settled",
+			balances: map[int64]int64{1: 0, 2: 0},
+			want:     []Transfer{},
+		},
+		{
+			name:     "one payer",
+			balances: map[int64]int64{1: 2000, 2: -1000, 3: -1000},
+			want:     []Transfer{{From: 2, To: 1, Amount: 1000}, {From: 3, To: 1, Amount: 1000}},
+		},
+		{
+			name:     "exact match first",
+			balances: map[int64]int64{1: 500, 2: 300, 3: -300, 4: -500},
+			want:     []Transfer{{From: 4, To: 1, Amount: 500}, {From: 3, To: 2, Amount: 300}},
+		},
+		{
+			name:     "chain",
+			balances: map[int64]int64{1: 700, 2: -200, 3: -500},
+			want:     []Transfer{{From: 3, To: 1, Amount: 500}, {From: 2, To: 1, Amount: 200}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SettleUp(tt.balances)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SettleUp = %v; want %v", got, tt.want)
+			}
+			if len(got) >= max(len(tt.balances), 1) {
+				t.Errorf("expected fewer than %d transfers; got %d", len(tt.balances), len(got))
+			}
+		})
+	}
+}
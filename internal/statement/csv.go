@@ -0,0 +1,121 @@
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseCSV reads a CSV statement laid out as the mapping describes. Rows that
+// cannot be read are returned with Err set so they can be reviewed; only a
+// file that cannot be read at all, or lacks a mapped column, fails.
+func ParseCSV(r io.Reader, mapping Mapping) ([]Row, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	layout, _ := DateLayout(mapping.DateFormat)
+
+	br := bufio.NewReader(r)
+	// Spreadsheet exports often start with a byte order mark
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		_, _ = br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = []rune(mapping.Delimiter)[0]
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var header []string
+	if mapping.HasHeader {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("parse CSV statement: %w", err)
+		}
+		header = record
+	}
+
+	dateIndex, err := columnIndex(header, mapping.DateColumn)
+	if err != nil {
+		return nil, err
+	}
+	amountIndex, err := columnIndex(header, mapping.AmountColumn)
+	if err != nil {
+		return nil, err
+	}
+	descriptionIndex := -1
+	if mapping.DescriptionColumn != "" {
+		if descriptionIndex, err = columnIndex(header, mapping.DescriptionColumn); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := []Row{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse CSV statement: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line}
+		field := func(index int) string {
+			if index < 0 || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		row.Description = field(descriptionIndex)
+		if max(dateIndex, amountIndex, descriptionIndex) >= len(record) {
+			row.Err = fmt.Errorf("line has %d columns", len(record))
+			rows = append(rows, row)
+			continue
+		}
+
+		date, err := time.Parse(layout, field(dateIndex))
+		if err != nil {
+			row.Err = fmt.Errorf("invalid date %q, expected %s", field(dateIndex), mapping.DateFormat)
+			rows = append(rows, row)
+			continue
+		}
+		row.Date = date
+
+		amount, err := ParseAmount(field(amountIndex), mapping.DecimalSeparator)
+		if err != nil {
+			row.Err = err
+			rows = append(rows, row)
+			continue
+		}
+		row.classify(amount, mapping.ExpenseSign)
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// columnIndex finds a column by header name, ignoring case, or by its 1-based
+// number.
+func columnIndex(header []string, column string) (int, error) {
+	column = strings.TrimSpace(column)
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(column); err == nil && n >= 1 {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("parse CSV statement: missing %q column", column)
+}
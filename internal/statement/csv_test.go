@@ -0,0 +1,110 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDateLayout(t *testing.T) {
+	tests := map[string]string{
+		"YYYY-MM-DD":       "2006-01-02",
+		"DD/MM/YYYY":       "02/01/2006",
+		"M/D/YY":           "1/2/06",
+		"DD MMM YYYY":      "02 Jan 2006",
+		"YYYY-MM-DDTHH:mm": "2006-01-02T15:04",
+	}
+	for format, want := range tests {
+		if layout, err := DateLayout(format); err != nil || layout != want {
+			t.Errorf("DateLayout(%q) = %q, %v; want %q", format, layout, err, want)
+		}
+	}
+
+	for _, format := range []string{"MM/YYYY", "DD.MM.YYYY x", "DD_MM_YYYY"} {
+		if _, err := DateLayout(format); err == nil {
+			t.Errorf("DateLayout(%q): expected an error", format)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value   string
+		decimal string
+		want    float64
+	}{
+		{"-1,234.50", ".", -1234.50},
+		{"₱ 1,234.50", ".", 1234.50},
+		{"(45.00)", ".", -45},
+		{"45.00-", ".", -45},
+		{"-1.234,50 €", ",", -1234.50},
+	}
+	for _, test := range tests {
+		if amount, err := ParseAmount(test.value, test.decimal); err != nil || amount != test.want {
+			t.Errorf("ParseAmount(%q) = %v, %v; want %v", test.value, amount, err, test.want)
+		}
+	}
+
+	if _, err := ParseAmount("n/a", "."); err == nil {
+		t.Error("expected an error for an amount without digits")
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := "\xef\xbb\xbfPosting Date,Details,Amount\n" +
+		"03/01/2024,Jollibee Makati,\"-1,250.00\"\n" +
+		"04/01/2024,Salary,\"50,000.00\"\n" +
+		"2024-01-05,Meralco,-3200\n" +
+		"06/01/2024,Grab\n"
+
+	rows, err := ParseCSV(strings.NewReader(input), Mapping{
+		HasHeader:         true,
+		DateColumn:        "posting date",
+		AmountColumn:      "Amount",
+		DescriptionColumn: "Details",
+		DateFormat:        "DD/MM/YYYY",
+	})
+	if err != nil {
+		t.Fatalf("ParseCSV failed: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows; got %+v", rows)
+	}
+
+	lunch := rows[0]
+	if lunch.Err != nil || lunch.Inflow || lunch.Amount != 1250 || lunch.Description != "Jollibee Makati" || lunch.Date.Format("2006-01-02") != "2024-01-03" || lunch.Line != 2 {
+		t.Errorf("unexpected expense row %+v", lunch)
+	}
+	if !rows[1].Inflow || rows[1].Amount != 50000 {
+		t.Errorf("expected the salary as an inflow; got %+v", rows[1])
+	}
+	if rows[2].Err == nil || rows[2].Description != "Meralco" {
+		t.Errorf("expected an invalid date; got %+v", rows[2])
+	}
+	if rows[3].Err == nil {
+		t.Errorf("expected a short line to be invalid; got %+v", rows[3])
+	}
+}
+
+func TestParseCSVWithoutHeader(t *testing.T) {
+	input := "15.01.2024;GROCERY;1.234,50\n16.01.2024;REFUND;-20,00\n"
+
+	rows, err := ParseCSV(strings.NewReader(input), Mapping{
+		Delimiter:         ";",
+		DateColumn:        "1",
+		AmountColumn:      "3",
+		DescriptionColumn: "2",
+		DateFormat:        "DD.MM.YYYY",
+		ExpenseSign:       SignPositive,
+		DecimalSeparator:  ",",
+	})
+	if err != nil {
+		t.Fatalf("ParseCSV failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Inflow || rows[0].Amount != 1234.50 || !rows[1].Inflow {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+
+	if _, err := ParseCSV(strings.NewReader(input), Mapping{HasHeader: true, DateColumn: "Date", AmountColumn: "Amount"}); err == nil {
+		t.Error("expected an error for a missing column")
+	}
+}
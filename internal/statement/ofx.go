@@ -0,0 +1,155 @@
+package statement
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// OFXStatement is a bank or credit card statement read from an OFX or QFX
+// file.
+type OFXStatement struct {
+	// Account identifies the account the statement is for, as a hash of its
+	// bank and account numbers so they are never stored. Empty when the file
+	// does not say.
+	Account string
+	// Currency is the ISO 4217 code of the amounts, empty when the file does
+	// not say
+	Currency string
+	Rows     []Row
+}
+
+// ParseOFX reads the STMTTRN records of an OFX 1.x (SGML) or 2.x (XML)
+// statement, which QFX files are too. Both are read the same way: element
+// values run up to the next tag, so the closing tags SGML leaves out are not
+// needed. Amounts follow the OFX convention of negative debits.
+func ParseOFX(r io.Reader) (*OFXStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse OFX statement: %w", err)
+	}
+
+	content := string(data)
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("parse OFX statement: no <OFX> element")
+	}
+
+	var (
+		result         = &OFXStatement{Rows: []Row{}}
+		bankID, acctID string
+		current        map[string]string
+		currentLine    int
+	)
+
+	line := 1 + strings.Count(content[:start], "\n")
+	rest := content[start:]
+	for {
+		open := strings.IndexByte(rest, '<')
+		if open < 0 {
+			break
+		}
+		line += strings.Count(rest[:open], "\n")
+		rest = rest[open:]
+
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			return nil, fmt.Errorf("parse OFX statement: line %d: unterminated tag", line)
+		}
+		tag := strings.ToUpper(strings.TrimSpace(rest[1:end]))
+		rest = rest[end+1:]
+
+		// Processing instructions, comments and empty tags carry nothing
+		if tag == "" || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+
+		if tag[0] == '/' {
+			if tag[1:] == "STMTTRN" && current != nil {
+				row := ofxRow(current)
+				row.Line = currentLine
+				result.Rows = append(result.Rows, row)
+				current = nil
+			}
+			continue
+		}
+
+		// XML attributes are not used by OFX
+		name, _, _ := strings.Cut(strings.TrimSuffix(tag, "/"), " ")
+		next := strings.IndexByte(rest, '<')
+		if next < 0 {
+			next = len(rest)
+		}
+		value := html.UnescapeString(strings.TrimSpace(rest[:next]))
+
+		switch {
+		case name == "STMTTRN":
+			current = map[string]string{}
+			currentLine = line
+		case value == "":
+			// An aggregate such as BANKACCTFROM
+		case current != nil:
+			// The payee's NAME aggregate repeats NAME; keep the first
+			if _, ok := current[name]; !ok {
+				current[name] = value
+			}
+		case name == "CURDEF" && result.Currency == "":
+			result.Currency = strings.ToUpper(value)
+		case name == "BANKID" && bankID == "":
+			bankID = value
+		case name == "ACCTID" && acctID == "":
+			acctID = value
+		}
+	}
+
+	if acctID != "" {
+		hash := sha256.Sum256([]byte(bankID + "/" + acctID))
+		result.Account = hex.EncodeToString(hash[:])
+	}
+
+	return result, nil
+}
+
+// ofxRow turns the elements of a STMTTRN record into a row.
+func ofxRow(fields map[string]string) Row {
+	row := Row{FITID: fields["FITID"], Description: fields["NAME"]}
+	if row.Description == "" {
+		row.Description = fields["MEMO"]
+	}
+
+	posted := fields["DTPOSTED"]
+	if posted == "" {
+		posted = fields["DTUSER"]
+	}
+	// Dates are YYYYMMDD optionally followed by a time and a time zone; the
+	// day is enough for an expense
+	if len(posted) < 8 {
+		row.Err = fmt.Errorf("invalid date %q", posted)
+		return row
+	}
+	date, err := time.Parse("20060102", posted[:8])
+	if err != nil {
+		row.Err = fmt.Errorf("invalid date %q", posted)
+		return row
+	}
+	row.Date = date
+
+	// OFX has no thousands separators, but some banks write a decimal comma
+	decimalSeparator := "."
+	if !strings.Contains(fields["TRNAMT"], ".") {
+		decimalSeparator = ","
+	}
+	amount, err := ParseAmount(fields["TRNAMT"], decimalSeparator)
+	if err != nil {
+		row.Err = err
+		return row
+	}
+	row.classify(amount, SignNegative)
+
+	return row
+}
@@ -0,0 +1,129 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+)
+
+const ofxSGMLSample = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>php
+<BANKACCTFROM>
+<BANKID>010
+<ACCTID>1234567890
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101
+<DTEND>20240131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240103120000.000[+8:PHT]
+<TRNAMT>-1250.00
+<FITID>2024010301
+<NAME>JOLLIBEE MAKATI
+<MEMO>POS PURCHASE
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240115
+<TRNAMT>50000.00
+<FITID>2024011501
+<MEMO>PAYROLL
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>2024
+<TRNAMT>-10.00
+<FITID>2024011502
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXMLSample = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+	<CREDITCARDMSGSRSV1>
+		<CCSTMTTRNRS>
+			<CCSTMTRS>
+				<CURDEF>USD</CURDEF>
+				<CCACCTFROM><ACCTID>4111111111111111</ACCTID></CCACCTFROM>
+				<BANKTRANLIST>
+					<STMTTRN>
+						<TRNTYPE>DEBIT</TRNTYPE>
+						<DTPOSTED>20240205</DTPOSTED>
+						<TRNAMT>-42,50</TRNAMT>
+						<FITID>CC-001</FITID>
+						<PAYEE><NAME>Barnes &amp; Noble</NAME></PAYEE>
+					</STMTTRN>
+				</BANKTRANLIST>
+			</CCSTMTRS>
+		</CCSTMTTRNRS>
+	</CREDITCARDMSGSRSV1>
+</OFX>`
+
+func TestParseOFXSGML(t *testing.T) {
+	parsed, err := ParseOFX(strings.NewReader(ofxSGMLSample))
+	if err != nil {
+		t.Fatalf("ParseOFX failed: %v", err)
+	}
+	if parsed.Currency != "PHP" || len(parsed.Account) != 64 {
+		t.Errorf("unexpected statement currency %q and account %q", parsed.Currency, parsed.Account)
+	}
+	if len(parsed.Rows) != 3 {
+		t.Fatalf("expected 3 rows; got %+v", parsed.Rows)
+	}
+
+	lunch := parsed.Rows[0]
+	if lunch.Err != nil || lunch.Inflow || lunch.Amount != 1250 || lunch.FITID != "2024010301" || lunch.Description != "JOLLIBEE MAKATI" || lunch.Date.Format("2006-01-02") != "2024-01-03" || lunch.Line != 24 {
+		t.Errorf("unexpected expense row %+v", lunch)
+	}
+	if payroll := parsed.Rows[1]; !payroll.Inflow || payroll.Description != "PAYROLL" {
+		t.Errorf("expected the payroll as an inflow described by its memo; got %+v", payroll)
+	}
+	if parsed.Rows[2].Err == nil || parsed.Rows[2].FITID != "2024011502" {
+		t.Errorf("expected an invalid date with its FITID kept; got %+v", parsed.Rows[2])
+	}
+}
+
+func TestParseOFXXML(t *testing.T) {
+	parsed, err := ParseOFX(strings.NewReader(ofxXMLSample))
+	if err != nil {
+		t.Fatalf("ParseOFX failed: %v", err)
+	}
+	if parsed.Currency != "USD" || len(parsed.Rows) != 1 {
+		t.Fatalf("unexpected statement %+v", parsed)
+	}
+
+	row := parsed.Rows[0]
+	if row.Err != nil || row.Inflow || row.Amount != 42.5 || row.FITID != "CC-001" || row.Description != "Barnes & Noble" {
+		t.Errorf("unexpected row %+v", row)
+	}
+
+	// The same account always hashes the same, and differently from others
+	again, _ := ParseOFX(strings.NewReader(ofxXMLSample))
+	other, _ := ParseOFX(strings.NewReader(ofxSGMLSample))
+	if again.Account != parsed.Account || other.Account == parsed.Account {
+		t.Errorf("unexpected account keys %q, %q, %q", parsed.Account, again.Account, other.Account)
+	}
+
+	if _, err := ParseOFX(strings.NewReader("Date,Amount\n")); err == nil {
+		t.Error("expected an error for a file without an OFX element")
+	}
+}
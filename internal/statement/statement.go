@@ -0,0 +1,215 @@
+// Package statement reads bank and e-wallet statements into rows that can be
+// reviewed and imported as expenses.
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sign tells which sign a statement gives to money spent.
+type Sign string
+
+const (
+	// SignNegative is used by most bank exports: spending is shown as a
+	// negative amount and deposits as positive ones.
+	SignNegative Sign = "negative"
+	// SignPositive is used by most credit card statements: spending is shown
+	// as a positive amount and payments or refunds as negative ones.
+	SignPositive Sign = "positive"
+)
+
+// Row is one transaction of a statement.
+type Row struct {
+	// Line is the 1-based line of the row in the file
+	Line int
+	Date time.Time
+	// Amount is the magnitude of the transaction in major units
+	Amount      float64
+	Description string
+	// FITID is the bank's ID of the transaction, unique within its account.
+	// Only OFX statements have one.
+	FITID string
+	// Inflow marks money coming in, such as a salary credit or a refund,
+	// which is not imported as an expense.
+	Inflow bool
+	// Err is set when the row could not be read; the other fields are then
+	// filled as far as they could be.
+	Err error
+}
+
+// Mapping says where a CSV statement keeps each field and how to read it.
+type Mapping struct {
+	// Delimiter separates fields, e.g. "," or ";". Defaults to ","
+	Delimiter string
+	// HasHeader is set when the first line names the columns
+	HasHeader bool
+	// DateColumn, AmountColumn and DescriptionColumn are header names,
+	// matched ignoring case, or 1-based column numbers. DescriptionColumn is
+	// optional.
+	DateColumn        string
+	AmountColumn      string
+	DescriptionColumn string
+	// DateFormat spells the date with YYYY, YY, MMMM, MMM, MM, M, DD, D, HH,
+	// mm and ss, e.g. DD/MM/YYYY. Defaults to YYYY-MM-DD
+	DateFormat string
+	// ExpenseSign defaults to SignNegative
+	ExpenseSign Sign
+	// DecimalSeparator is "." or ",". Defaults to "."
+	DecimalSeparator string
+}
+
+var dateTokens = []struct {
+	token  string
+	layout string
+}{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MMMM", "January"},
+	{"MMM", "Jan"},
+	{"MM", "01"},
+	{"M", "1"},
+	{"DD", "02"},
+	{"D", "2"},
+	{"HH", "15"},
+	{"mm", "04"},
+	{"ss", "05"},
+}
+
+// DateLayout turns a date format such as DD/MM/YYYY into a time layout.
+// Besides the tokens only punctuation, spaces and T are allowed, and the
+// format must have a year, a month and a day.
+func DateLayout(format string) (string, error) {
+	var layout strings.Builder
+	seen := map[byte]bool{}
+
+	for rest := format; rest != ""; {
+		matched := false
+		for _, t := range dateTokens {
+			if strings.HasPrefix(rest, t.token) {
+				layout.WriteString(t.layout)
+				seen[t.token[0]] = true
+				rest = rest[len(t.token):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		c := rest[0]
+		// Underscores pad numbers in time layouts, so they are refused too
+		if c == 'T' || !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_') {
+			layout.WriteByte(c)
+			rest = rest[1:]
+			continue
+		}
+		return "", fmt.Errorf("invalid date format %q: unexpected %q", format, c)
+	}
+
+	if !seen['Y'] || !seen['M'] || !seen['D'] {
+		return "", fmt.Errorf("invalid date format %q: needs a year, a month and a day", format)
+	}
+	return layout.String(), nil
+}
+
+// Validate checks the mapping and fills in its defaults.
+func (m *Mapping) Validate() error {
+	if m.Delimiter == "" {
+		m.Delimiter = ","
+	}
+	if len([]rune(m.Delimiter)) != 1 || m.Delimiter == `"` || m.Delimiter == "\n" || m.Delimiter == "\r" {
+		return fmt.Errorf("invalid delimiter %q", m.Delimiter)
+	}
+
+	if strings.TrimSpace(m.DateColumn) == "" || strings.TrimSpace(m.AmountColumn) == "" {
+		return errors.New("the date and amount columns are required")
+	}
+	if !m.HasHeader {
+		for _, column := range []string{m.DateColumn, m.AmountColumn, m.DescriptionColumn} {
+			if n, err := strconv.Atoi(column); column != "" && (err != nil || n < 1) {
+				return fmt.Errorf("column %q must be a 1-based number when the file has no header", column)
+			}
+		}
+	}
+
+	if m.DateFormat == "" {
+		m.DateFormat = "YYYY-MM-DD"
+	}
+	if _, err := DateLayout(m.DateFormat); err != nil {
+		return err
+	}
+
+	switch m.ExpenseSign {
+	case "":
+		m.ExpenseSign = SignNegative
+	case SignNegative, SignPositive:
+	default:
+		return fmt.Errorf("invalid expense sign %q", m.ExpenseSign)
+	}
+
+	switch m.DecimalSeparator {
+	case "":
+		m.DecimalSeparator = "."
+	case ".", ",":
+	default:
+		return fmt.Errorf("invalid decimal separator %q", m.DecimalSeparator)
+	}
+
+	return nil
+}
+
+// ParseAmount reads an amount as banks print it. Currency symbols, spaces
+// and thousands separators are ignored; a minus sign before or after the
+// number, or parentheses around it, make it negative.
+func ParseAmount(value string, decimalSeparator string) (float64, error) {
+	s := strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	var number strings.Builder
+	digits := 0
+	for _, c := range s {
+		switch {
+		case '0' <= c && c <= '9':
+			number.WriteRune(c)
+			digits++
+		case string(c) == decimalSeparator:
+			number.WriteByte('.')
+		case c == '-' || c == '−':
+			negative = true
+		}
+	}
+	if digits == 0 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+
+	amount, err := strconv.ParseFloat(number.String(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// classify sets the row's amount and direction from a signed amount.
+func (row *Row) classify(amount float64, sign Sign) {
+	if sign == SignPositive {
+		amount = -amount
+	}
+	row.Inflow = amount > 0
+	row.Amount = math.Abs(amount)
+	if amount == 0 {
+		row.Err = errors.New("amount is zero")
+	}
+}
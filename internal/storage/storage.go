@@ -0,0 +1,123 @@
+// Package storage keeps uploaded files on the local disk under the SHA-256
+// of their content, so a file uploaded twice is stored once.
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid storage key")
+	ErrNotFound   = errors.New("file not found")
+)
+
+// Local stores files in a directory, each at <dir>/<first two hex digits of
+// its key>/<key> to keep directories small.
+type Local struct {
+	dir string
+}
+
+// NewLocal returns a store in dir, creating the directory if needed.
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir}, nil
+}
+
+// Put stores the content of r and returns its key, the hex SHA-256 of the
+// content, along with its size. Storing content that is already there
+// leaves the existing file in place.
+func (l *Local) Put(r io.Reader) (key string, size int64, err error) {
+	tmp, err := os.CreateTemp(l.dir, ".upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	// Removing fails harmlessly once the file has been renamed
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err = io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	key = hex.EncodeToString(hash.Sum(nil))
+	path, _ := l.path(key)
+	if _, err := os.Stat(path); err == nil {
+		return key, size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return key, size, nil
+}
+
+// Open returns the file stored under key, or ErrNotFound.
+func (l *Local) Open(key string) (*os.File, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Exists reports whether a file is stored under key.
+func (l *Local) Exists(key string) (bool, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete removes the file stored under key. Deleting a missing file is not
+// an error.
+func (l *Local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to its file, refusing anything but a lowercase hex
+// SHA-256 so a key can never point outside the store.
+func (l *Local) path(key string) (string, error) {
+	if len(key) != sha256.Size*2 {
+		return "", ErrInvalidKey
+	}
+	for _, c := range key {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return "", ErrInvalidKey
+		}
+	}
+	return filepath.Join(l.dir, key[:2], key), nil
+}
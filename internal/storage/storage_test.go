@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(filepath.Join(dir, "attachments"))
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+
+	key, size, err := store.Put(strings.NewReader("receipt"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	// sha256sum of "receipt"
+	if key != "6f32860910ca0fb2a20c7fda143666b09dbf8db5238195c90a586fb542ff0cad" || size != 7 {
+		t.Errorf("unexpected key %q and size %d", key, size)
+	}
+
+	// The same content is stored once
+	again, _, err := store.Put(strings.NewReader("receipt"))
+	if err != nil || again != key {
+		t.Errorf("expected the same key; got %q, %v", again, err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "attachments", key[:2]))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected one stored file; got %v, %v", entries, err)
+	}
+
+	file, err := store.Open(key)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	content, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || string(content) != "receipt" {
+		t.Errorf("unexpected content %q, %v", content, err)
+	}
+
+	if exists, err := store.Exists(key); !exists || err != nil {
+		t.Errorf("expected the file to exist; got %v, %v", exists, err)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Open(key); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound; got %v", err)
+	}
+	if exists, err := store.Exists(key); exists || err != nil {
+		t.Errorf("expected the file to be gone; got %v, %v", exists, err)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Errorf("expected deleting a missing file to succeed; got %v", err)
+	}
+
+	for _, key := range []string{"", "../../etc/passwd", strings.Repeat("A", 64), strings.Repeat("a", 63) + "/"} {
+		if _, err := store.Open(key); err != ErrInvalidKey {
+			t.Errorf("Open(%q) = %v; want ErrInvalidKey", key, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Package xlsx streams single-sheet Office Open XML spreadsheets, enough for
+// data exports that open in Excel, LibreOffice and Google Sheets.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Number is a numeric cell written as given, e.g. "1250.50", so amounts
+// keep their exact decimals.
+type Number string
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const packageRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+const workbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const sheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const sheetEnd = `</sheetData></worksheet>`
+
+// Writer writes rows to the only sheet of a workbook as they come, so large
+// exports are never held in memory.
+type Writer struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+// NewWriter starts a workbook on w with one sheet called sheetName.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	archive := zip.NewWriter(w)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", packageRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, sheetStart); err != nil {
+		return nil, err
+	}
+
+	return &Writer{zip: archive, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Cells may be strings, Numbers, integers, floats
+// or nil for an empty cell; anything else is written as text.
+func (w *Writer) WriteRow(cells ...any) error {
+	w.rows++
+
+	var row bytes.Buffer
+	fmt.Fprintf(&row, `<row r="%d">`, w.rows)
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(w.rows)
+		switch value := cell.(type) {
+		case nil:
+			continue
+		case Number:
+			fmt.Fprintf(&row, `<c r="%s"><v>%s</v></c>`, ref, escape(string(value)))
+		case int:
+			fmt.Fprintf(&row, `<c r="%s"><v>%d</v></c>`, ref, value)
+		case int64:
+			fmt.Fprintf(&row, `<c r="%s"><v>%d</v></c>`, ref, value)
+		case float64:
+			fmt.Fprintf(&row, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(value, 'f', -1, 64))
+		case string:
+			fmt.Fprintf(&row, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(value))
+		default:
+			fmt.Fprintf(&row, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(fmt.Sprint(value)))
+		}
+	}
+	row.WriteString(`</row>`)
+
+	_, err := w.sheet.Write(row.Bytes())
+	return err
+}
+
+// Close finishes the sheet and the workbook. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if _, err := io.WriteString(w.sheet, sheetEnd); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// columnName turns a 0-based column index into its letters: A, B, ... Z, AA.
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// escape makes text safe for XML, replacing characters XML cannot hold.
+func escape(text string) string {
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
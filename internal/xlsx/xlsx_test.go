@@ -0,0 +1,83 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Expenses & more")
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.WriteRow("Description", "Amount", "Count"); err != nil {
+		t.Fatalf("WriteRow failed: %v", err)
+	}
+	if err := w.WriteRow("Coffee <large> & cake", Number("1250.50"), 3, nil, 0.5); err != nil {
+		t.Fatalf("WriteRow failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected a zip archive: %v", err)
+	}
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("Open %s failed: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		parts[file.Name] = string(content)
+
+		// Every part must be well-formed XML
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", file.Name, err)
+			}
+		}
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Coffee &lt;large&gt; &amp; cake</t></is></c>`,
+		`<c r="B2"><v>1250.50</v></c>`,
+		`<c r="C2"><v>3</v></c>`,
+		`<c r="E2"><v>0.5</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet lacks %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="D2"`) {
+		t.Error("expected no cell for nil")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := columnName(index); got != want {
+			t.Errorf("columnName(%d) = %s; want %s", index, got, want)
+		}
+	}
+}